@@ -0,0 +1,31 @@
+package discovery
+
+import (
+	"strconv"
+	"time"
+)
+
+// AuditRecord represents a mutating api call.
+type AuditRecord struct {
+	ID      string    `json:"id"`
+	Time    time.Time `json:"time"`
+	Method  string    `json:"method"`
+	User    string    `json:"user"`
+	Kind    string    `json:"kind"`
+	Request string    `json:"request"`
+	Code    uint32    `json:"code"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// Header creates the header for csv or table output.
+func (a AuditRecord) Header() []string {
+	return []string{"TIME", "USER", "KIND", "METHOD", "CODE", "REQUEST", "ERROR"}
+}
+
+// Row creates a row for csv or table output.
+func (a AuditRecord) Row() []string {
+	return []string{a.Time.Format(time.RFC3339), a.User, a.Kind, a.Method, strconv.FormatUint(uint64(a.Code), 10), a.Request, a.Error}
+}
+
+// AuditRecords is a list of audit records.
+type AuditRecords []AuditRecord
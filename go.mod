@@ -22,6 +22,9 @@ require (
 	github.com/stretchr/testify v1.8.4
 	github.com/zbindenren/king v0.3.2
 	github.com/zbindenren/sfmt v0.1.0
+	go.etcd.io/bbolt v1.3.7
+	go.etcd.io/etcd/api/v3 v3.5.9
+	go.etcd.io/etcd/client/v3 v3.5.9
 	go.uber.org/zap v1.26.0
 	golang.org/x/oauth2 v0.17.0
 	golang.org/x/term v0.20.0
@@ -21,11 +21,22 @@ import (
 // for machines.
 //
 // If the above fails it checks if the token is a valid oidc token. If successful access is granted to a user.
+// With a client verifier (WithClientVerifier), oidc access tokens of the client credentials flow are accepted
+// as well.
 //
-// In both (successful) cases it extracts the user and adds it in the current context.
+// In all (successful) cases it extracts the user and adds it in the current context.
 //
-// Reflection and list requests are not authorized.
-func Func(verifier Verifier, th *TokenHandler, l *zap.SugaredLogger, claimConfig ClaimConfig) func(ctx context.Context) (context.Context, error) {
+// Requests without token are authenticated with a verified tls client certificate (WithCertConfig).
+//
+// Reflection requests are not authenticated. Authenticated requests are authorized by the
+// authorizer interceptor (see Authorizer).
+func Func(verifier Verifier, th *TokenHandler, l *zap.SugaredLogger, claimConfig ClaimConfig, opts ...FuncOption) func(ctx context.Context) (context.Context, error) {
+	cfg := funcConfig{}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
 	return func(ctx context.Context) (context.Context, error) {
 		methodName := methodNameFromContext(ctx)
 		if strings.HasPrefix(methodName, "/grpc.reflection") {
@@ -34,6 +45,19 @@ func Func(verifier Verifier, th *TokenHandler, l *zap.SugaredLogger, claimConfig
 
 		token, err := grpc_auth.AuthFromMD(ctx, "bearer")
 		if err != nil {
+			// client certificates
+			if cert, ok := certificateFromContext(ctx); ok {
+				u := cfg.certConfig.User(cert)
+
+				l.Debugw("grpc authentication",
+					"methodName", methodName,
+					"certificate", u.Username,
+					"namespaces", strings.Join(u.Namespaces, ","),
+				)
+
+				return context.WithValue(ctx, userKey, u), nil
+			}
+
 			return nil, status.Errorf(codes.Unauthenticated, "failed to get authentication token: %s", err)
 		}
 
@@ -61,7 +85,38 @@ func Func(verifier Verifier, th *TokenHandler, l *zap.SugaredLogger, claimConfig
 		// personal personal
 		idToken, err := verifier.Verify(ctx, token)
 		if err != nil {
-			return nil, status.Errorf(codes.PermissionDenied, "personal token is not valid: %s", err)
+			if cfg.clientVerifier == nil {
+				return nil, status.Errorf(codes.PermissionDenied, "personal token is not valid: %s", err)
+			}
+
+			// client credentials
+			accessToken, clientErr := cfg.clientVerifier.Verify(ctx, token)
+			if clientErr != nil {
+				return nil, status.Errorf(codes.PermissionDenied, "token is neither a valid personal token (%s) nor a valid client token (%s)", err, clientErr)
+			}
+
+			c := claims{}
+
+			if err := accessToken.Claims(&c); err != nil {
+				return nil, status.Errorf(codes.Internal, "could not get claims: %s", err)
+			}
+
+			u := User{
+				Username: claimConfig.ClientID(c),
+				Roles:    claimConfig.Roles(c),
+				Kind:     ClientToken,
+			}
+
+			u.Namespaces = claimConfig.ClientNamespaces(c, u.Roles)
+
+			l.Infow("grpc authentication",
+				"methodName", methodName,
+				"client", u.Username,
+				"roles", strings.Join(u.Roles, ","),
+				"namespaces", strings.Join(u.Namespaces, ","),
+			)
+
+			return context.WithValue(ctx, userKey, u), nil
 		}
 
 		c := claims{}
@@ -77,25 +132,40 @@ func Func(verifier Verifier, th *TokenHandler, l *zap.SugaredLogger, claimConfig
 			Kind:     UserToken,
 		}
 
+		u.Namespaces = claimConfig.Namespaces(c, u.Roles)
+
 		l.Infow("grpc authentication",
 			"methodName", methodName,
 			"isUserToken", u.IsUser(),
 			"name", u.Username,
 			"roles", strings.Join(u.Roles, ","),
+			"namespaces", strings.Join(u.Namespaces, ","),
 		)
 
 		return context.WithValue(ctx, userKey, u), nil
 	}
 }
 
-// UnaryAuthorizeInterceptor authorizes GRPC requests.
-func UnaryAuthorizeInterceptor(rwRoles ...string) grpc.UnaryServerInterceptor {
-	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		if err := authorizeUser(ctx, rwRoles...); err != nil {
-			return nil, err
-		}
+type funcConfig struct {
+	certConfig     CertConfig
+	clientVerifier Verifier
+}
 
-		return handler(ctx, req)
+// FuncOption configures the authentication function.
+type FuncOption func(*funcConfig)
+
+// WithCertConfig authenticates requests without token with verified tls client certificates.
+func WithCertConfig(c CertConfig) FuncOption {
+	return func(cfg *funcConfig) {
+		cfg.certConfig = c
+	}
+}
+
+// WithClientVerifier accepts oidc access tokens of the client credentials flow, that are
+// verified by v. The verifier should check the audience of the tokens.
+func WithClientVerifier(v Verifier) FuncOption {
+	return func(cfg *funcConfig) {
+		cfg.clientVerifier = v
 	}
 }
 
@@ -118,60 +188,142 @@ func StreamMethodNameInterceptor() grpc.StreamServerInterceptor {
 	}
 }
 
-// StreamAuthorizeInterceptor authorizes GRPC streams.
-func StreamAuthorizeInterceptor(rwRoles ...string) grpc.StreamServerInterceptor {
-	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-		if err := authorizeUser(stream.Context(), rwRoles...); err != nil {
-			return err
-		}
+// ClaimConfig configures how to get username, roles and namespaces from claims.
+type ClaimConfig struct {
+	username         string
+	roles            string
+	namespaces       string
+	clientNamespaces string
+	roleNamespaces   map[string][]string
+}
 
-		return handler(srv, stream)
+// ClaimOption configures a ClaimConfig.
+type ClaimOption func(*ClaimConfig)
+
+// WithNamespacesClaim gets the namespaces of a user from claim.
+func WithNamespacesClaim(claim string) ClaimOption {
+	return func(c *ClaimConfig) {
+		c.namespaces = claim
 	}
 }
 
-// ClaimConfig configures how to get username and roles from claims.
-type ClaimConfig struct {
-	username string
-	roles    string
+// WithClientNamespacesClaim gets the namespaces of client credentials tokens from claim.
+func WithClientNamespacesClaim(claim string) ClaimOption {
+	return func(c *ClaimConfig) {
+		c.clientNamespaces = claim
+	}
+}
+
+// WithRoleNamespaces maps roles to namespaces. Namespaces can be patterns as understood
+// by path.Match.
+func WithRoleNamespaces(m map[string][]string) ClaimOption {
+	return func(c *ClaimConfig) {
+		c.roleNamespaces = m
+	}
 }
 
 // NewClaimConfig creates a new ClaimConfig.
-func NewClaimConfig(username, roles string) ClaimConfig {
-	return ClaimConfig{
+func NewClaimConfig(username, roles string, opts ...ClaimOption) ClaimConfig {
+	c := ClaimConfig{
 		username: username,
 		roles:    roles,
 	}
+
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return c
 }
 
 type claims map[string]interface{}
 
 // Roles gets the roles from claims map.
 func (c ClaimConfig) Roles(claims claims) []string {
-	r, ok := claims[c.roles]
+	return claims.strings(c.roles)
+}
+
+// Namespaces gets the namespaces from the namespaces claim and the namespaces mapped to
+// roles. If neither a namespaces claim nor a role mapping is configured, users have
+// access to all namespaces.
+func (c ClaimConfig) Namespaces(claims claims, roles []string) []string {
+	if c.namespaces == "" && len(c.roleNamespaces) == 0 {
+		return []string{"*"}
+	}
+
+	namespaces := []string{}
+
+	if c.namespaces != "" {
+		namespaces = append(namespaces, claims.strings(c.namespaces)...)
+	}
+
+	for _, r := range roles {
+		namespaces = append(namespaces, c.roleNamespaces[r]...)
+	}
+
+	return namespaces
+}
+
+// ClientNamespaces gets the namespaces of client credentials tokens from the client
+// namespaces claim and the namespaces mapped to roles. Clients have no access to all
+// namespaces by default.
+func (c ClaimConfig) ClientNamespaces(claims claims, roles []string) []string {
+	namespaces := []string{}
+
+	if c.clientNamespaces != "" {
+		namespaces = append(namespaces, claims.strings(c.clientNamespaces)...)
+	}
+
+	for _, r := range roles {
+		namespaces = append(namespaces, c.roleNamespaces[r]...)
+	}
+
+	return namespaces
+}
+
+// ClientID gets the client id of client credentials tokens from the claims client_id
+// (RFC 9068) or azp.
+func (c ClaimConfig) ClientID(claims claims) string {
+	for _, claim := range []string{"client_id", "azp"} {
+		if id, ok := claims[claim].(string); ok && id != "" {
+			return id
+		}
+	}
+
+	return ""
+}
+
+// strings gets a string or a list of strings from claim.
+func (c claims) strings(claim string) []string {
+	r, ok := c[claim]
 	if !ok {
 		return []string{}
 	}
 
+	if s, ok := r.(string); ok {
+		return []string{s}
+	}
+
 	rls, ok := r.([]interface{})
 	if ok {
-		roles := make([]string, 0, len(rls))
+		values := make([]string, 0, len(rls))
 
 		for _, r := range rls {
 			r, isString := r.(string)
 			if isString {
-				roles = append(roles, r)
+				values = append(values, r)
 			}
 		}
 
-		return roles
+		return values
 	}
 
-	roles, ok := r.([]string)
+	values, ok := r.([]string)
 	if !ok {
 		return []string{}
 	}
 
-	return roles
+	return values
 }
 
 // Username gets the username from claims map.
@@ -190,45 +342,6 @@ func (c ClaimConfig) Username(claims claims) string {
 	return username
 }
 
-//nolint:gocyclo // maybe move rules to other function
-func authorizeUser(ctx context.Context, rwRoles ...string) error {
-	fullMethod := methodNameFromContext(ctx)
-	if strings.HasPrefix(fullMethod, "/grpc.reflection") {
-		return nil
-	}
-
-	u, ok := UserFromContext(ctx)
-	if !ok {
-		return status.Errorf(codes.Unauthenticated, "unauthententicated user")
-	}
-
-	// following methods are only allowed when user has a rw role.
-	switch fullMethod {
-	case "/postfinance.discovery.v1.NamespaceAPI/RegisterNamespace":
-		if u.IsMachine() || !u.HasRole(rwRoles...) {
-			return status.Errorf(codes.PermissionDenied, "%s token for %s is not allowed to register a namespace", u.Kind.String(), u.Username)
-		}
-	case "/postfinance.discovery.v1.NamespaceAPI/UnregisterNamespace":
-		if u.IsMachine() || !u.HasRole(rwRoles...) {
-			return status.Errorf(codes.PermissionDenied, "%s token for %s is not allowed to unregister a namespace", u.Kind.String(), u.Username)
-		}
-	case "/postfinance.discovery.v1.ServerAPI/RegisterServer":
-		if u.IsMachine() || !u.HasRole(rwRoles...) {
-			return status.Errorf(codes.PermissionDenied, "%s token for %s is not allowed to register a server", u.Kind.String(), u.Username)
-		}
-	case "/postfinance.discovery.v1.ServerAPI/UnregisterServer":
-		if u.IsMachine() || !u.HasRole(rwRoles...) {
-			return status.Errorf(codes.PermissionDenied, "%s token for %s is not allowed to unregister a server", u.Kind.String(), u.Username)
-		}
-	case "/postfinance.discovery.v1.TokenAPI/Create":
-		if u.IsMachine() || !u.HasRole(rwRoles...) {
-			return status.Errorf(codes.PermissionDenied, "%s token for %s is not allowed to create a token", u.Kind.String(), u.Username)
-		}
-	}
-
-	return nil
-}
-
 func methodNameFromContext(ctx context.Context) string {
 	m, ok := ctx.Value(methodNameKey).(string)
 	if !ok {
@@ -72,3 +72,64 @@ func (m mockVerifier) Verify(ctx context.Context, rawIDToken string) (*oidc.IDTo
 	}
 	return nil, errors.New("invalid token - mock")
 }
+
+func TestClaimConfigNamespaces(t *testing.T) {
+	c := claims{
+		"roles":      []interface{}{"team-a", "dev"},
+		"namespaces": []interface{}{"shared"},
+		"group":      "team-b",
+	}
+
+	t.Run("not configured", func(t *testing.T) {
+		cc := NewClaimConfig("username", "roles")
+		assert.Equal(t, []string{"*"}, cc.Namespaces(c, cc.Roles(c)))
+	})
+
+	t.Run("claim", func(t *testing.T) {
+		cc := NewClaimConfig("username", "roles", WithNamespacesClaim("namespaces"))
+		assert.Equal(t, []string{"shared"}, cc.Namespaces(c, cc.Roles(c)))
+
+		cc = NewClaimConfig("username", "roles", WithNamespacesClaim("group"))
+		assert.Equal(t, []string{"team-b"}, cc.Namespaces(c, cc.Roles(c)))
+
+		cc = NewClaimConfig("username", "roles", WithNamespacesClaim("missing"))
+		assert.Empty(t, cc.Namespaces(c, cc.Roles(c)))
+	})
+
+	t.Run("role mapping", func(t *testing.T) {
+		cc := NewClaimConfig("username", "roles",
+			WithNamespacesClaim("namespaces"),
+			WithRoleNamespaces(map[string][]string{
+				"team-a": {"team-a", "team-a-*"},
+				"ops":    {"*"},
+			}),
+		)
+		assert.Equal(t, []string{"shared", "team-a", "team-a-*"}, cc.Namespaces(c, cc.Roles(c)))
+	})
+}
+
+func TestClaimConfigClient(t *testing.T) {
+	c := claims{
+		"azp":        "ci",
+		"roles":      []interface{}{"team-a"},
+		"namespaces": []interface{}{"shared"},
+	}
+
+	cc := NewClaimConfig("username", "roles")
+	assert.Equal(t, "ci", cc.ClientID(c))
+	assert.Empty(t, cc.ClientNamespaces(c, cc.Roles(c)), "no access to all namespaces by default")
+
+	c["client_id"] = "ci-client"
+	assert.Equal(t, "ci-client", cc.ClientID(c))
+
+	cc = NewClaimConfig("username", "roles",
+		WithClientNamespacesClaim("namespaces"),
+		WithRoleNamespaces(map[string][]string{"team-a": {"team-a"}}),
+	)
+	assert.Equal(t, []string{"shared", "team-a"}, cc.ClientNamespaces(c, cc.Roles(c)))
+
+	u := User{Kind: ClientToken}
+	assert.True(t, u.IsClient())
+	assert.Equal(t, "client", u.Kind.String())
+	assert.Contains(t, u.policyRoles(), RoleMachine)
+}
@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// canIMethod is allowed for all authenticated users.
+const canIMethod = methodPrefix + "AuthAPI/CanI"
+
+// Authorizer authorizes grpc requests with a policy.
+type Authorizer struct {
+	mu     sync.RWMutex
+	policy *Policy
+	path   string
+	sum    [sha256.Size]byte
+	l      *zap.SugaredLogger
+}
+
+// NewAuthorizer creates an authorizer with a static policy.
+func NewAuthorizer(p *Policy, l *zap.SugaredLogger) *Authorizer {
+	return &Authorizer{
+		policy: p,
+		l:      l,
+	}
+}
+
+// NewFileAuthorizer creates an authorizer with the policy from a yaml file. The
+// file is reloaded with Watch.
+func NewFileAuthorizer(path string, l *zap.SugaredLogger) (*Authorizer, error) {
+	a := &Authorizer{
+		path: path,
+		l:    l,
+	}
+
+	if _, err := a.Reload(); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// Reload loads the policy file, if it has changed. Invalid policies are not loaded.
+func (a *Authorizer) Reload() (bool, error) {
+	if a.path == "" {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(a.path)
+	if err != nil {
+		return false, err
+	}
+
+	sum := sha256.Sum256(data)
+
+	a.mu.RLock()
+	unchanged := a.policy != nil && sum == a.sum
+	a.mu.RUnlock()
+
+	if unchanged {
+		return false, nil
+	}
+
+	p, err := ParsePolicy(data)
+	if err != nil {
+		return false, err
+	}
+
+	a.mu.Lock()
+	a.policy = p
+	a.sum = sum
+	a.mu.Unlock()
+
+	return true, nil
+}
+
+// Watch reloads the policy file every interval. On errors the current policy stays
+// active. It runs until context ctx is canceled.
+func (a *Authorizer) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := a.Reload()
+			if err != nil {
+				a.l.Errorw("failed to reload policy", "path", a.path, "err", err)
+				continue
+			}
+
+			if changed {
+				a.l.Infow("policy reloaded", "path", a.path)
+			}
+		}
+	}
+}
+
+// Authorize evaluates if user u is allowed to do request r.
+func (a *Authorizer) Authorize(u User, r Request) (allowed bool, reason string) {
+	a.mu.RLock()
+	p := a.policy
+	a.mu.RUnlock()
+
+	return p.Authorize(u, r)
+}
+
+// UnaryServerInterceptor authorizes unary grpc requests.
+func (a *Authorizer) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := a.authorize(ctx, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor authorizes grpc streams. Every received message is authorized.
+func (a *Authorizer) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if strings.HasPrefix(info.FullMethod, "/grpc.reflection") {
+			return handler(srv, stream)
+		}
+
+		if _, ok := UserFromContext(stream.Context()); !ok {
+			return status.Errorf(codes.Unauthenticated, "unauthententicated user")
+		}
+
+		return handler(srv, &authorizedStream{
+			ServerStream: stream,
+			authorize: func(m interface{}) error {
+				return a.authorize(stream.Context(), info.FullMethod, m)
+			},
+		})
+	}
+}
+
+func (a *Authorizer) authorize(ctx context.Context, method string, req interface{}) error {
+	if strings.HasPrefix(method, "/grpc.reflection") {
+		return nil
+	}
+
+	u, ok := UserFromContext(ctx)
+	if !ok {
+		return status.Errorf(codes.Unauthenticated, "unauthententicated user")
+	}
+
+	if method == canIMethod {
+		return nil
+	}
+
+	r := requestFromMessage(method, req)
+
+	allowed, reason := a.Authorize(u, r)
+	if !allowed {
+		return status.Errorf(codes.PermissionDenied, "%s token for %s is not allowed to call %s: %s", u.Kind.String(), u.Username, r, reason)
+	}
+
+	return nil
+}
+
+// authorizedStream authorizes all received messages.
+type authorizedStream struct {
+	grpc.ServerStream
+	authorize func(m interface{}) error
+}
+
+func (s *authorizedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	return s.authorize(m)
+}
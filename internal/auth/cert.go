@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// CertConfig maps verified client certificates to users.
+type CertConfig struct {
+	namespaces map[string][]string
+}
+
+// NewCertConfig creates a new CertConfig. The keys of namespaces are patterns as understood
+// by path.Match. They are matched against the common name and the dns names of the client
+// certificates.
+func NewCertConfig(namespaces map[string][]string) CertConfig {
+	return CertConfig{
+		namespaces: namespaces,
+	}
+}
+
+// User returns the machine user of a client certificate. The username is the common name or,
+// if it is empty, the first dns name of the certificate. Certificates that match no pattern
+// have no namespaces.
+func (c CertConfig) User(cert *x509.Certificate) User {
+	names := certificateNames(cert)
+
+	u := User{
+		Namespaces: []string{},
+		ExpiresAt:  cert.NotAfter,
+		Kind:       MachineToken,
+	}
+
+	if len(names) > 0 {
+		u.Username = names[0]
+	}
+
+	for pattern, namespaces := range c.namespaces {
+		for _, name := range names {
+			if match(pattern, name) {
+				for _, n := range namespaces {
+					u.Namespaces = appendUnique(u.Namespaces, n)
+				}
+
+				break
+			}
+		}
+	}
+
+	sort.Strings(u.Namespaces)
+
+	return u
+}
+
+// NewServerTLSConfig creates the tls configuration of a server. If clientCAFile is not empty,
+// client certificates signed by one of its certificates are verified. Clients without
+// certificate are still accepted.
+func NewServerTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %w", err)
+	}
+
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if clientCAFile != "" {
+		pool, err := LoadCertPool(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return cfg, nil
+}
+
+// NewPinnedTLSConfig creates a client tls configuration that only accepts the (first)
+// certificate of server configuration cfg. It is used to connect to the own server.
+func NewPinnedTLSConfig(cfg *tls.Config) (*tls.Config, error) {
+	if len(cfg.Certificates) == 0 || len(cfg.Certificates[0].Certificate) == 0 {
+		return nil, errors.New("tls configuration has no certificate")
+	}
+
+	pinned := cfg.Certificates[0].Certificate[0]
+
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: true, //nolint:gosec // the certificate is verified by VerifyPeerCertificate
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 || !bytes.Equal(rawCerts[0], pinned) {
+				return errors.New("unexpected server certificate")
+			}
+
+			return nil
+		},
+	}, nil
+}
+
+// LoadCertPool creates a cert pool with only the certificates in pemFile.
+func LoadCertPool(pemFile string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(pemFile) //nolint:gosec // path is configured by the administrator
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file '%s': %w", pemFile, err)
+	}
+
+	pool := x509.NewCertPool()
+
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("%s: no pem encoded certificates found", pemFile)
+	}
+
+	return pool, nil
+}
+
+// certificateFromContext returns the verified client certificate of the grpc peer.
+func certificateFromContext(ctx context.Context) (*x509.Certificate, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil, false
+	}
+
+	chains := tlsInfo.State.VerifiedChains
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return nil, false
+	}
+
+	return chains[0][0], true
+}
+
+// certificateNames returns the common name and dns names of a certificate.
+func certificateNames(cert *x509.Certificate) []string {
+	names := []string{}
+
+	if cert.Subject.CommonName != "" {
+		names = append(names, cert.Subject.CommonName)
+	}
+
+	return append(names, cert.DNSNames...)
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+func TestCertConfigUser(t *testing.T) {
+	c := NewCertConfig(map[string][]string{
+		"*.example.com":     {"default"},
+		"host1.example.com": {"team-a", "default"},
+		"*.example.net":     {"team-b"},
+	})
+
+	t.Run("common name", func(t *testing.T) {
+		u := c.User(&x509.Certificate{Subject: pkix.Name{CommonName: "host1.example.com"}})
+		assert.Equal(t, "host1.example.com", u.Username)
+		assert.Equal(t, []string{"default", "team-a"}, u.Namespaces)
+		assert.True(t, u.IsMachine())
+	})
+
+	t.Run("dns names", func(t *testing.T) {
+		u := c.User(&x509.Certificate{DNSNames: []string{"host2.example.org", "host2.example.net"}})
+		assert.Equal(t, "host2.example.org", u.Username)
+		assert.Equal(t, []string{"team-b"}, u.Namespaces)
+	})
+
+	t.Run("not mapped", func(t *testing.T) {
+		u := c.User(&x509.Certificate{Subject: pkix.Name{CommonName: "host3.example.org"}})
+		assert.Equal(t, "host3.example.org", u.Username)
+		assert.Empty(t, u.Namespaces)
+	})
+}
+
+func TestFuncCertificate(t *testing.T) {
+	th := NewTokenHandler("thesecret", "discovery.postifnance.ch")
+	certConfig := NewCertConfig(map[string][]string{"*.example.com": {"default"}})
+	f := Func(mockVerifier{ok: false}, th, zap.NewNop().Sugar(), ClaimConfig{}, WithCertConfig(certConfig))
+
+	t.Run("verified certificate", func(t *testing.T) {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: "host1.example.com"}}
+		ctx := peer.NewContext(context.Background(), &peer.Peer{
+			AuthInfo: credentials.TLSInfo{
+				State: tls.ConnectionState{
+					VerifiedChains: [][]*x509.Certificate{{cert}},
+				},
+			},
+		})
+
+		c, err := f(ctx)
+		require.NoError(t, err)
+
+		u, ok := UserFromContext(c)
+		require.True(t, ok)
+		assert.Equal(t, "host1.example.com", u.Username)
+		assert.Equal(t, []string{"default"}, u.Namespaces)
+	})
+
+	t.Run("unverified certificate", func(t *testing.T) {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{
+			AuthInfo: credentials.TLSInfo{
+				State: tls.ConnectionState{
+					PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "host1.example.com"}}},
+				},
+			},
+		})
+
+		_, err := f(ctx)
+		assert.Error(t, err)
+	})
+}
@@ -36,10 +36,13 @@ type ClientOption func(a *Client)
 
 // Client handles requests to the keycloak server.
 type Client struct {
-	cli           *http.Client
-	endPoint      string
-	clientID      string
-	tokenEndpoint string
+	cli                         *http.Client
+	endPoint                    string
+	clientID                    string
+	clientSecret                string
+	tokenEndpoint               string
+	authorizationEndpoint       string
+	deviceAuthorizationEndpoint string
 }
 
 // NewClient creates a new client with a configured token endpoint.
@@ -83,6 +86,10 @@ func NewClient(endPoint, clientID string, opts ...ClientOption) (*Client, error)
 
 	c.tokenEndpoint = fmt.Sprintf("%s", m["token_endpoint"])
 
+	// optional endpoints for the device and authorization code flows
+	c.authorizationEndpoint, _ = m["authorization_endpoint"].(string)
+	c.deviceAuthorizationEndpoint, _ = m["device_authorization_endpoint"].(string)
+
 	return &c, nil
 }
 
@@ -100,6 +107,25 @@ func WithTransport(transport http.RoundTripper) ClientOption {
 	}
 }
 
+// WithClientSecret sets the secret of confidential clients.
+func WithClientSecret(secret string) ClientOption {
+	return func(c *Client) {
+		c.clientSecret = secret
+	}
+}
+
+// ClientCredentialsToken returns an OAUTH 2.0 access token with Client Credentials Grant type.
+// The client needs a secret (WithClientSecret).
+func (c *Client) ClientCredentialsToken() (*Token, error) {
+	if c.clientSecret == "" {
+		return nil, fmt.Errorf("client credentials grant requires a client secret")
+	}
+
+	data := url.Values{"grant_type": {"client_credentials"}}
+
+	return c.requestToken(data)
+}
+
 // Token returns an OAUTH 2.0 token with Password Grant type.
 func (c *Client) Token(username, password string, out io.Writer) (*Token, error) {
 	t, err := c.getToken(username, password)
@@ -209,7 +235,7 @@ func (c *Client) requestToken(data url.Values) (*Token, error) {
 		return nil, fmt.Errorf("creating token request: %w", err)
 	}
 
-	req.SetBasicAuth(c.clientID, "")
+	req.SetBasicAuth(c.clientID, c.clientSecret)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 	resp, err := c.cli.Do(req)
@@ -304,7 +330,10 @@ func handleResponse(r *http.Response) error {
 			return fmt.Errorf("request failed - status %s: %s", r.Status, string(body))
 		}
 
-		return fmt.Errorf("request %s: %s: %s", r.Status, e.Error, e.ErrorDescription)
+		return &responseError{
+			status: r.Status,
+			resp:   e,
+		}
 	}
 
 	return nil
@@ -314,3 +343,13 @@ type errResponse struct {
 	Error            string `json:"error"`
 	ErrorDescription string `json:"error_description"`
 }
+
+// responseError is an oauth2 error response (RFC 6749 section 5.2).
+type responseError struct {
+	status string
+	resp   errResponse
+}
+
+func (e *responseError) Error() string {
+	return fmt.Sprintf("request %s: %s: %s", e.status, e.resp.Error, e.resp.ErrorDescription)
+}
@@ -0,0 +1,55 @@
+package auth
+
+import "sync"
+
+// DenyList contains the token ids (jti) of revoked machine tokens.
+type DenyList struct {
+	m   *sync.RWMutex
+	ids map[string]struct{}
+}
+
+// NewDenyList creates a new empty deny list.
+func NewDenyList() *DenyList {
+	return &DenyList{
+		m:   &sync.RWMutex{},
+		ids: map[string]struct{}{},
+	}
+}
+
+// Add adds token ids to the deny list.
+func (d *DenyList) Add(ids ...string) {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	for _, id := range ids {
+		d.ids[id] = struct{}{}
+	}
+}
+
+// Remove removes a token id from the deny list.
+func (d *DenyList) Remove(id string) {
+	d.m.Lock()
+	delete(d.ids, id)
+	d.m.Unlock()
+}
+
+// Reset replaces all token ids.
+func (d *DenyList) Reset(ids ...string) {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	d.ids = make(map[string]struct{}, len(ids))
+
+	for _, id := range ids {
+		d.ids[id] = struct{}{}
+	}
+}
+
+// Contains returns true if token id is on the deny list.
+func (d *DenyList) Contains(id string) bool {
+	d.m.RLock()
+	_, ok := d.ids[id]
+	d.m.RUnlock()
+
+	return ok
+}
@@ -0,0 +1,239 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	deviceCodeGrantType    = "urn:ietf:params:oauth:grant-type:device_code"
+	dfltDevicePollInterval = 5 * time.Second
+)
+
+// deviceAuthorization is the response of the device authorization endpoint (RFC 8628 section 3.2).
+type deviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// DeviceToken returns an OAUTH 2.0 token with the Device Authorization Grant (RFC 8628). The
+// verification url and the user code are written to out. It polls the token endpoint until
+// the user has authorized the device, the device code expires or ctx is canceled.
+func (c *Client) DeviceToken(ctx context.Context, out io.Writer) (*Token, error) {
+	if c.deviceAuthorizationEndpoint == "" {
+		return nil, errors.New("oidc provider does not support the device authorization grant")
+	}
+
+	data := url.Values{"client_id": {c.clientID}, "scope": {"openid"}}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.deviceAuthorizationEndpoint, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("creating device authorization request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.cli.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("requesting device authorization: %w", err)
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if err := handleResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var da deviceAuthorization
+
+	if err := json.NewDecoder(resp.Body).Decode(&da); err != nil {
+		return nil, fmt.Errorf("decoding device authorization: %w", err)
+	}
+
+	if da.VerificationURIComplete != "" {
+		fmt.Fprintf(out, "open %s and confirm code %s\n", da.VerificationURIComplete, da.UserCode)
+	} else {
+		fmt.Fprintf(out, "open %s and enter code %s\n", da.VerificationURI, da.UserCode)
+	}
+
+	interval := dfltDevicePollInterval
+	if da.Interval > 0 {
+		interval = time.Duration(da.Interval) * time.Second
+	}
+
+	if da.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(da.ExpiresIn)*time.Second)
+		defer cancel()
+	}
+
+	data = url.Values{"client_id": {c.clientID}, "device_code": {da.DeviceCode}, "grant_type": {deviceCodeGrantType}}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for device authorization: %w", ctx.Err())
+		case <-time.After(interval):
+		}
+
+		t, err := c.requestToken(data)
+
+		var re *responseError
+		if !errors.As(err, &re) {
+			return t, err
+		}
+
+		switch re.resp.Error {
+		case "authorization_pending":
+		case "slow_down":
+			interval += dfltDevicePollInterval
+		default:
+			return nil, err
+		}
+	}
+}
+
+// AuthCodeToken returns an OAUTH 2.0 token with the Authorization Code Grant and PKCE (RFC 7636).
+// It listens for the redirect on the loopback interface (RFC 8252 section 7.3) on port or on
+// a random port, if port is 0. The authorization url is passed to function open, that should
+// open it in a browser.
+func (c *Client) AuthCodeToken(ctx context.Context, port int, open func(authURL string)) (*Token, error) {
+	if c.authorizationEndpoint == "" {
+		return nil, errors.New("oidc provider has no authorization endpoint")
+	}
+
+	verifier, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := randomString(16)
+	if err != nil {
+		return nil, err
+	}
+
+	authURL, err := url.Parse(c.authorizationEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parsing authorization endpoint: %w", err)
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return nil, fmt.Errorf("listening for redirect: %w", err)
+	}
+
+	redirectURI := fmt.Sprintf("http://%s/callback", listener.Addr().String())
+	challenge := sha256.Sum256([]byte(verifier))
+
+	q := authURL.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", c.clientID)
+	q.Set("redirect_uri", redirectURI)
+	q.Set("scope", "openid")
+	q.Set("state", state)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+	authURL.RawQuery = q.Encode()
+
+	type result struct {
+		code string
+		err  error
+	}
+
+	results := make(chan result, 1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		var res result
+
+		params := r.URL.Query()
+
+		switch {
+		case params.Get("state") != state:
+			res.err = errors.New("invalid state in authorization response")
+		case params.Get("error") != "":
+			res.err = fmt.Errorf("authorization failed: %s: %s", params.Get("error"), params.Get("error_description"))
+		case params.Get("code") == "":
+			res.err = errors.New("no code in authorization response")
+		default:
+			res.code = params.Get("code")
+		}
+
+		if res.err != nil {
+			http.Error(w, res.err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprintln(w, "Login successful. You can close this window.")
+		}
+
+		select {
+		case results <- res:
+		default:
+		}
+	})
+
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: dfltTimeout,
+	}
+
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+
+	defer func() {
+		_ = srv.Close()
+	}()
+
+	open(authURL.String())
+
+	var res result
+
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for authorization: %w", ctx.Err())
+	case res = <-results:
+	}
+
+	if res.err != nil {
+		return nil, res.err
+	}
+
+	data := url.Values{
+		"client_id":     {c.clientID},
+		"code":          {res.code},
+		"code_verifier": {verifier},
+		"grant_type":    {"authorization_code"},
+		"redirect_uri":  {redirectURI},
+	}
+
+	return c.requestToken(data)
+}
+
+// randomString returns n random bytes encoded as base64url.
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
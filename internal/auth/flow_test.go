@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeIDP is a minimal oidc provider for the device and authorization code flows.
+type fakeIDP struct {
+	*httptest.Server
+	t          *testing.T
+	pending    int32 // number of authorization_pending responses
+	challenges map[string]string
+}
+
+func newFakeIDP(t *testing.T) *fakeIDP {
+	idp := &fakeIDP{
+		t:          t,
+		challenges: map[string]string{},
+	}
+
+	mux := http.NewServeMux()
+	idp.Server = httptest.NewServer(mux)
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                        idp.URL,
+			"token_endpoint":                idp.URL + "/token",
+			"authorization_endpoint":        idp.URL + "/auth",
+			"device_authorization_endpoint": idp.URL + "/device",
+		})
+	})
+
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"device_code":      "thedevicecode",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": idp.URL + "/activate",
+			"expires_in":       60,
+			"interval":         1,
+		})
+	})
+
+	mux.HandleFunc("/auth", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		idp.challenges["thecode"] = q.Get("code_challenge")
+
+		redirect := q.Get("redirect_uri") + "?" + url.Values{"code": {"thecode"}, "state": {q.Get("state")}}.Encode()
+		http.Redirect(w, r, redirect, http.StatusFound)
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+
+		switch r.PostForm.Get("grant_type") {
+		case deviceCodeGrantType:
+			if r.PostForm.Get("device_code") != "thedevicecode" {
+				idp.tokenError(w, "invalid_grant")
+				return
+			}
+
+			if atomic.AddInt32(&idp.pending, -1) >= 0 {
+				idp.tokenError(w, "authorization_pending")
+				return
+			}
+		case "authorization_code":
+			sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+			if idp.challenges[r.PostForm.Get("code")] != base64.RawURLEncoding.EncodeToString(sum[:]) {
+				idp.tokenError(w, "invalid_grant")
+				return
+			}
+		case "client_credentials":
+			if id, secret, _ := r.BasicAuth(); id != "ci" || secret != "cisecret" {
+				idp.tokenError(w, "invalid_client")
+				return
+			}
+
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"access_token": idp.idToken("ci"),
+			})
+
+			return
+		default:
+			idp.tokenError(w, "unsupported_grant_type")
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"id_token":      idp.idToken("jdoe"),
+			"refresh_token": "therefreshtoken",
+		})
+	})
+
+	return idp
+}
+
+func (idp *fakeIDP) tokenError(w http.ResponseWriter, code string) {
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(errResponse{Error: code})
+}
+
+func (idp *fakeIDP) idToken(username string) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss":      idp.URL,
+		"aud":      "discovery",
+		"exp":      time.Now().Add(time.Hour).Unix(),
+		"username": username,
+	}).SignedString([]byte("secret"))
+	require.NoError(idp.t, err)
+
+	return token
+}
+
+func TestDeviceToken(t *testing.T) {
+	idp := newFakeIDP(t)
+	defer idp.Close()
+
+	atomic.StoreInt32(&idp.pending, 1)
+
+	c, err := NewClient(idp.URL, "discovery")
+	require.NoError(t, err)
+
+	out := &strings.Builder{}
+
+	tkn, err := c.DeviceToken(context.Background(), out)
+	require.NoError(t, err)
+	assert.Equal(t, "jdoe", tkn.Username)
+	assert.Equal(t, "therefreshtoken", tkn.RefreshToken)
+	assert.Contains(t, out.String(), "ABCD-EFGH")
+
+	t.Run("canceled", func(t *testing.T) {
+		atomic.StoreInt32(&idp.pending, 100)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+		defer cancel()
+
+		_, err := c.DeviceToken(ctx, io.Discard)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestAuthCodeToken(t *testing.T) {
+	idp := newFakeIDP(t)
+	defer idp.Close()
+
+	c, err := NewClient(idp.URL, "discovery")
+	require.NoError(t, err)
+
+	// the browser follows the redirect to the local callback
+	browser := func(authURL string) {
+		go func() {
+			resp, err := http.Get(authURL) //nolint:gosec,noctx // test
+			if err == nil {
+				_ = resp.Body.Close()
+			}
+		}()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tkn, err := c.AuthCodeToken(ctx, 0, browser)
+	require.NoError(t, err)
+	assert.Equal(t, "jdoe", tkn.Username)
+	assert.Equal(t, "therefreshtoken", tkn.RefreshToken)
+
+	t.Run("invalid state", func(t *testing.T) {
+		_, err := c.AuthCodeToken(ctx, 0, func(authURL string) {
+			u, err := url.Parse(authURL)
+			require.NoError(t, err)
+
+			go func() {
+				resp, err := http.Get(u.Query().Get("redirect_uri") + "?code=thecode&state=invalid") //nolint:gosec,noctx // test
+				if err == nil {
+					_ = resp.Body.Close()
+				}
+			}()
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestClientCredentialsToken(t *testing.T) {
+	idp := newFakeIDP(t)
+	defer idp.Close()
+
+	c, err := NewClient(idp.URL, "ci", WithClientSecret("cisecret"))
+	require.NoError(t, err)
+
+	tkn, err := c.ClientCredentialsToken()
+	require.NoError(t, err)
+	assert.NotEmpty(t, tkn.AccessToken)
+	assert.Empty(t, tkn.RefreshToken)
+
+	c, err = NewClient(idp.URL, "ci", WithClientSecret("wrong"))
+	require.NoError(t, err)
+
+	_, err = c.ClientCredentialsToken()
+	assert.Error(t, err)
+
+	c, err = NewClient(idp.URL, "ci")
+	require.NoError(t, err)
+
+	_, err = c.ClientCredentialsToken()
+	assert.Error(t, err)
+}
@@ -1,34 +1,114 @@
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"time"
 
 	jwt "github.com/golang-jwt/jwt/v4"
+	"go.uber.org/zap"
 )
 
+// ErrTokenRevoked is returned for tokens on the deny list.
+var ErrTokenRevoked = errors.New("token is revoked")
+
+// ScopeSD is the scope of read-only scrape tokens.
+const ScopeSD = "sd"
+
 // TokenHandler creates tokens.
 type TokenHandler struct {
-	issuer string
-	secret string
+	issuer   string
+	secret   string
+	keys     *KeySet
+	denyList *DenyList
+	legacy   *zap.SugaredLogger
+}
+
+// TokenHandlerOption configures a TokenHandler.
+type TokenHandlerOption func(*TokenHandler)
+
+// WithDenyList rejects all tokens with a token id (jti) on the deny list d.
+func WithDenyList(d *DenyList) TokenHandlerOption {
+	return func(t *TokenHandler) {
+		t.denyList = d
+	}
+}
+
+// WithKeySet signs tokens with the signing key of key set k and verifies tokens with a
+// key id (kid) with the keys in k. Tokens signed with the secret are still accepted, if
+// the secret is not empty.
+func WithKeySet(k *KeySet) TokenHandlerOption {
+	return func(t *TokenHandler) {
+		t.keys = k
+	}
+}
+
+// WithLegacyTokens accepts machine tokens issued by earlier versions, which mixed up the
+// issuer and the secret: they are signed with the issuer and contain the secret as issuer.
+// Every accepted legacy token is logged as warning with l, so that it can be reissued.
+//
+// Deprecated: legacy tokens will no longer be accepted in the next major release.
+func WithLegacyTokens(l *zap.SugaredLogger) TokenHandlerOption {
+	return func(t *TokenHandler) {
+		t.legacy = l
+	}
 }
 
 // NewTokenHandler creates a now TokenHandler
-func NewTokenHandler(secret, issuer string) *TokenHandler {
-	return &TokenHandler{
+func NewTokenHandler(secret, issuer string, opts ...TokenHandlerOption) *TokenHandler {
+	t := &TokenHandler{
 		issuer: issuer,
 		secret: secret,
 	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
 }
 
-// Create creates a new token. If expires is 0, it never expires.
+// Create creates a new token with a unique token id (jti). The id is stored as
+// subject. If expires is 0, it never expires.
 func (t *TokenHandler) Create(id string, expires time.Duration, namespaces ...string) (string, error) {
+	claims, err := newTokenClaims(id, t.issuer, expires, namespaces)
+	if err != nil {
+		return "", err
+	}
+
+	return t.sign(claims)
+}
+
+// CreateScrape creates a read-only scrape token, that can only list the target groups
+// of servers in namespaces. If servers is empty, all servers are allowed.
+func (t *TokenHandler) CreateScrape(id string, expires time.Duration, servers []string, namespaces ...string) (string, error) {
+	claims, err := newTokenClaims(id, t.issuer, expires, namespaces)
+	if err != nil {
+		return "", err
+	}
+
+	claims.Scope = ScopeSD
+	claims.Servers = servers
+
+	return t.sign(claims)
+}
+
+func newTokenClaims(id, issuer string, expires time.Duration, namespaces []string) (TokenClaims, error) {
 	now := time.Now()
 
+	jti := make([]byte, 16)
+
+	if _, err := rand.Read(jti); err != nil {
+		return TokenClaims{}, err
+	}
+
 	claims := TokenClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			ID:        id,
-			Issuer:    t.issuer,
+			ID:        hex.EncodeToString(jti),
+			Subject:   id,
+			Issuer:    issuer,
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 		},
@@ -39,40 +119,153 @@ func (t *TokenHandler) Create(id string, expires time.Duration, namespaces ...st
 		claims.ExpiresAt = jwt.NewNumericDate(now.Add(expires))
 	}
 
+	return claims, nil
+}
+
+func (t *TokenHandler) sign(claims TokenClaims) (string, error) {
+	if t.keys != nil && t.keys.SigningKey() != nil {
+		k := t.keys.SigningKey()
+
+		token := jwt.NewWithClaims(k.Method, claims)
+		token.Header["kid"] = k.ID
+
+		return token.SignedString(k.signer)
+	}
+
+	if t.secret == "" {
+		return "", errors.New("no token signing key or secret configured")
+	}
+
 	token := jwt.New(jwt.SigningMethodHS256)
 	token.Claims = claims
 
 	return token.SignedString([]byte(t.secret))
 }
 
-// Validate validates a token. If successful it returns a machine user.
+// Validate validates a token. If successful it returns a machine or scrape user.
 func (t *TokenHandler) Validate(token string) (*User, error) {
+	claims, err := t.Claims(token)
+	if err != nil {
+		return nil, err
+	}
+
+	u := User{
+		Username:   claims.Username(),
+		Namespaces: claims.Namespaces,
+		Kind:       MachineToken,
+		TokenID:    claims.RegisteredClaims.ID,
+	}
+
+	switch claims.Scope {
+	case "":
+	case ScopeSD:
+		u.Kind = ScrapeToken
+		u.Servers = claims.Servers
+	default:
+		return nil, fmt.Errorf("unsupported token scope '%s'", claims.Scope)
+	}
+
+	if claims.ExpiresAt != nil {
+		u.ExpiresAt = claims.ExpiresAt.Time
+	}
+
+	return &u, nil
+}
+
+// Claims validates a token and returns its claims.
+func (t *TokenHandler) Claims(token string) (*TokenClaims, error) {
+	claims, err := t.claims(token)
+	if err != nil {
+		legacy, ok := t.legacyClaims(token)
+		if !ok {
+			return nil, err
+		}
+
+		claims = legacy
+	}
+
+	if t.denyList != nil && t.denyList.Contains(claims.RegisteredClaims.ID) {
+		return nil, ErrTokenRevoked
+	}
+
+	return claims, nil
+}
+
+func (t *TokenHandler) claims(token string) (*TokenClaims, error) {
 	tknClaims := TokenClaims{}
 
-	tkn, err := jwt.ParseWithClaims(token, &tknClaims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(t.secret), nil
-	})
+	tkn, err := jwt.ParseWithClaims(token, &tknClaims, t.verificationKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
 
 	claims, ok := tkn.Claims.(*TokenClaims)
 	if !ok || !tkn.Valid {
-		return nil, fmt.Errorf("invalid token: %w", err)
+		return nil, errors.New("invalid token")
 	}
 
 	if claims.RegisteredClaims.Issuer != t.issuer {
 		return nil, fmt.Errorf("wrong issuer is '%s', not %s", tknClaims.Issuer, t.issuer)
 	}
 
-	u := User{
-		Username:   claims.RegisteredClaims.ID,
-		Namespaces: claims.Namespaces,
-		Kind:       MachineToken,
+	return claims, nil
+}
+
+// legacyClaims returns the claims of a valid legacy token (see WithLegacyTokens).
+func (t *TokenHandler) legacyClaims(token string) (*TokenClaims, bool) {
+	if !t.acceptsLegacy() {
+		return nil, false
 	}
 
-	if claims.ExpiresAt != nil {
-		u.ExpiresAt = claims.ExpiresAt.Time
+	claims := TokenClaims{}
+
+	tkn, err := jwt.ParseWithClaims(token, &claims, func(tkn *jwt.Token) (interface{}, error) {
+		if _, ok := tkn.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %s", tkn.Method.Alg())
+		}
+
+		return []byte(t.issuer), nil
+	})
+	if err != nil || !tkn.Valid || claims.Issuer != t.secret {
+		return nil, false
 	}
 
-	return &u, nil
+	t.legacy.Warnw("accepted legacy machine token, reissue it", "id", claims.ID, "subject", claims.Username())
+
+	return &claims, true
+}
+
+func (t *TokenHandler) acceptsLegacy() bool {
+	return t.legacy != nil && t.secret != "" && t.issuer != ""
+}
+
+// verificationKey returns the key to verify token: the secret for HMAC signed tokens and
+// the key with the token's key id otherwise.
+func (t *TokenHandler) verificationKey(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if t.secret == "" {
+			return nil, errors.New("hmac signed tokens are not accepted")
+		}
+
+		return []byte(t.secret), nil
+	}
+
+	if t.keys == nil {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+
+	kid, _ := token.Header["kid"].(string)
+
+	k, ok := t.keys.Key(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown key id '%s'", kid)
+	}
+
+	if k.Method.Alg() != token.Method.Alg() {
+		return nil, fmt.Errorf("signing method %s does not match key %s", token.Method.Alg(), kid)
+	}
+
+	return k.Public, nil
 }
 
 // IsMachine checks if token is a machine token issued by lslb service.
@@ -84,11 +277,28 @@ func (t *TokenHandler) IsMachine(token string) (bool, error) {
 		return false, err
 	}
 
+	if t.acceptsLegacy() && tknClaims["iss"] == t.secret {
+		return true, nil
+	}
+
 	return tknClaims["iss"] == t.issuer, nil
 }
 
-// TokenClaims is like jwt standard claims with additional list of namespaces.
+// TokenClaims is like jwt standard claims with additional list of namespaces. Scrape
+// tokens have scope sd and an optional list of servers.
 type TokenClaims struct {
 	jwt.RegisteredClaims
 	Namespaces []string `json:"namespaces,omitempty"`
+	Scope      string   `json:"scope,omitempty"`
+	Servers    []string `json:"servers,omitempty"`
+}
+
+// Username returns the subject. Tokens created before the introduction of token ids
+// have no subject and the id is returned.
+func (c TokenClaims) Username() string {
+	if c.Subject != "" {
+		return c.Subject
+	}
+
+	return c.ID
 }
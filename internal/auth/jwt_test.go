@@ -7,6 +7,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 )
 
 func TestJWT(t *testing.T) {
@@ -25,6 +26,29 @@ func TestJWT(t *testing.T) {
 		assert.Equal(t, u.Namespaces, []string{"namespace1", "namespace2"})
 		assert.Equal(t, u.Kind, MachineToken)
 		assert.True(t, u.ExpiresAt.After(time.Now()))
+		assert.NotEmpty(t, u.TokenID)
+		assert.NotEqual(t, u.TokenID, u.Username)
+	})
+
+	t.Run("revoked token", func(t *testing.T) {
+		u, err := th.Validate(token)
+		require.NoError(t, err)
+
+		d := NewDenyList()
+		rth := NewTokenHandler("thesecret", "issuer", WithDenyList(d))
+
+		_, err = rth.Validate(token)
+		require.NoError(t, err)
+
+		d.Add(u.TokenID)
+
+		_, err = rth.Validate(token)
+		assert.ErrorIs(t, err, ErrTokenRevoked)
+
+		d.Remove(u.TokenID)
+
+		_, err = rth.Validate(token)
+		assert.NoError(t, err)
 	})
 
 	t.Run("invalid token - wrong issuer", func(t *testing.T) {
@@ -42,6 +66,26 @@ func TestJWT(t *testing.T) {
 	})
 }
 
+func TestScrapeToken(t *testing.T) {
+	th := NewTokenHandler("thesecret", "issuer")
+
+	token, err := th.CreateScrape("prometheus", 0, []string{"prometheus1"}, "namespace1")
+	require.NoError(t, err)
+
+	u, err := th.Validate(token)
+	require.NoError(t, err)
+	assert.Equal(t, ScrapeToken, u.Kind)
+	assert.Equal(t, "scrape", u.Kind.String())
+	assert.True(t, u.IsScrape())
+	assert.False(t, u.IsMachine())
+	assert.Equal(t, []string{"prometheus1"}, u.Servers)
+	assert.Equal(t, []string{"namespace1"}, u.Namespaces)
+
+	claims, err := th.Claims(token)
+	require.NoError(t, err)
+	assert.Equal(t, ScopeSD, claims.Scope)
+}
+
 func TestJWTCompatibility(t *testing.T) {
 
 	/*
@@ -75,8 +119,10 @@ func TestJWTCompatibility(t *testing.T) {
 	th := NewTokenHandler("thesecret", "issuer")
 
 	t.Run("valid old token", func(t *testing.T) {
-		_, err := th.Validate(oldToken)
-		assert.NoError(t, err)
+		u, err := th.Validate(oldToken)
+		require.NoError(t, err)
+		assert.Equal(t, "username", u.Username)
+		assert.Equal(t, "username", u.TokenID)
 	})
 
 	t.Run("valid token", func(t *testing.T) {
@@ -84,3 +130,41 @@ func TestJWTCompatibility(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestLegacyToken(t *testing.T) {
+	// earlier versions created the token handler with the issuer as secret
+	token, err := NewTokenHandler("issuer", "thesecret").Create("username", time.Hour, "namespace1")
+	require.NoError(t, err)
+
+	th := NewTokenHandler("thesecret", "issuer")
+
+	ok, err := th.IsMachine(token)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = th.Validate(token)
+	assert.Error(t, err)
+
+	d := NewDenyList()
+	lth := NewTokenHandler("thesecret", "issuer", WithLegacyTokens(zap.NewNop().Sugar()), WithDenyList(d))
+
+	ok, err = lth.IsMachine(token)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	u, err := lth.Validate(token)
+	require.NoError(t, err)
+	assert.Equal(t, "username", u.Username)
+	assert.Equal(t, []string{"namespace1"}, u.Namespaces)
+
+	d.Add(u.TokenID)
+
+	_, err = lth.Validate(token)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+
+	forged, err := NewTokenHandler("issuer", "othersecret").Create("username", time.Hour, "*")
+	require.NoError(t, err)
+
+	_, err = lth.Validate(forged)
+	assert.Error(t, err, "issuer must be the secret")
+}
@@ -0,0 +1,325 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+// Key is an asymmetric key to sign or verify machine tokens.
+type Key struct {
+	// ID is the JWK thumbprint (RFC 7638) of the public key. It is used as kid.
+	ID     string
+	Method jwt.SigningMethod
+	Public crypto.PublicKey
+	signer crypto.Signer
+}
+
+// NewKey creates a key from a public key, or from a private key (crypto.Signer).
+// Supported are RSA, ECDSA (P-256, P-384, P-521) and Ed25519 keys.
+func NewKey(k interface{}) (*Key, error) {
+	key := Key{}
+
+	if s, ok := k.(crypto.Signer); ok {
+		key.signer = s
+		k = s.Public()
+	}
+
+	switch pub := k.(type) {
+	case *rsa.PublicKey:
+		key.Method = jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256():
+			key.Method = jwt.SigningMethodES256
+		case elliptic.P384():
+			key.Method = jwt.SigningMethodES384
+		case elliptic.P521():
+			key.Method = jwt.SigningMethodES512
+		default:
+			return nil, fmt.Errorf("unsupported elliptic curve %s", pub.Curve.Params().Name)
+		}
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", k)
+	}
+
+	key.Public = k
+
+	id, err := key.JWK().Thumbprint()
+	if err != nil {
+		return nil, err
+	}
+
+	key.ID = id
+
+	return &key, nil
+}
+
+// JWK returns the public key as json web key.
+func (k Key) JWK() JWK {
+	j := JWK{
+		Use: "sig",
+		Alg: k.Method.Alg(),
+		Kid: k.ID,
+	}
+
+	switch pub := k.Public.(type) {
+	case *rsa.PublicKey:
+		j.Kty = "RSA"
+		j.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		j.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		j.Kty = "EC"
+		j.Crv = pub.Curve.Params().Name
+		j.X = base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, size)))
+		j.Y = base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		j.Kty = "OKP"
+		j.Crv = "Ed25519"
+		j.X = base64.RawURLEncoding.EncodeToString(pub)
+	}
+
+	return j
+}
+
+// JWK is a public json web key (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// Thumbprint returns the base64url encoded SHA-256 JWK thumbprint (RFC 7638).
+func (j JWK) Thumbprint() (string, error) {
+	var members interface{}
+
+	// the required members in lexicographic order
+	switch j.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{j.E, j.Kty, j.N}
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{j.Crv, j.Kty, j.X, j.Y}
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{j.Crv, j.Kty, j.X}
+	default:
+		return "", fmt.Errorf("unsupported key type %s", j.Kty)
+	}
+
+	d, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(d)
+
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// JWKS is a json web key set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// KeySet holds the key to sign machine tokens and all keys to verify them.
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+// NewKeySet creates a new key set. The signing key is optional. If set, it is also used
+// for verification. All verification keys must be public keys or private keys.
+func NewKeySet(signing crypto.Signer, verification ...interface{}) (*KeySet, error) {
+	ks := KeySet{
+		keys: make(map[string]*Key),
+	}
+
+	if signing != nil {
+		k, err := NewKey(signing)
+		if err != nil {
+			return nil, fmt.Errorf("signing key: %w", err)
+		}
+
+		ks.signing = k
+		ks.keys[k.ID] = k
+	}
+
+	for _, v := range verification {
+		k, err := NewKey(v)
+		if err != nil {
+			return nil, fmt.Errorf("verification key: %w", err)
+		}
+
+		if _, ok := ks.keys[k.ID]; !ok {
+			ks.keys[k.ID] = &Key{
+				ID:     k.ID,
+				Method: k.Method,
+				Public: k.Public,
+			}
+		}
+	}
+
+	return &ks, nil
+}
+
+// LoadKeySet loads a key set from pem files. The signing key file must contain a private
+// key. If signingKeyFile is empty, tokens are not signed with the key set. Verification key
+// files can contain public keys, certificates or private keys.
+func LoadKeySet(signingKeyFile string, verificationKeyFiles ...string) (*KeySet, error) {
+	var signing crypto.Signer
+
+	if signingKeyFile != "" {
+		keys, err := readPEMKeys(signingKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(keys) != 1 {
+			return nil, fmt.Errorf("%s: expected one private key, found %d keys", signingKeyFile, len(keys))
+		}
+
+		s, ok := keys[0].(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("%s: not a private key", signingKeyFile)
+		}
+
+		signing = s
+	}
+
+	verification := []interface{}{}
+
+	for _, f := range verificationKeyFiles {
+		keys, err := readPEMKeys(f)
+		if err != nil {
+			return nil, err
+		}
+
+		verification = append(verification, keys...)
+	}
+
+	return NewKeySet(signing, verification...)
+}
+
+// SigningKey returns the signing key or nil, if the key set has no signing key.
+func (ks *KeySet) SigningKey() *Key {
+	return ks.signing
+}
+
+// Key returns the verification key with key id kid.
+func (ks *KeySet) Key(kid string) (*Key, bool) {
+	k, ok := ks.keys[kid]
+
+	return k, ok
+}
+
+// JWKS returns all verification keys as json web key set sorted by key id.
+func (ks *KeySet) JWKS() JWKS {
+	j := JWKS{
+		Keys: []JWK{},
+	}
+
+	if ks == nil {
+		return j
+	}
+
+	for _, k := range ks.keys {
+		j.Keys = append(j.Keys, k.JWK())
+	}
+
+	sort.Slice(j.Keys, func(i, k int) bool {
+		return j.Keys[i].Kid < j.Keys[k].Kid
+	})
+
+	return j
+}
+
+// readPEMKeys reads all keys in a pem file.
+func readPEMKeys(path string) ([]interface{}, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path is configured by the administrator
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []interface{}{}
+
+	for {
+		var block *pem.Block
+
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		k, err := parsePEMBlock(block)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		keys = append(keys, k)
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s: no pem encoded keys found", path)
+	}
+
+	return keys, nil
+}
+
+func parsePEMBlock(b *pem.Block) (interface{}, error) {
+	switch b.Type {
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(b.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(b.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(b.Bytes)
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(b.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(b.Bytes)
+	case "CERTIFICATE":
+		c, err := x509.ParseCertificate(b.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		return c.PublicKey, nil
+	default:
+		return nil, errors.New("unsupported pem block type " + b.Type)
+	}
+}
@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThumbprint(t *testing.T) {
+	// example from RFC 7638 section 3.1
+	j := JWK{
+		Kty: "RSA",
+		N:   "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		E:   "AQAB",
+	}
+
+	tp, err := j.Thumbprint()
+	require.NoError(t, err)
+	assert.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", tp)
+}
+
+func TestKeySet(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name string
+		key  crypto.Signer
+		alg  string
+	}{
+		{"rsa", rsaKey, "RS256"},
+		{"ecdsa", ecKey, "ES256"},
+		{"ed25519", edKey, "EdDSA"},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			ks, err := NewKeySet(tc.key)
+			require.NoError(t, err)
+
+			th := NewTokenHandler("", "issuer", WithKeySet(ks))
+
+			token, err := th.Create("username", time.Hour, "namespace1")
+			require.NoError(t, err)
+
+			header := jwtHeader(t, token)
+			assert.Equal(t, tc.alg, header.Method.Alg())
+			assert.Equal(t, ks.SigningKey().ID, header.Header["kid"])
+
+			u, err := th.Validate(token)
+			require.NoError(t, err)
+			assert.Equal(t, "username", u.Username)
+
+			jwks := ks.JWKS()
+			require.Len(t, jwks.Keys, 1)
+			assert.Equal(t, tc.alg, jwks.Keys[0].Alg)
+			assert.Equal(t, ks.SigningKey().ID, jwks.Keys[0].Kid)
+		})
+	}
+
+	t.Run("rotation", func(t *testing.T) {
+		oldKS, err := NewKeySet(rsaKey)
+		require.NoError(t, err)
+
+		oldToken, err := NewTokenHandler("", "issuer", WithKeySet(oldKS)).Create("old", 0)
+		require.NoError(t, err)
+
+		ks, err := NewKeySet(ecKey, rsaKey.Public())
+		require.NoError(t, err)
+
+		th := NewTokenHandler("", "issuer", WithKeySet(ks))
+
+		newToken, err := th.Create("new", 0)
+		require.NoError(t, err)
+
+		_, err = th.Validate(oldToken)
+		assert.NoError(t, err)
+
+		_, err = th.Validate(newToken)
+		assert.NoError(t, err)
+
+		_, err = NewTokenHandler("", "issuer", WithKeySet(oldKS)).Validate(newToken)
+		assert.Error(t, err)
+
+		assert.Len(t, ks.JWKS().Keys, 2)
+	})
+
+	t.Run("hmac", func(t *testing.T) {
+		ks, err := NewKeySet(nil, rsaKey.Public())
+		require.NoError(t, err)
+
+		token, err := NewTokenHandler("thesecret", "issuer").Create("username", 0)
+		require.NoError(t, err)
+
+		_, err = NewTokenHandler("thesecret", "issuer", WithKeySet(ks)).Validate(token)
+		assert.NoError(t, err)
+
+		_, err = NewTokenHandler("", "issuer", WithKeySet(ks)).Validate(token)
+		assert.Error(t, err)
+
+		_, err = NewTokenHandler("", "issuer", WithKeySet(ks)).Create("username", 0)
+		assert.Error(t, err)
+	})
+}
+
+func TestLoadKeySet(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	signing := filepath.Join(dir, "signing.pem")
+	writePEM(t, signing, pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+
+	ecDER, err := x509.MarshalECPrivateKey(ecKey)
+	require.NoError(t, err)
+
+	edDER, err := x509.MarshalPKIXPublicKey(edPub)
+	require.NoError(t, err)
+
+	verification := filepath.Join(dir, "verification.pem")
+	writePEM(t, verification,
+		pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDER},
+		pem.Block{Type: "PUBLIC KEY", Bytes: edDER},
+	)
+
+	ks, err := LoadKeySet(signing, verification)
+	require.NoError(t, err)
+	require.NotNil(t, ks.SigningKey())
+	assert.Equal(t, jwt.SigningMethodRS256, ks.SigningKey().Method)
+	assert.Len(t, ks.JWKS().Keys, 3)
+
+	edKS, err := NewKeySet(edKey)
+	require.NoError(t, err)
+
+	k, ok := ks.Key(edKS.SigningKey().ID)
+	require.True(t, ok)
+	assert.Equal(t, jwt.SigningMethodEdDSA, k.Method)
+
+	t.Run("public signing key", func(t *testing.T) {
+		_, err := LoadKeySet(verification)
+		assert.Error(t, err)
+	})
+
+	t.Run("no keys", func(t *testing.T) {
+		empty := filepath.Join(dir, "empty.pem")
+		require.NoError(t, os.WriteFile(empty, []byte("no pem"), 0o600))
+
+		_, err := LoadKeySet("", empty)
+		assert.Error(t, err)
+	})
+}
+
+func writePEM(t *testing.T, path string, blocks ...pem.Block) {
+	t.Helper()
+
+	b := strings.Builder{}
+
+	for i := range blocks {
+		require.NoError(t, pem.Encode(&b, &blocks[i]))
+	}
+
+	require.NoError(t, os.WriteFile(path, []byte(b.String()), 0o600))
+}
+
+func jwtHeader(t *testing.T, token string) *jwt.Token {
+	t.Helper()
+
+	tkn, _, err := new(jwt.Parser).ParseUnverified(token, &TokenClaims{})
+	require.NoError(t, err)
+
+	return tkn
+}
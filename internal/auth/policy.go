@@ -0,0 +1,351 @@
+package auth
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/postfinance/discovery"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+)
+
+// Roles every user gets additionally to the roles from the oidc token.
+const (
+	// RoleAuthenticated is the role of all authenticated users and machines.
+	RoleAuthenticated = "system:authenticated"
+	// RoleMachine is the role of all machine and client credentials tokens.
+	RoleMachine = "system:machine"
+)
+
+const (
+	methodPrefix = "/postfinance.discovery.v1."
+	scrapeMethod = methodPrefix + "ServiceAPI/ListTargetGroup"
+)
+
+// Policy maps roles to the rules that grant access to api methods.
+type Policy struct {
+	Roles map[string][]Rule `yaml:"roles"`
+}
+
+// Rule allows to call methods on namespaces and servers. All fields contain patterns
+// as understood by path.Match. An empty list or "*" matches everything. Methods without
+// a leading slash are relative to package postfinance.discovery.v1 (i.e. ServiceAPI/*).
+// Kinds restricts the rule to token kinds (user, machine or client).
+type Rule struct {
+	Methods    []string `yaml:"methods"`
+	Namespaces []string `yaml:"namespaces,omitempty"`
+	Servers    []string `yaml:"servers,omitempty"`
+	Kinds      []string `yaml:"kinds,omitempty"`
+}
+
+// Request is an api call to authorize.
+type Request struct {
+	Method     string
+	Namespaces []string
+	Servers    []string
+}
+
+// DefaultPolicy is the policy if no policy file is configured: users with one of rwRoles
+// can call all methods, all other users and machines can manage services and list
+// namespaces and servers. Machine and client credentials tokens with one of rwRoles
+// are not granted all methods.
+func DefaultPolicy(rwRoles ...string) *Policy {
+	p := Policy{
+		Roles: map[string][]Rule{
+			RoleAuthenticated: {
+				{
+					Methods: []string{
+						"ServiceAPI/*",
+						"NamespaceAPI/ListNamespace",
+						"NamespaceAPI/ListDeletedNamespace",
+						"ServerAPI/ListServer",
+						"TokenAPI/Info",
+					},
+				},
+			},
+		},
+	}
+
+	for _, r := range rwRoles {
+		p.Roles[r] = []Rule{
+			{
+				Methods: []string{"*"},
+				Kinds:   []string{UserToken.String()},
+			},
+		}
+	}
+
+	return &p
+}
+
+// LoadPolicy loads a yaml policy file.
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path is configured by the administrator
+	if err != nil {
+		return nil, err
+	}
+
+	return ParsePolicy(data)
+}
+
+// ParsePolicy parses and validates a yaml policy.
+func ParsePolicy(data []byte) (*Policy, error) {
+	p := Policy{}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("failed to decode policy: %w", err)
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// Validate checks all patterns of the policy.
+func (p Policy) Validate() error {
+	for role, rules := range p.Roles {
+		for i, r := range rules {
+			if len(r.Methods) == 0 {
+				return fmt.Errorf("role %s: rule %d: no methods", role, i)
+			}
+
+			for _, k := range r.Kinds {
+				if k != UserToken.String() && k != MachineToken.String() && k != ClientToken.String() {
+					return fmt.Errorf("role %s: rule %d: unknown kind '%s'", role, i, k)
+				}
+			}
+
+			for _, patterns := range [][]string{r.Methods, r.Namespaces, r.Servers} {
+				for _, pattern := range patterns {
+					if _, err := path.Match(pattern, ""); err != nil {
+						return fmt.Errorf("role %s: rule %d: pattern '%s': %w", role, i, pattern, err)
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Authorize evaluates if user u is allowed to do request r. The reason describes
+// which role allowed the request or why it is denied.
+func (p Policy) Authorize(u User, r Request) (allowed bool, reason string) {
+	if u.IsScrape() {
+		return authorizeScrape(u, r)
+	}
+
+	roles := u.policyRoles()
+
+	for _, role := range roles {
+		for _, rule := range p.Roles[role] {
+			if rule.matches(r, u.Kind) {
+				return true, fmt.Sprintf("allowed by role %s", role)
+			}
+		}
+	}
+
+	return false, fmt.Sprintf("no rule of roles [%s] allows %s", strings.Join(roles, ", "), r)
+}
+
+// authorizeScrape allows scrape tokens to list target groups of their servers. The
+// namespaces are checked when reading the services.
+func authorizeScrape(u User, r Request) (allowed bool, reason string) {
+	if r.Method != scrapeMethod {
+		return false, fmt.Sprintf("scrape tokens can only call %s", scrapeMethod)
+	}
+
+	if !matchAll(u.Servers, r.Servers) {
+		return false, fmt.Sprintf("scrape token is restricted to servers %s", strings.Join(u.Servers, ","))
+	}
+
+	return true, "allowed by scrape token"
+}
+
+// String returns a human readable representation of the request.
+func (r Request) String() string {
+	s := r.Method
+
+	if len(r.Namespaces) > 0 {
+		s += " in namespaces " + strings.Join(r.Namespaces, ",")
+	}
+
+	if len(r.Servers) > 0 {
+		s += " on servers " + strings.Join(r.Servers, ",")
+	}
+
+	return s
+}
+
+func (r Rule) matches(req Request, kind TokenKind) bool {
+	if len(r.Kinds) > 0 && !contains(r.Kinds, kind.String()) {
+		return false
+	}
+
+	methodMatches := false
+
+	for _, m := range r.Methods {
+		if m == "*" || match(FullMethod(m), req.Method) {
+			methodMatches = true
+			break
+		}
+	}
+
+	return methodMatches && matchAll(r.Namespaces, req.Namespaces) && matchAll(r.Servers, req.Servers)
+}
+
+// matchAll returns true, if all values match one of the patterns.
+func matchAll(patterns, values []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+
+	for _, v := range values {
+		ok := false
+
+		for _, p := range patterns {
+			if match(p, v) {
+				ok = true
+				break
+			}
+		}
+
+		if !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+func match(pattern, s string) bool {
+	ok, _ := path.Match(pattern, s)
+
+	return ok
+}
+
+// FullMethod returns the full grpc method name for methods relative to package
+// postfinance.discovery.v1.
+func FullMethod(m string) string {
+	if strings.HasPrefix(m, "/") {
+		return m
+	}
+
+	return methodPrefix + m
+}
+
+// policyRoles returns the user's roles with the system roles.
+func (u User) policyRoles() []string {
+	roles := append([]string{}, u.Roles...)
+	sort.Strings(roles)
+
+	if u.IsMachine() || u.IsClient() {
+		roles = append(roles, RoleMachine)
+	}
+
+	return append(roles, RoleAuthenticated)
+}
+
+// requestFromMessage extracts the addressed namespaces and servers from a grpc request
+// message: fields namespace and server, the name field of namespace and server api
+// requests, repeated fields namespaces and servers (token api) and the same fields of
+// repeated messages (bulk requests). Service api calls
+// that change services without namespace address the default namespace.
+func requestFromMessage(method string, msg interface{}) Request {
+	r := Request{
+		Method: method,
+	}
+
+	m, ok := msg.(proto.Message)
+	if !ok {
+		return r
+	}
+
+	var (
+		nameIs           string
+		defaultNamespace string
+	)
+
+	service, name := path.Split(method)
+
+	switch service {
+	case methodPrefix + "NamespaceAPI/":
+		nameIs = "namespace"
+	case methodPrefix + "ServerAPI/":
+		nameIs = "server"
+	case methodPrefix + "ServiceAPI/":
+		if !strings.HasPrefix(name, "List") && !strings.HasPrefix(name, "Watch") {
+			defaultNamespace = discovery.DefaultNamespace().Name
+		}
+	}
+
+	var collect func(protoreflect.Message)
+
+	collect = func(pm protoreflect.Message) {
+		fields := pm.Descriptor().Fields()
+
+		for i := 0; i < fields.Len(); i++ {
+			fd := fields.Get(i)
+			v := pm.Get(fd)
+
+			switch {
+			case fd.IsList() && fd.Kind() == protoreflect.MessageKind:
+				l := v.List()
+				for i := 0; i < l.Len(); i++ {
+					collect(l.Get(i).Message())
+				}
+			case fd.IsList() && fd.Kind() == protoreflect.StringKind && (fd.Name() == "namespaces" || fd.Name() == "servers"):
+				l := v.List()
+				for i := 0; i < l.Len(); i++ {
+					if fd.Name() == "namespaces" {
+						r.Namespaces = appendUnique(r.Namespaces, l.Get(i).String())
+					} else {
+						r.Servers = appendUnique(r.Servers, l.Get(i).String())
+					}
+				}
+			case fd.IsList() || fd.IsMap() || fd.Kind() != protoreflect.StringKind:
+			case fd.Name() == "namespace" && v.String() == "" && defaultNamespace != "":
+				r.Namespaces = appendUnique(r.Namespaces, defaultNamespace)
+			case v.String() == "":
+			case fd.Name() == "namespace" || (fd.Name() == "name" && nameIs == "namespace"):
+				r.Namespaces = appendUnique(r.Namespaces, v.String())
+			case fd.Name() == "server" || (fd.Name() == "name" && nameIs == "server"):
+				r.Servers = appendUnique(r.Servers, v.String())
+			}
+		}
+	}
+
+	collect(m.ProtoReflect())
+
+	return r
+}
+
+func appendUnique(s []string, v string) []string {
+	for _, e := range s {
+		if e == v {
+			return s
+		}
+	}
+
+	return append(s, v)
+}
@@ -0,0 +1,227 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	discoveryv1 "github.com/postfinance/discovery/pkg/discoverypb/postfinance/discovery/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const testPolicy = `
+roles:
+  ops:
+    - methods: ["*"]
+  team-a:
+    - methods: ["ServiceAPI/*"]
+      namespaces: ["team-a", "team-a-*"]
+    - methods: ["ServiceAPI/ListTargetGroup"]
+      servers: ["prometheus-a*"]
+    - methods: ["TokenAPI/Create"]
+      namespaces: ["team-a-*"]
+      servers: ["prometheus-a*"]
+  system:authenticated:
+    - methods: ["NamespaceAPI/List*", "ServerAPI/ListServer"]
+`
+
+func TestPolicy(t *testing.T) {
+	p, err := ParsePolicy([]byte(testPolicy))
+	require.NoError(t, err)
+
+	ops := User{Username: "ops", Roles: []string{"ops"}, Kind: UserToken}
+	teamA := User{Username: "a", Roles: []string{"team-a"}, Kind: UserToken}
+	machine := User{Username: "m", Namespaces: []string{"team-a"}, Kind: MachineToken}
+
+	tt := []struct {
+		name    string
+		user    User
+		method  string
+		req     interface{}
+		allowed bool
+	}{
+		{"ops registers server", ops, "ServerAPI/RegisterServer", &discoveryv1.RegisterServerRequest{Name: "prometheus1"}, true},
+		{"team registers server", teamA, "ServerAPI/RegisterServer", &discoveryv1.RegisterServerRequest{Name: "prometheus1"}, false},
+		{"team lists servers", teamA, "ServerAPI/ListServer", &discoveryv1.ListServerRequest{}, true},
+		{"team registers service", teamA, "ServiceAPI/RegisterService", &discoveryv1.RegisterServiceRequest{Namespace: "team-a-dev"}, true},
+		{"team registers service in other namespace", teamA, "ServiceAPI/RegisterService", &discoveryv1.RegisterServiceRequest{Namespace: "team-b"}, false},
+		{"team registers service in default namespace", teamA, "ServiceAPI/RegisterService", &discoveryv1.RegisterServiceRequest{}, false},
+		{"team registers services in bulk", teamA, "ServiceAPI/RegisterServices", &discoveryv1.RegisterServicesRequest{
+			Services: []*discoveryv1.RegisterServiceRequest{{Namespace: "team-a"}, {Namespace: "team-a-dev"}},
+		}, true},
+		{"team registers services in bulk in other namespace", teamA, "ServiceAPI/RegisterServices", &discoveryv1.RegisterServicesRequest{
+			Services: []*discoveryv1.RegisterServiceRequest{{Namespace: "team-a"}, {Namespace: "team-b"}},
+		}, false},
+		{"team lists target groups of server", teamA, "ServiceAPI/ListTargetGroup", &discoveryv1.ListTargetGroupRequest{Server: "prometheus-a1", Namespace: "team-b"}, true},
+		{"team lists target groups of other server", teamA, "ServiceAPI/ListTargetGroup", &discoveryv1.ListTargetGroupRequest{Server: "prometheus-b1", Namespace: "team-b"}, false},
+		{"team creates token", teamA, "TokenAPI/Create", &discoveryv1.CreateRequest{Id: "a", Namespaces: []string{"team-a-dev"}}, true},
+		{"team creates token for all namespaces", teamA, "TokenAPI/Create", &discoveryv1.CreateRequest{Id: "a", Namespaces: []string{"*"}}, false},
+		{"team creates token for other namespace", teamA, "TokenAPI/Create", &discoveryv1.CreateRequest{Id: "a", Namespaces: []string{"team-a-dev", "team-b"}}, false},
+		{"team creates scrape token", teamA, "TokenAPI/Create", &discoveryv1.CreateRequest{Id: "a", Namespaces: []string{"team-a-dev"}, Scope: ScopeSD, Servers: []string{"prometheus-a1"}}, true},
+		{"team creates scrape token for other server", teamA, "TokenAPI/Create", &discoveryv1.CreateRequest{Id: "a", Namespaces: []string{"team-a-dev"}, Scope: ScopeSD, Servers: []string{"prometheus-b1"}}, false},
+		{"team unregisters namespace", teamA, "NamespaceAPI/UnregisterNamespace", &discoveryv1.UnregisterNamespaceRequest{Name: "team-a"}, false},
+		{"machine lists namespaces", machine, "NamespaceAPI/ListNamespace", &discoveryv1.ListNamespaceRequest{}, true},
+		{"machine registers service", machine, "ServiceAPI/RegisterService", &discoveryv1.RegisterServiceRequest{Namespace: "team-a"}, false},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			r := requestFromMessage(FullMethod(tc.method), tc.req)
+
+			allowed, reason := p.Authorize(tc.user, r)
+			assert.Equal(t, tc.allowed, allowed, reason)
+		})
+	}
+}
+
+func TestDefaultPolicy(t *testing.T) {
+	p := DefaultPolicy("admin")
+
+	admin := User{Username: "admin", Roles: []string{"admin"}, Kind: UserToken}
+	user := User{Username: "user", Kind: UserToken}
+	machine := User{Username: "m", Roles: []string{"admin"}, Kind: MachineToken}
+
+	for _, m := range []string{
+		"NamespaceAPI/RegisterNamespace",
+		"NamespaceAPI/UnregisterNamespace",
+		"NamespaceAPI/RestoreNamespace",
+		"ServerAPI/RegisterServer",
+		"ServerAPI/UnregisterServer",
+		"ServerAPI/Rebalance",
+		"ServerAPI/DrainServer",
+		"AuditAPI/ListAuditRecord",
+		"TokenAPI/Create",
+		"TokenAPI/Revoke",
+		"TokenAPI/List",
+	} {
+		r := Request{Method: FullMethod(m)}
+
+		allowed, _ := p.Authorize(admin, r)
+		assert.True(t, allowed, m)
+
+		allowed, _ = p.Authorize(user, r)
+		assert.False(t, allowed, m)
+	}
+
+	for _, m := range []string{
+		"ServiceAPI/RegisterService",
+		"ServiceAPI/WatchServices",
+		"NamespaceAPI/ListNamespace",
+		"ServerAPI/ListServer",
+		"TokenAPI/Info",
+	} {
+		r := Request{Method: FullMethod(m), Namespaces: []string{"default"}}
+
+		allowed, _ := p.Authorize(user, r)
+		assert.True(t, allowed, m)
+
+		allowed, _ = p.Authorize(machine, r)
+		assert.True(t, allowed, m)
+	}
+
+	t.Run("machine tokens have no oidc roles", func(t *testing.T) {
+		m := User{Username: "m", Kind: MachineToken}
+		allowed, _ := p.Authorize(m, Request{Method: FullMethod("ServerAPI/RegisterServer")})
+		assert.False(t, allowed)
+	})
+
+	t.Run("rw roles are restricted to users", func(t *testing.T) {
+		client := User{Username: "ci", Roles: []string{"admin"}, Kind: ClientToken}
+
+		for _, u := range []User{machine, client} {
+			for _, m := range []string{"ServerAPI/RegisterServer", "TokenAPI/Create", "NamespaceAPI/UnregisterNamespace"} {
+				allowed, reason := p.Authorize(u, Request{Method: FullMethod(m)})
+				assert.False(t, allowed, "%s %s: %s", u.Kind, m, reason)
+			}
+		}
+	})
+}
+
+func TestScrapePolicy(t *testing.T) {
+	p := DefaultPolicy("admin")
+
+	scrape := User{Username: "prometheus", Roles: []string{"admin"}, Namespaces: []string{"default"}, Servers: []string{"prometheus1"}, Kind: ScrapeToken}
+	all := User{Username: "prometheus", Namespaces: []string{"default"}, Kind: ScrapeToken}
+
+	tt := []struct {
+		name    string
+		user    User
+		method  string
+		req     interface{}
+		allowed bool
+	}{
+		{"list target groups", scrape, "ServiceAPI/ListTargetGroup", &discoveryv1.ListTargetGroupRequest{Server: "prometheus1", Namespace: "default"}, true},
+		{"list target groups of other server", scrape, "ServiceAPI/ListTargetGroup", &discoveryv1.ListTargetGroupRequest{Server: "prometheus2", Namespace: "default"}, false},
+		{"list target groups of all servers", all, "ServiceAPI/ListTargetGroup", &discoveryv1.ListTargetGroupRequest{Server: "prometheus2", Namespace: "default"}, true},
+		{"list services", scrape, "ServiceAPI/ListService", &discoveryv1.ListServiceRequest{Namespace: "default"}, false},
+		{"register service", scrape, "ServiceAPI/RegisterService", &discoveryv1.RegisterServiceRequest{Namespace: "default"}, false},
+		{"register server", scrape, "ServerAPI/RegisterServer", &discoveryv1.RegisterServerRequest{Name: "prometheus1"}, false},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			allowed, reason := p.Authorize(tc.user, requestFromMessage(FullMethod(tc.method), tc.req))
+			assert.Equal(t, tc.allowed, allowed, reason)
+		})
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	_, err := ParsePolicy([]byte("roles:\n  ops:\n    - namespaces: [default]\n"))
+	assert.Error(t, err, "no methods")
+
+	_, err = ParsePolicy([]byte("roles:\n  ops:\n    - methods: [\"[\"]\n"))
+	assert.Error(t, err, "bad pattern")
+
+	_, err = ParsePolicy([]byte("roles:\n  ops:\n    - method: [\"*\"]\n"))
+	assert.Error(t, err, "unknown field")
+
+	_, err = ParsePolicy([]byte("roles:\n  ops:\n    - methods: [\"*\"]\n      kinds: [scrape]\n"))
+	assert.Error(t, err, "unknown kind")
+}
+
+func TestFileAuthorizer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testPolicy), 0o600))
+
+	a, err := NewFileAuthorizer(path, zap.NewNop().Sugar())
+	require.NoError(t, err)
+
+	u := User{Username: "b", Roles: []string{"team-b"}, Kind: UserToken}
+	r := Request{Method: FullMethod("ServiceAPI/RegisterService"), Namespaces: []string{"team-b"}}
+
+	allowed, _ := a.Authorize(u, r)
+	assert.False(t, allowed)
+
+	changed, err := a.Reload()
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	require.NoError(t, os.WriteFile(path, []byte(testPolicy+`  team-b:
+    - methods: ["ServiceAPI/*"]
+      namespaces: ["team-b"]
+`), 0o600))
+
+	changed, err = a.Reload()
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	allowed, _ = a.Authorize(u, r)
+	assert.True(t, allowed)
+
+	t.Run("invalid policy is not loaded", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte("roles: ["), 0o600))
+
+		_, err := a.Reload()
+		assert.Error(t, err)
+
+		allowed, _ := a.Authorize(u, r)
+		assert.True(t, allowed)
+	})
+}
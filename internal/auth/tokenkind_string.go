@@ -10,11 +10,13 @@ func _() {
 	var x [1]struct{}
 	_ = x[MachineToken-0]
 	_ = x[UserToken-1]
+	_ = x[ScrapeToken-2]
+	_ = x[ClientToken-3]
 }
 
-const _TokenKind_name = "machineuser"
+const _TokenKind_name = "machineuserscrapeclient"
 
-var _TokenKind_index = [...]uint8{0, 7, 11}
+var _TokenKind_index = [...]uint8{0, 7, 11, 17, 23}
 
 func (i TokenKind) String() string {
 	if i < 0 || i >= TokenKind(len(_TokenKind_index)-1) {
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"path"
 	"time"
 )
 
@@ -13,6 +14,8 @@ type User struct {
 	Namespaces []string
 	ExpiresAt  time.Time
 	Kind       TokenKind
+	TokenID    string   // the unique id (jti) of machine tokens
+	Servers    []string // the servers of scrape tokens
 }
 
 // IsUser returns true if the token corresponds to a user token and
@@ -27,6 +30,16 @@ func (u User) IsMachine() bool {
 	return u.Kind == MachineToken
 }
 
+// IsClient returns true if the token is an oidc client credentials token.
+func (u User) IsClient() bool {
+	return u.Kind == ClientToken
+}
+
+// IsScrape returns true if the token is a read-only scrape token.
+func (u User) IsScrape() bool {
+	return u.Kind == ScrapeToken
+}
+
 // UserFromContext gets user from context.
 func UserFromContext(ctx context.Context) (User, bool) {
 	userPtr, ok := ctx.Value(userKey).(*User)
@@ -52,11 +65,12 @@ func (u User) HasRole(roles ...string) bool {
 	return false
 }
 
-// HasNamespace returns true if user has one of namespaces.
+// HasNamespace returns true if user has one of namespaces. The user's namespaces
+// can be patterns as understood by path.Match.
 func (u User) HasNamespace(namespaces ...string) bool {
 	for _, un := range u.Namespaces {
-		for _, u := range namespaces {
-			if u == un {
+		for _, n := range namespaces {
+			if ok, _ := path.Match(un, n); ok {
 				return true
 			}
 		}
@@ -65,12 +79,15 @@ func (u User) HasNamespace(namespaces ...string) bool {
 	return false
 }
 
-// TokenKind defines the kind of token. There are two possible tokens: machine and users.
+// TokenKind defines the kind of token. There are four possible tokens: machine, users, scrape and client.
 type TokenKind int
 
-// Two possible tokens: machine and users. User tokens are issued by oidc provider, where machine
-// tokens are issued by discovery service.
+// Four possible tokens: machine, users, scrape and client. User and client (client credentials) tokens are
+// issued by oidc provider, where machine and scrape tokens are issued by discovery service. Scrape tokens can
+// only list target groups.
 const (
 	MachineToken TokenKind = iota // machine
 	UserToken                     // user
+	ScrapeToken                   // scrape
+	ClientToken                   // client
 )
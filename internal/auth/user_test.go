@@ -89,6 +89,20 @@ func TestHasNamespace(t *testing.T) {
 			User{},
 			false,
 		},
+		{
+			[]string{"team-a-dev"},
+			User{
+				Namespaces: []string{"team-a-*"},
+			},
+			true,
+		},
+		{
+			[]string{"team-b"},
+			User{
+				Namespaces: []string{"team-a-*"},
+			},
+			false,
+		},
 	}
 
 	for _, tc := range tt {
@@ -0,0 +1,146 @@
+// Package backup creates and restores archives of all namespaces, servers, services and
+// tokens.
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/postfinance/discovery"
+	"github.com/postfinance/discovery/internal/repo"
+	"github.com/postfinance/store"
+	"gopkg.in/yaml.v3"
+)
+
+// Version is the current archive version. Archives of version 1 contain no tokens.
+const Version = 2
+
+// Archive contains all namespaces, servers, services and issued and revoked tokens.
+type Archive struct {
+	Version    int                  `json:"version"`
+	Created    time.Time            `json:"created"`
+	Namespaces discovery.Namespaces `json:"namespaces"`
+	Servers    discovery.Servers    `json:"servers"`
+	Services   discovery.Services   `json:"services"`
+	Tokens     discovery.Tokens     `json:"tokens"`
+}
+
+// Format is the encoding of an archive.
+type Format string
+
+// Supported formats.
+const (
+	JSON Format = "json"
+	YAML Format = "yaml"
+)
+
+// FormatFromPath returns YAML for files with .yaml or .yml extension
+// and JSON otherwise.
+func FormatFromPath(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return YAML
+	default:
+		return JSON
+	}
+}
+
+// Create creates an archive from backend.
+func Create(backend store.Backend) (*Archive, error) {
+	namespaces, err := repo.NewNamespace(backend).List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	servers, err := repo.NewServer(backend).List("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list servers: %w", err)
+	}
+
+	services, err := repo.NewService(backend).List("", "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+
+	tokens, err := repo.NewToken(backend).List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tokens: %w", err)
+	}
+
+	return &Archive{
+		Version:    Version,
+		Created:    time.Now(),
+		Namespaces: namespaces,
+		Servers:    servers,
+		Services:   services,
+		Tokens:     tokens,
+	}, nil
+}
+
+// Write writes the archive in format f to w.
+func (a Archive) Write(w io.Writer, f Format) error {
+	d, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if f == YAML {
+		// the discovery types only implement json marshaling
+		var v interface{}
+
+		if err := yaml.Unmarshal(d, &v); err != nil {
+			return err
+		}
+
+		d, err = yaml.Marshal(v)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = w.Write(d)
+
+	return err
+}
+
+// Read reads an archive in format f from r.
+func Read(r io.Reader, f Format) (*Archive, error) {
+	d, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if f == YAML {
+		var v interface{}
+
+		if err := yaml.Unmarshal(d, &v); err != nil {
+			return nil, err
+		}
+
+		d, err = json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	a := Archive{}
+
+	if err := json.Unmarshal(d, &a); err != nil {
+		return nil, err
+	}
+
+	if !a.supported() {
+		return nil, fmt.Errorf("unsupported archive version %d", a.Version)
+	}
+
+	return &a, nil
+}
+
+// supported returns true for the current and previous archive versions.
+func (a Archive) supported() bool {
+	return a.Version >= 1 && a.Version <= Version
+}
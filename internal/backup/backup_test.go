@@ -0,0 +1,123 @@
+package backup
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/postfinance/discovery"
+	"github.com/postfinance/discovery/internal/repo"
+	"github.com/postfinance/store/hash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupRestore(t *testing.T) {
+	src, err := hash.New(hash.WithPrefix("/discovery"))
+	require.NoError(t, err)
+
+	_, err = repo.NewNamespace(src).Save(discovery.Namespace{Name: "ns1", Export: discovery.Standard, Labels: discovery.Labels{"team": "a"}})
+	require.NoError(t, err)
+
+	_, err = repo.NewServer(src).Save(*discovery.NewServer("server1", discovery.Labels{"env": "prod"}))
+	require.NoError(t, err)
+
+	svc, err := discovery.NewService("svc", "http://host1:8080/metrics")
+	require.NoError(t, err)
+
+	svc.Namespace = "ns1"
+	svc.Servers = []string{"server1"}
+	svc.TTL = time.Minute
+
+	svc, err = repo.NewService(src).Save(*svc)
+	require.NoError(t, err)
+
+	tokens := repo.NewToken(src)
+
+	_, err = tokens.Save(discovery.Token{TokenID: "t1", ID: "machine", Namespaces: []string{"ns1"}, IssuedAt: time.Now()})
+	require.NoError(t, err)
+
+	_, err = tokens.Save(discovery.Token{TokenID: "t2", ID: "revoked", IssuedAt: time.Now(), RevokedAt: time.Now()})
+	require.NoError(t, err)
+
+	a, err := Create(src)
+	require.NoError(t, err)
+	assert.Len(t, a.Namespaces, 1)
+	assert.Len(t, a.Servers, 1)
+	assert.Len(t, a.Services, 1)
+	assert.Len(t, a.Tokens, 2)
+
+	for _, f := range []Format{JSON, YAML} {
+		f := f
+
+		t.Run(string(f), func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			require.NoError(t, a.Write(buf, f))
+
+			restored, err := Read(buf, f)
+			require.NoError(t, err)
+			assert.Equal(t, a.Namespaces[0].Labels, restored.Namespaces[0].Labels)
+			assert.Equal(t, a.Servers[0].Name, restored.Servers[0].Name)
+			assert.Equal(t, svc.Endpoint.String(), restored.Services[0].Endpoint.String())
+			assert.Equal(t, svc.TTL, restored.Services[0].TTL)
+
+			dst, err := hash.New(hash.WithPrefix("/discovery"))
+			require.NoError(t, err)
+
+			r, err := Restore(dst, restored, Fail)
+			require.NoError(t, err)
+			assert.Equal(t, Count{Restored: 1}, r.Services)
+
+			s, err := repo.NewService(dst).Get(svc.ID, "ns1")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"server1"}, s.Servers)
+
+			_, err = repo.NewLease(dst).Get(svc.ID, "ns1")
+			assert.NoError(t, err)
+
+			assert.Equal(t, Count{Restored: 2}, r.Tokens)
+
+			revoked, err := repo.NewToken(dst).Get("t2")
+			require.NoError(t, err)
+			assert.True(t, revoked.IsRevoked(), "revocations are restored")
+
+			_, err = Restore(dst, restored, Fail)
+			assert.Error(t, err)
+
+			r, err = Restore(dst, restored, Skip)
+			require.NoError(t, err)
+			assert.Equal(t, Count{Skipped: 1}, r.Namespaces)
+			assert.Equal(t, Count{Skipped: 1}, r.Services)
+
+			r, err = Restore(dst, restored, Skip)
+			require.NoError(t, err)
+			assert.Equal(t, Count{Skipped: 2}, r.Tokens)
+
+			t1, err := repo.NewToken(dst).Get("t1")
+			require.NoError(t, err)
+
+			t1.RevokedAt = time.Now()
+
+			_, err = repo.NewToken(dst).Save(*t1)
+			require.NoError(t, err)
+
+			r, err = Restore(dst, restored, Overwrite)
+			require.NoError(t, err)
+			assert.Equal(t, Count{Restored: 1}, r.Servers)
+			assert.Equal(t, Count{Restored: 2}, r.Tokens)
+
+			t1, err = repo.NewToken(dst).Get("t1")
+			require.NoError(t, err)
+			assert.True(t, t1.IsRevoked(), "revoked tokens are not overwritten by valid ones")
+		})
+	}
+
+	t.Run("version", func(t *testing.T) {
+		_, err := Read(bytes.NewBufferString(`{"version": 3}`), JSON)
+		assert.Error(t, err)
+
+		a, err := Read(bytes.NewBufferString(`{"version": 1}`), JSON)
+		require.NoError(t, err, "archives without tokens")
+		assert.Empty(t, a.Tokens)
+	})
+}
@@ -0,0 +1,227 @@
+package backup
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/postfinance/discovery"
+	"github.com/postfinance/discovery/internal/repo"
+	"github.com/postfinance/store"
+)
+
+// Policy defines how objects that already exist in the store are handled.
+type Policy string
+
+// Conflict policies.
+const (
+	// Fail aborts the restore before anything is written if an object already exists.
+	Fail Policy = "fail"
+	// Skip keeps existing objects.
+	Skip Policy = "skip"
+	// Overwrite replaces existing objects.
+	Overwrite Policy = "overwrite"
+)
+
+// maxConflicts is the maximum number of conflicts reported in an error.
+const maxConflicts = 10
+
+// Result contains the number of restored and skipped objects.
+type Result struct {
+	Namespaces Count
+	Servers    Count
+	Services   Count
+	Tokens     Count
+}
+
+// Count is the number of restored and skipped objects of one kind.
+type Count struct {
+	Restored int
+	Skipped  int
+}
+
+// Restore writes all namespaces, servers, services and tokens of the archive to backend.
+// Services with a ttl get a new lease. Revoked tokens stay revoked, even if they are
+// overwritten by a token that was not revoked when the archive was created.
+func Restore(backend store.Backend, a *Archive, p Policy) (*Result, error) {
+	if err := a.validate(); err != nil {
+		return nil, err
+	}
+
+	existing, err := Create(backend)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces := map[string]bool{}
+	for _, n := range existing.Namespaces {
+		namespaces[n.Name] = true
+	}
+
+	servers := map[string]bool{}
+	for _, s := range existing.Servers {
+		servers[s.Name] = true
+	}
+
+	services := map[string]bool{}
+	for _, s := range existing.Services {
+		services[s.Namespace+"/"+s.ID] = true
+	}
+
+	tokens := map[string]discovery.Token{}
+	for _, t := range existing.Tokens {
+		tokens[t.TokenID] = t
+	}
+
+	if p == Fail {
+		if err := a.conflicts(namespaces, servers, services, tokens); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		r           = &Result{}
+		namespaceDB = repo.NewNamespace(backend)
+		serverDB    = repo.NewServer(backend)
+		serviceDB   = repo.NewService(backend)
+		leaseDB     = repo.NewLease(backend)
+		tokenDB     = repo.NewToken(backend)
+	)
+
+	for _, n := range a.Namespaces {
+		if namespaces[n.Name] && p == Skip {
+			r.Namespaces.Skipped++
+			continue
+		}
+
+		if _, err := namespaceDB.Save(n); err != nil {
+			return r, fmt.Errorf("failed to restore namespace %s: %w", n.Name, err)
+		}
+
+		r.Namespaces.Restored++
+	}
+
+	for _, s := range a.Servers {
+		if servers[s.Name] && p == Skip {
+			r.Servers.Skipped++
+			continue
+		}
+
+		if _, err := serverDB.Save(s); err != nil {
+			return r, fmt.Errorf("failed to restore server %s: %w", s.Name, err)
+		}
+
+		r.Servers.Restored++
+	}
+
+	for _, s := range a.Services {
+		if services[s.Namespace+"/"+s.ID] && p == Skip {
+			r.Services.Skipped++
+			continue
+		}
+
+		svc, err := serviceDB.Save(s)
+		if err != nil {
+			return r, fmt.Errorf("failed to restore service %s: %w", s.Endpoint, err)
+		}
+
+		if svc.TTL > 0 {
+			if _, err := leaseDB.Save(*discovery.NewLease(svc.ID, svc.Namespace, svc.TTL)); err != nil {
+				return r, fmt.Errorf("failed to restore lease of service %s: %w", s.Endpoint, err)
+			}
+		}
+
+		r.Services.Restored++
+	}
+
+	for _, t := range a.Tokens {
+		current, ok := tokens[t.TokenID]
+		if ok && p == Skip {
+			r.Tokens.Skipped++
+			continue
+		}
+
+		if ok && current.IsRevoked() && !t.IsRevoked() {
+			t.RevokedAt = current.RevokedAt
+		}
+
+		if _, err := tokenDB.Save(t); err != nil {
+			return r, fmt.Errorf("failed to restore token %s: %w", t.TokenID, err)
+		}
+
+		r.Tokens.Restored++
+	}
+
+	return r, nil
+}
+
+func (a Archive) validate() error {
+	if !a.supported() {
+		return fmt.Errorf("unsupported archive version %d", a.Version)
+	}
+
+	for _, n := range a.Namespaces {
+		if err := n.Validate(); err != nil {
+			return fmt.Errorf("invalid namespace %s: %w", n.Name, err)
+		}
+	}
+
+	for _, s := range a.Servers {
+		if err := s.Validate(); err != nil {
+			return fmt.Errorf("invalid server %s: %w", s.Name, err)
+		}
+	}
+
+	for _, s := range a.Services {
+		if err := s.Validate(); err != nil {
+			return fmt.Errorf("invalid service %s: %w", s.Endpoint, err)
+		}
+	}
+
+	for _, t := range a.Tokens {
+		if t.TokenID == "" || strings.Contains(t.TokenID, "/") {
+			return fmt.Errorf("invalid token id '%s'", t.TokenID)
+		}
+	}
+
+	return nil
+}
+
+func (a Archive) conflicts(namespaces, servers, services map[string]bool, tokens map[string]discovery.Token) error {
+	conflicts := []string{}
+
+	for _, n := range a.Namespaces {
+		if namespaces[n.Name] {
+			conflicts = append(conflicts, "namespace "+n.Name)
+		}
+	}
+
+	for _, s := range a.Servers {
+		if servers[s.Name] {
+			conflicts = append(conflicts, "server "+s.Name)
+		}
+	}
+
+	for _, s := range a.Services {
+		if services[s.Namespace+"/"+s.ID] {
+			conflicts = append(conflicts, "service "+s.Namespace+"/"+s.Endpoint.String())
+		}
+	}
+
+	for _, t := range a.Tokens {
+		if _, ok := tokens[t.TokenID]; ok {
+			conflicts = append(conflicts, "token "+t.TokenID)
+		}
+	}
+
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	n := len(conflicts)
+
+	if n > maxConflicts {
+		conflicts = append(conflicts[:maxConflicts], "...")
+	}
+
+	return fmt.Errorf("%d objects already exist: %s", n, strings.Join(conflicts, ", "))
+}
@@ -0,0 +1,253 @@
+// Package bolt is an embedded single node store backend. All entries are kept in memory
+// and persisted to a bbolt database file.
+package bolt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/postfinance/store"
+	"github.com/postfinance/store/hash"
+	bbolt "go.etcd.io/bbolt"
+)
+
+const openTimeout = time.Second
+
+var bucket = []byte("discovery")
+
+// Backend is a store.Backend for a single process. Reads and watches are served by an
+// in-memory backend, writes are persisted to the database file before they are applied
+// to memory. Put options like TTLs are not persisted.
+type Backend struct {
+	store.Backend
+	db *bbolt.DB
+	mu sync.Mutex
+}
+
+var _ store.Backend = (*Backend)(nil)
+
+// New opens or creates the database file path and loads all entries. The file can only
+// be opened by one process at a time.
+func New(path string) (*Backend, error) {
+	db, err := bbolt.Open(path, 0o600, &bbolt.Options{Timeout: openTimeout})
+	if err != nil {
+		if errors.Is(err, bbolt.ErrTimeout) {
+			return nil, fmt.Errorf("database %s is used by another process", path)
+		}
+
+		return nil, fmt.Errorf("failed to open database %s: %w", path, err)
+	}
+
+	mem, err := hash.New()
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	b := &Backend{
+		Backend: mem,
+		db:      db,
+	}
+
+	if err := b.load(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to load database %s: %w", path, err)
+	}
+
+	return b, nil
+}
+
+// load loads all entries of the database into memory.
+func (b *Backend) load() error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		bkt, err := tx.CreateBucketIfNotExists(bucket)
+		if err != nil {
+			return err
+		}
+
+		return bkt.ForEach(func(k, v []byte) error {
+			_, err := b.Backend.Put(&store.Entry{
+				Key:   string(k),
+				Value: append([]byte{}, v...),
+			})
+
+			return err
+		})
+	})
+}
+
+// Put persists and stores an entry. The entry is only stored in memory, if it was
+// persisted.
+func (b *Backend) Put(e *store.Entry, opts ...store.PutOption) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	prev, existed := b.value(e.Key)
+
+	if err := b.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucket).Put([]byte(e.Key), e.Value)
+	}); err != nil {
+		return false, err
+	}
+
+	ok, err := b.Backend.Put(e, opts...)
+	if err != nil {
+		// the database has to match the memory
+		if rerr := b.db.Update(func(tx *bbolt.Tx) error {
+			if existed {
+				return tx.Bucket(bucket).Put([]byte(e.Key), prev)
+			}
+
+			return tx.Bucket(bucket).Delete([]byte(e.Key))
+		}); rerr != nil {
+			return false, fmt.Errorf("%w (restore failed: %s)", err, rerr)
+		}
+	}
+
+	return ok, err
+}
+
+// Del removes the entries matching key from the database and deletes them from memory
+// afterwards.
+func (b *Backend) Del(key string, opts ...store.DelOption) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	deleted, err := b.match(key, opts...)
+	if err != nil || len(deleted) == 0 {
+		return 0, err
+	}
+
+	if err := b.db.Update(func(tx *bbolt.Tx) error {
+		bkt := tx.Bucket(bucket)
+
+		for _, k := range deleted {
+			if err := bkt.Delete([]byte(k)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	for _, k := range deleted {
+		if _, err := b.Backend.Del(k); err != nil {
+			return 0, err
+		}
+	}
+
+	return int64(len(deleted)), nil
+}
+
+// match returns the keys in memory that Del(key, opts...) deletes. The deletion is
+// applied to a copy of the entries below key.
+func (b *Backend) match(key string, opts ...store.DelOption) ([]string, error) {
+	entries, err := b.Backend.Get(strings.TrimSuffix(key, "/"), store.WithPrefix())
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	scratch, err := hash.New()
+	if err != nil {
+		return nil, err
+	}
+
+	defer scratch.Close()
+
+	for i := range entries {
+		if _, err := scratch.Put(&store.Entry{Key: entries[i].Key, Value: entries[i].Value}); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := scratch.Del(key, opts...); err != nil {
+		return nil, err
+	}
+
+	deleted := []string{}
+
+	for i := range entries {
+		if e, err := scratch.Get(entries[i].Key); err != nil || len(e) == 0 {
+			deleted = append(deleted, entries[i].Key)
+		}
+	}
+
+	return deleted, nil
+}
+
+// Txn puts entries and deletes keys in one database transaction, if the values of the
+// compared keys are unchanged.
+func (b *Backend) Txn(cmps, puts []store.Entry, dels []string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, c := range cmps {
+		v, ok := b.value(c.Key)
+		if ok != (c.Value != nil) || !bytes.Equal(v, c.Value) {
+			return false, nil
+		}
+	}
+
+	if err := b.db.Update(func(tx *bbolt.Tx) error {
+		bkt := tx.Bucket(bucket)
+
+		for _, e := range puts {
+			if err := bkt.Put([]byte(e.Key), e.Value); err != nil {
+				return err
+			}
+		}
+
+		for _, k := range dels {
+			if err := bkt.Delete([]byte(k)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return false, err
+	}
+
+	for i := range puts {
+		if _, err := b.Backend.Put(&puts[i]); err != nil {
+			return false, err
+		}
+	}
+
+	for _, k := range dels {
+		if _, err := b.Backend.Del(k); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// value returns the value of key in memory.
+func (b *Backend) value(key string) ([]byte, bool) {
+	entries, err := b.Backend.Get(key)
+	if err != nil || len(entries) == 0 {
+		return nil, false
+	}
+
+	return entries[0].Value, true
+}
+
+// Close closes the in-memory backend and the database.
+func (b *Backend) Close() error {
+	if err := b.Backend.Close(); err != nil {
+		return err
+	}
+
+	return b.db.Close()
+}
@@ -0,0 +1,127 @@
+package bolt
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/postfinance/discovery"
+	"github.com/postfinance/discovery/internal/repo"
+	"github.com/postfinance/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bbolt "go.etcd.io/bbolt"
+)
+
+func TestBackend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "discovery.db")
+
+	b, err := New(path)
+	require.NoError(t, err)
+
+	namespaces := repo.NewNamespace(b)
+	services := repo.NewService(b)
+
+	_, err = namespaces.Save(discovery.Namespace{Name: "ns1"})
+	require.NoError(t, err)
+
+	for i, ns := range []string{"ns1", "ns1", "ns2"} {
+		svc, err := discovery.NewService("svc", fmt.Sprintf("http://host%d.example.com/metrics", i))
+		require.NoError(t, err)
+
+		svc.Namespace = ns
+
+		_, err = services.Save(*svc)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, services.DeleteFromNamespace("ns1"))
+
+	t.Run("file is locked", func(t *testing.T) {
+		_, err := New(path)
+		assert.Error(t, err)
+	})
+
+	require.NoError(t, b.Close())
+
+	b, err = New(path)
+	require.NoError(t, err)
+
+	defer b.Close()
+
+	namespaces = repo.NewNamespace(b)
+	services = repo.NewService(b)
+
+	ns, err := namespaces.Get("ns1")
+	require.NoError(t, err)
+	assert.Equal(t, "ns1", ns.Name)
+
+	s, err := services.List("ns1", "")
+	require.NoError(t, err)
+	assert.Empty(t, s)
+
+	s, err = services.List("ns2", "")
+	require.NoError(t, err)
+	assert.Len(t, s, 1)
+
+	t.Run("watch", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		ch := namespaces.Chan(ctx, func(err error) {
+			assert.NoError(t, err)
+		})
+
+		go func() {
+			_, err := namespaces.Save(discovery.Namespace{Name: "ns3"})
+			assert.NoError(t, err)
+			assert.NoError(t, namespaces.Delete("ns3"))
+		}()
+
+		e := <-ch
+		assert.Equal(t, repo.Change, e.Event)
+		assert.Equal(t, "ns3", e.Name)
+
+		e = <-ch
+		assert.Equal(t, repo.Delete, e.Event)
+		assert.Equal(t, "ns3", e.Name)
+	})
+}
+
+func TestTxn(t *testing.T) {
+	b, err := New(filepath.Join(t.TempDir(), "discovery.db"))
+	require.NoError(t, err)
+
+	defer b.Close()
+
+	var _ repo.Txn = b
+
+	_, err = b.Put(&store.Entry{Key: "a", Value: []byte("1")})
+	require.NoError(t, err)
+
+	ok, err := b.Txn([]store.Entry{{Key: "a", Value: []byte("2")}}, []store.Entry{{Key: "b", Value: []byte("1")}}, nil)
+	require.NoError(t, err)
+	assert.False(t, ok, "a changed")
+
+	_, err = b.Get("b")
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+
+	ok, err = b.Txn([]store.Entry{{Key: "a", Value: []byte("1")}, {Key: "b"}}, []store.Entry{{Key: "b", Value: []byte("1")}}, []string{"a"})
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	entries, err := b.Get("b")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1"), entries[0].Value)
+
+	_, err = b.Get("a")
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+
+	require.NoError(t, b.db.View(func(tx *bbolt.Tx) error {
+		assert.Nil(t, tx.Bucket(bucket).Get([]byte("a")))
+		assert.Equal(t, []byte("1"), tx.Bucket(bucket).Get([]byte("b")))
+
+		return nil
+	}))
+}
@@ -0,0 +1,729 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/kong"
+	"github.com/postfinance/discovery"
+	"github.com/postfinance/discovery/internal/server/convert"
+	discoveryv1 "github.com/postfinance/discovery/pkg/discoverypb/postfinance/discovery/v1"
+	"github.com/zbindenren/sfmt"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"gopkg.in/yaml.v3"
+)
+
+// ownerLabel marks namespaces, servers and services created by apply. Prometheus
+// drops labels starting with '__' after relabeling.
+const ownerLabel = "__managed_by"
+
+type applyCmd struct {
+	Filename string `short:"f" help:"Manifest file or directory with manifest files (*.yaml, *.yml)." required:"true" type:"existingpath"`
+	Prune    bool   `help:"Unregister namespaces, servers and services of owner, which are not in the manifests."`
+	DryRun   bool   `help:"Only print the plan."`
+	Owner    string `help:"Value of the ownership label (__managed_by) of applied objects. Only objects of the same owner are pruned." default:"apply"`
+	Adopt    bool   `help:"Take over existing objects without ownership label or with another owner. Without it, apply refuses to change them."`
+}
+
+func (a applyCmd) Run(g *Globals, l *zap.SugaredLogger, c *kong.Context) error {
+	m, err := loadManifests(a.Filename)
+	if err != nil {
+		return err
+	}
+
+	desired, err := m.state(a.Owner)
+	if err != nil {
+		return err
+	}
+
+	conn, err := g.conn()
+	if err != nil {
+		return err
+	}
+
+	clients := applyClients{
+		namespace: discoveryv1.NewNamespaceAPIClient(conn),
+		server:    discoveryv1.NewServerAPIClient(conn),
+		service:   discoveryv1.NewServiceAPIClient(conn),
+	}
+
+	current, err := clients.state(g)
+	if err != nil {
+		return err
+	}
+
+	p := newPlan(desired, current, a.Owner, a.Prune)
+
+	if len(p) == 0 {
+		l.Info("no changes")
+		return nil
+	}
+
+	sw := sfmt.SliceWriter{
+		Writer: os.Stdout,
+	}
+
+	sw.Write(sfmt.ParseFormat("table"), p)
+
+	if a.DryRun {
+		return nil
+	}
+
+	if n := p.adoptions(); n > 0 && !a.Adopt {
+		return fmt.Errorf("%d existing objects are not owned by %s, use --adopt to take them over", n, a.Owner)
+	}
+
+	return clients.apply(g, l, p)
+}
+
+// manifest is the content of one or more manifest files.
+type manifest struct {
+	Namespaces []namespaceManifest `yaml:"namespaces"`
+	Servers    []serverManifest    `yaml:"servers"`
+	Services   []serviceManifest   `yaml:"services"`
+}
+
+type namespaceManifest struct {
+	Name     string           `yaml:"name"`
+	Export   string           `yaml:"export"`
+	Labels   discovery.Labels `yaml:"labels"`
+	Public   bool             `yaml:"public"`
+	Replicas int              `yaml:"replicas"`
+}
+
+type serverManifest struct {
+	Name       string           `yaml:"name"`
+	Labels     discovery.Labels `yaml:"labels"`
+	Weight     int              `yaml:"weight"`
+	MaxTargets int              `yaml:"max_targets"`
+}
+
+type serviceManifest struct {
+	Name        string           `yaml:"name"`
+	Namespace   string           `yaml:"namespace"`
+	Endpoints   []string         `yaml:"endpoints"`
+	Labels      discovery.Labels `yaml:"labels"`
+	Selector    string           `yaml:"selector"`
+	Description string           `yaml:"description"`
+	Replicas    int              `yaml:"replicas"`
+}
+
+// loadManifests reads all manifests in path. If path is a directory, all *.yaml and
+// *.yml files in path and its sub directories are read.
+func loadManifests(path string) (*manifest, error) {
+	files := []string{path}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		files = []string{}
+
+		err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if ext := filepath.Ext(p); !d.IsDir() && (ext == ".yaml" || ext == ".yml") {
+				files = append(files, p)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	m := &manifest{}
+
+	for _, f := range files {
+		if err := m.read(f); err != nil {
+			return nil, fmt.Errorf("failed to read manifest %s: %w", f, err)
+		}
+	}
+
+	return m, nil
+}
+
+func (m *manifest) read(path string) error {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+
+	for {
+		doc := manifest{}
+
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		m.Namespaces = append(m.Namespaces, doc.Namespaces...)
+		m.Servers = append(m.Servers, doc.Servers...)
+		m.Services = append(m.Services, doc.Services...)
+	}
+}
+
+// state converts the manifest to the desired state. All objects get the ownership label.
+func (m manifest) state(owner string) (*state, error) {
+	s := &state{}
+
+	for _, n := range m.Namespaces {
+		export := n.Export
+		if export == "" {
+			export = "standard"
+		}
+
+		e, err := exportConfig(export)
+		if err != nil {
+			return nil, fmt.Errorf("namespace %s: %w", n.Name, err)
+		}
+
+		ns := discovery.Namespace{
+			Name:     n.Name,
+			Export:   e,
+			Labels:   withOwner(n.Labels, owner),
+			Public:   n.Public,
+			Replicas: n.Replicas,
+		}
+
+		if err := ns.Validate(); err != nil {
+			return nil, fmt.Errorf("namespace %s: %w", n.Name, err)
+		}
+
+		s.namespaces = append(s.namespaces, ns)
+	}
+
+	for _, srv := range m.Servers {
+		server := discovery.NewServer(srv.Name, withOwner(srv.Labels, owner))
+		server.Weight = srv.Weight
+		server.MaxTargets = srv.MaxTargets
+
+		if err := server.Validate(); err != nil {
+			return nil, fmt.Errorf("server %s: %w", srv.Name, err)
+		}
+
+		s.servers = append(s.servers, *server)
+	}
+
+	for _, svc := range m.Services {
+		for _, ep := range svc.Endpoints {
+			service, err := discovery.NewService(svc.Name, ep)
+			if err != nil {
+				return nil, fmt.Errorf("service %s: %w", ep, err)
+			}
+
+			if svc.Namespace != "" {
+				service.Namespace = svc.Namespace
+			}
+
+			service.Labels = withOwner(svc.Labels, owner)
+			service.Selector = svc.Selector
+			service.Description = svc.Description
+			service.Replicas = svc.Replicas
+
+			if err := service.Validate(); err != nil {
+				return nil, fmt.Errorf("service %s: %w", ep, err)
+			}
+
+			s.services = append(s.services, *service)
+		}
+	}
+
+	return s, s.checkDuplicates()
+}
+
+// state represents namespaces, servers and services.
+type state struct {
+	namespaces discovery.Namespaces
+	servers    discovery.Servers
+	services   discovery.Services
+}
+
+func (s state) checkDuplicates() error {
+	seen := map[string]bool{}
+
+	for _, k := range s.keys() {
+		if seen[k] {
+			return fmt.Errorf("%s is defined more than once", k)
+		}
+
+		seen[k] = true
+	}
+
+	return nil
+}
+
+func (s state) keys() []string {
+	keys := []string{}
+
+	for i := range s.namespaces {
+		keys = append(keys, "namespace "+s.namespaces[i].Name)
+	}
+
+	for i := range s.servers {
+		keys = append(keys, "server "+s.servers[i].Name)
+	}
+
+	for i := range s.services {
+		keys = append(keys, "service "+serviceKey(s.services[i]))
+	}
+
+	return keys
+}
+
+// Actions of a change.
+const (
+	actionCreate = "create"
+	actionUpdate = "update"
+	actionDelete = "delete"
+	// actionAdopt updates an existing object, that is not owned by the owner of the plan.
+	actionAdopt = "adopt"
+)
+
+// Kinds of a change.
+const (
+	kindNamespace = "namespace"
+	kindServer    = "server"
+	kindService   = "service"
+)
+
+// change is one step of a plan.
+type change struct {
+	Action    string
+	Kind      string
+	Name      string
+	namespace *discovery.Namespace
+	server    *discovery.Server
+	service   *discovery.Service
+}
+
+// Header creates the header for csv or table output.
+func (c change) Header() []string {
+	return []string{"ACTION", "KIND", "NAME"}
+}
+
+// Row creates a row for csv or table output.
+func (c change) Row() []string {
+	return []string{c.Action, c.Kind, c.Name}
+}
+
+// plan is the list of changes needed to get from the current to the desired state.
+// Objects are created and updated before objects are deleted.
+type plan []change
+
+// adoptions returns the number of adopted objects.
+func (p plan) adoptions() int {
+	n := 0
+
+	for _, c := range p {
+		if c.Action == actionAdopt {
+			n++
+		}
+	}
+
+	return n
+}
+
+// newPlan compares the desired with the current state. Existing objects without the ownership
+// label of owner are adopted. If prune is true, objects with the ownership label of owner,
+// which are not in the desired state are deleted.
+//
+//nolint:gocyclo // one block per kind
+func newPlan(desired, current *state, owner string, prune bool) plan {
+	p := plan{}
+	deletes := plan{}
+
+	namespaces := map[string]discovery.Namespace{}
+	for _, n := range current.namespaces {
+		namespaces[n.Name] = n
+	}
+
+	for i := range desired.namespaces {
+		n := desired.namespaces[i]
+
+		cur, ok := namespaces[n.Name]
+
+		switch {
+		case !ok:
+			p = append(p, change{Action: actionCreate, Kind: kindNamespace, Name: n.Name, namespace: &n})
+		case cur.Labels.Get(ownerLabel) != owner:
+			p = append(p, change{Action: actionAdopt, Kind: kindNamespace, Name: n.Name, namespace: &n})
+		case cur.Export != n.Export || cur.Public != n.Public || cur.Replicas != n.Replicas || !labelsEqual(cur.Labels, n.Labels):
+			p = append(p, change{Action: actionUpdate, Kind: kindNamespace, Name: n.Name, namespace: &n})
+		}
+
+		delete(namespaces, n.Name)
+	}
+
+	servers := map[string]discovery.Server{}
+	for _, s := range current.servers {
+		servers[s.Name] = s
+	}
+
+	for i := range desired.servers {
+		s := desired.servers[i]
+
+		cur, ok := servers[s.Name]
+
+		switch {
+		case !ok:
+			p = append(p, change{Action: actionCreate, Kind: kindServer, Name: s.Name, server: &s})
+		case cur.Labels.Get(ownerLabel) != owner:
+			p = append(p, change{Action: actionAdopt, Kind: kindServer, Name: s.Name, server: &s})
+		case cur.EffectiveWeight() != s.EffectiveWeight() || cur.MaxTargets != s.MaxTargets || !labelsEqual(cur.Labels, s.Labels):
+			p = append(p, change{Action: actionUpdate, Kind: kindServer, Name: s.Name, server: &s})
+		}
+
+		delete(servers, s.Name)
+	}
+
+	services := map[string]discovery.Service{}
+	for _, s := range current.services {
+		services[serviceKey(s)] = s
+	}
+
+	for i := range desired.services {
+		s := desired.services[i]
+		k := serviceKey(s)
+
+		cur, ok := services[k]
+
+		switch {
+		case !ok:
+			p = append(p, change{Action: actionCreate, Kind: kindService, Name: k, service: &s})
+		case cur.Labels.Get(ownerLabel) != owner:
+			p = append(p, change{Action: actionAdopt, Kind: kindService, Name: k, service: &s})
+		case cur.Name != s.Name || cur.Selector != s.Selector || cur.Description != s.Description || cur.Replicas != s.Replicas || !labelsEqual(cur.Labels, s.Labels):
+			p = append(p, change{Action: actionUpdate, Kind: kindService, Name: k, service: &s})
+		}
+
+		delete(services, k)
+	}
+
+	if !prune {
+		return p
+	}
+
+	for k := range services {
+		s := services[k]
+
+		if s.Labels.Get(ownerLabel) == owner {
+			deletes = append(deletes, change{Action: actionDelete, Kind: kindService, Name: k, service: &s})
+		}
+	}
+
+	for k := range servers {
+		s := servers[k]
+
+		if s.Labels.Get(ownerLabel) == owner {
+			deletes = append(deletes, change{Action: actionDelete, Kind: kindServer, Name: k, server: &s})
+		}
+	}
+
+	for k := range namespaces {
+		n := namespaces[k]
+
+		if n.Labels.Get(ownerLabel) == owner {
+			deletes = append(deletes, change{Action: actionDelete, Kind: kindNamespace, Name: k, namespace: &n})
+		}
+	}
+
+	// services before servers before namespaces
+	order := map[string]int{kindService: 0, kindServer: 1, kindNamespace: 2}
+
+	sort.SliceStable(deletes, func(i, j int) bool {
+		if deletes[i].Kind != deletes[j].Kind {
+			return order[deletes[i].Kind] < order[deletes[j].Kind]
+		}
+
+		return deletes[i].Name < deletes[j].Name
+	})
+
+	return append(p, deletes...)
+}
+
+type applyClients struct {
+	namespace discoveryv1.NamespaceAPIClient
+	server    discoveryv1.ServerAPIClient
+	service   discoveryv1.ServiceAPIClient
+}
+
+// state gets the current state from the discovery service.
+func (a applyClients) state(g *Globals) (*state, error) {
+	ctx, cancel := g.ctx()
+	defer cancel()
+
+	n, err := a.namespace.ListNamespace(ctx, &discoveryv1.ListNamespaceRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := a.server.ListServer(ctx, &discoveryv1.ListServerRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	svc, err := a.service.ListService(ctx, &discoveryv1.ListServiceRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &state{
+		namespaces: convert.NamespacesFromPB(n.GetNamespaces()),
+		servers:    convert.ServersFromPB(s.GetServers()),
+		services:   convert.ServicesFromPB(svc.GetServices()),
+	}, nil
+}
+
+// apply executes all changes of plan p. Changes of services are sent in batches.
+func (a applyClients) apply(g *Globals, l *zap.SugaredLogger, p plan) error {
+	var (
+		failed     bool
+		registers  = &discoveryv1.RegisterServicesRequest{}
+		unregister = &discoveryv1.UnregisterServicesRequest{}
+	)
+
+	for _, c := range p {
+		var err error
+
+		switch {
+		case c.Kind == kindService && c.Action == actionDelete:
+			unregister.Services = append(unregister.Services, &discoveryv1.UnRegisterServiceRequest{
+				Id:        c.service.Endpoint.String(),
+				Namespace: c.service.Namespace,
+			})
+
+			continue
+		case c.Kind == kindService:
+			registers.Services = append(registers.Services, &discoveryv1.RegisterServiceRequest{
+				Name:        c.service.Name,
+				Endpoint:    c.service.Endpoint.String(),
+				Labels:      c.service.Labels,
+				Description: c.service.Description,
+				Namespace:   c.service.Namespace,
+				Selector:    c.service.Selector,
+				Replicas:    uint32(c.service.Replicas),
+			})
+
+			continue
+		case c.Kind == kindServer && c.Action == actionDelete:
+			// services have to be registered before servers are removed
+			if !a.applyServices(g, l, registers, unregister) {
+				failed = true
+			}
+
+			registers, unregister = &discoveryv1.RegisterServicesRequest{}, &discoveryv1.UnregisterServicesRequest{}
+
+			err = a.unregisterServer(g, c.server.Name)
+		case c.Kind == kindServer:
+			err = a.registerServer(g, c.server)
+		case c.Kind == kindNamespace && c.Action == actionDelete:
+			if !a.applyServices(g, l, registers, unregister) {
+				failed = true
+			}
+
+			registers, unregister = &discoveryv1.RegisterServicesRequest{}, &discoveryv1.UnregisterServicesRequest{}
+
+			err = a.unregisterNamespace(g, c.namespace.Name)
+		case c.Kind == kindNamespace:
+			err = a.registerNamespace(g, c.namespace)
+		}
+
+		if err != nil {
+			failed = true
+
+			l.Errorw("failed to "+c.Action, "kind", c.Kind, "name", c.Name, "err", err)
+		}
+	}
+
+	if !a.applyServices(g, l, registers, unregister) {
+		failed = true
+	}
+
+	if failed {
+		return errors.New("apply failed")
+	}
+
+	return nil
+}
+
+// applyServices registers and unregisters services in batches. It returns false if
+// at least one service failed.
+func (a applyClients) applyServices(g *Globals, l *zap.SugaredLogger, registers *discoveryv1.RegisterServicesRequest,
+	unregister *discoveryv1.UnregisterServicesRequest) bool {
+	ok := true
+
+	for _, batch := range batches(len(registers.GetServices())) {
+		req := &discoveryv1.RegisterServicesRequest{
+			Services: registers.GetServices()[batch[0]:batch[1]],
+		}
+
+		ctx, cancel := g.ctx()
+		r, err := registerServices(ctx, l, a.service, req)
+
+		cancel()
+
+		if err != nil {
+			l.Errorw("failed to register services", "err", err)
+
+			ok = false
+
+			continue
+		}
+
+		for i, res := range r.GetResults() {
+			if res.GetCode() != uint32(codes.OK) {
+				l.Errorw("failed to register", "service", req.GetServices()[i].GetEndpoint(), "err", res.GetError())
+
+				ok = false
+			}
+		}
+	}
+
+	for _, batch := range batches(len(unregister.GetServices())) {
+		req := &discoveryv1.UnregisterServicesRequest{
+			Services: unregister.GetServices()[batch[0]:batch[1]],
+		}
+
+		ctx, cancel := g.ctx()
+		r, err := a.service.UnregisterServices(ctx, req)
+
+		cancel()
+
+		if err != nil {
+			l.Errorw("failed to unregister services", "err", err)
+
+			ok = false
+
+			continue
+		}
+
+		for _, res := range r.GetResults() {
+			if res.GetCode() != uint32(codes.OK) {
+				l.Errorw("failed to unregister", "service", res.GetId(), "err", res.GetError())
+
+				ok = false
+			}
+		}
+	}
+
+	return ok
+}
+
+func (a applyClients) registerNamespace(g *Globals, n *discovery.Namespace) error {
+	ctx, cancel := g.ctx()
+	defer cancel()
+
+	_, err := a.namespace.RegisterNamespace(ctx, &discoveryv1.RegisterNamespaceRequest{
+		Name:     n.Name,
+		Export:   int32(n.Export),
+		Labels:   n.Labels,
+		Public:   n.Public,
+		Replicas: uint32(n.Replicas),
+	})
+
+	return err
+}
+
+func (a applyClients) unregisterNamespace(g *Globals, name string) error {
+	ctx, cancel := g.ctx()
+	defer cancel()
+
+	_, err := a.namespace.UnregisterNamespace(ctx, &discoveryv1.UnregisterNamespaceRequest{
+		Name: name,
+	})
+
+	return err
+}
+
+func (a applyClients) registerServer(g *Globals, s *discovery.Server) error {
+	ctx, cancel := g.ctx()
+	defer cancel()
+
+	_, err := a.server.RegisterServer(ctx, &discoveryv1.RegisterServerRequest{
+		Name:       s.Name,
+		Labels:     s.Labels,
+		Weight:     int64(s.Weight),
+		MaxTargets: int64(s.MaxTargets),
+	})
+
+	return err
+}
+
+func (a applyClients) unregisterServer(g *Globals, name string) error {
+	ctx, cancel := g.ctx()
+	defer cancel()
+
+	_, err := a.server.UnregisterServer(ctx, &discoveryv1.UnregisterServerRequest{
+		Name: name,
+	})
+
+	return err
+}
+
+// batches splits n items in [start, end) ranges of at most importBatchSize items.
+func batches(n int) [][2]int {
+	result := [][2]int{}
+
+	for start := 0; start < n; start += importBatchSize {
+		end := start + importBatchSize
+		if end > n {
+			end = n
+		}
+
+		result = append(result, [2]int{start, end})
+	}
+
+	return result
+}
+
+func serviceKey(s discovery.Service) string {
+	return strings.Join([]string{s.Namespace, s.Endpoint.String()}, "/")
+}
+
+func withOwner(l discovery.Labels, owner string) discovery.Labels {
+	labels := discovery.Labels{}
+
+	for k, v := range l {
+		labels[k] = v
+	}
+
+	labels[ownerLabel] = owner
+
+	return labels
+}
+
+func labelsEqual(a, b discovery.Labels) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for k, v := range a {
+		if w, ok := b[k]; !ok || v != w {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,107 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/postfinance/discovery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPlan(t *testing.T) {
+	m := manifest{
+		Namespaces: []namespaceManifest{{Name: "ns1"}},
+		Servers:    []serverManifest{{Name: "server1", Labels: discovery.Labels{"env": "prod"}}},
+		Services: []serviceManifest{{
+			Name:      "svc",
+			Namespace: "ns1",
+			Endpoints: []string{"http://host1:8080/metrics", "http://host2:8080/metrics"},
+		}},
+	}
+
+	desired, err := m.state("team1")
+	require.NoError(t, err)
+
+	t.Run("create", func(t *testing.T) {
+		p := newPlan(desired, &state{}, "team1", true)
+		require.Len(t, p, 4)
+
+		for _, c := range p {
+			assert.Equal(t, actionCreate, c.Action)
+		}
+	})
+
+	t.Run("no changes", func(t *testing.T) {
+		p := newPlan(desired, desired, "team1", true)
+		assert.Empty(t, p)
+	})
+
+	t.Run("update and prune", func(t *testing.T) {
+		current := &state{
+			namespaces: append(discovery.Namespaces{
+				{Name: "other", Labels: discovery.Labels{ownerLabel: "team2"}},
+				{Name: "old", Labels: discovery.Labels{ownerLabel: "team1"}},
+			}, desired.namespaces...),
+			servers: discovery.Servers{
+				*discovery.NewServer("server1", discovery.Labels{"env": "test"}),
+			},
+			services: desired.services[:1],
+		}
+
+		p := newPlan(desired, current, "team1", true)
+		require.Len(t, p, 3)
+		assert.Equal(t, change{Action: actionAdopt, Kind: kindServer, Name: "server1"}, strip(p[0]), "server without owner")
+		assert.Equal(t, change{Action: actionCreate, Kind: kindService, Name: "ns1/http://host2:8080/metrics"}, strip(p[1]))
+		assert.Equal(t, change{Action: actionDelete, Kind: kindNamespace, Name: "old"}, strip(p[2]))
+
+		p = newPlan(desired, current, "team1", false)
+		assert.Len(t, p, 2)
+		assert.Equal(t, 1, p.adoptions())
+	})
+
+	t.Run("adopt", func(t *testing.T) {
+		current := &state{
+			namespaces: discovery.Namespaces{{Name: "ns1", Labels: discovery.Labels{ownerLabel: "team2"}}},
+			servers:    desired.servers,
+			services:   desired.services,
+		}
+
+		p := newPlan(desired, current, "team1", true)
+		require.Len(t, p, 1)
+		assert.Equal(t, change{Action: actionAdopt, Kind: kindNamespace, Name: "ns1"}, strip(p[0]), "namespace of other owner")
+	})
+
+	t.Run("weight", func(t *testing.T) {
+		current := &state{
+			namespaces: desired.namespaces,
+			servers:    desired.servers,
+			services:   desired.services,
+		}
+
+		m := m
+		m.Servers = []serverManifest{{Name: "server1", Labels: discovery.Labels{"env": "prod"}, Weight: 1}}
+
+		s, err := m.state("team1")
+		require.NoError(t, err)
+		assert.Empty(t, newPlan(s, current, "team1", true), "weight 1 is the default")
+
+		m.Servers[0].MaxTargets = 100
+
+		s, err = m.state("team1")
+		require.NoError(t, err)
+
+		p := newPlan(s, current, "team1", true)
+		require.Len(t, p, 1)
+		assert.Equal(t, change{Action: actionUpdate, Kind: kindServer, Name: "server1"}, strip(p[0]))
+	})
+
+	t.Run("duplicates", func(t *testing.T) {
+		m := manifest{Servers: []serverManifest{{Name: "server1"}, {Name: "server1"}}}
+		_, err := m.state("team1")
+		assert.Error(t, err)
+	})
+}
+
+func strip(c change) change {
+	return change{Action: c.Action, Kind: c.Kind, Name: c.Name}
+}
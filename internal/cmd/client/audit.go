@@ -0,0 +1,72 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/postfinance/discovery/internal/server/convert"
+	discoveryv1 "github.com/postfinance/discovery/pkg/discoverypb/postfinance/discovery/v1"
+	"github.com/zbindenren/sfmt"
+	"go.uber.org/zap"
+)
+
+type auditCmd struct {
+	List auditList `cmd:"" help:"List audit records."`
+}
+
+type auditList struct {
+	Output  string `short:"o" default:"table" help:"Output formats. Valid formats: json, yaml, csv, table."`
+	Headers bool   `short:"H" help:"Show headers."`
+	Since   string `short:"s" help:"Only list records newer than since. Either a duration (for example 24h) or a RFC3339 timestamp." default:"24h"`
+	User    string `short:"u" help:"Only list records of user."`
+	Method  string `short:"m" help:"Only list records of methods containing method (for example UnregisterServer)."`
+}
+
+func (a auditList) Run(g *Globals, l *zap.SugaredLogger, c *kong.Context) error {
+	since, err := parseSince(a.Since, time.Now())
+	if err != nil {
+		return err
+	}
+
+	cli, err := g.auditClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := g.ctx()
+	defer cancel()
+
+	r, err := cli.ListAuditRecord(ctx, &discoveryv1.ListAuditRecordRequest{
+		Since:  convert.TimeToPB(&since),
+		User:   a.User,
+		Method: a.Method,
+	})
+	if err != nil {
+		return err
+	}
+
+	sw := sfmt.SliceWriter{
+		Writer:    os.Stdout,
+		NoHeaders: !a.Headers,
+	}
+
+	sw.Write(sfmt.ParseFormat(a.Output), convert.AuditRecordsFromPB(r.GetRecords()))
+
+	return nil
+}
+
+// parseSince parses a duration relative to now or a RFC3339 timestamp.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("since %q is neither a duration nor a RFC3339 timestamp", s)
+	}
+
+	return t, nil
+}
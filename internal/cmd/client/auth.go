@@ -0,0 +1,47 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/alecthomas/kong"
+	discoveryv1 "github.com/postfinance/discovery/pkg/discoverypb/postfinance/discovery/v1"
+	"go.uber.org/zap"
+)
+
+type authCmd struct {
+	CanI authCanI `cmd:"" name:"can-i" help:"Check if you are allowed to call an api method."`
+}
+
+type authCanI struct {
+	Method     string   `arg:"" help:"The api method (i.e. ServerAPI/RegisterServer)." required:"true"`
+	Namespaces []string `short:"n" help:"The namespaces the call addresses."`
+	Servers    []string `short:"s" help:"The servers the call addresses."`
+}
+
+func (a authCanI) Run(g *Globals, l *zap.SugaredLogger, c *kong.Context) error {
+	cli, err := g.authClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := g.ctx()
+	defer cancel()
+
+	r, err := cli.CanI(ctx, &discoveryv1.CanIRequest{
+		Method:     a.Method,
+		Namespaces: a.Namespaces,
+		Servers:    a.Servers,
+	})
+	if err != nil {
+		return err
+	}
+
+	answer := "no"
+	if r.GetAllowed() {
+		answer = "yes"
+	}
+
+	fmt.Printf("%s - %s\n", answer, r.GetReason())
+
+	return nil
+}
@@ -3,6 +3,7 @@ package client
 
 import (
 	"context"
+	"crypto/tls"
 	"crypto/x509"
 	"os"
 	"path/filepath"
@@ -28,6 +29,9 @@ type CLI struct {
 	Service   serviceCmd   `cmd:"" help:"Register and unregister services." aliases:"svc"`
 	Namespace namespaceCmd `cmd:"" help:"Register and unregister namespaces." aliases:"ns"`
 	Token     tokenCmd     `cmd:"" help:"Manage access tokens"`
+	Apply     applyCmd     `cmd:"" help:"Apply namespaces, servers and services from manifest files."`
+	Audit     auditCmd     `cmd:"" help:"Show the audit log of mutating api calls."`
+	Auth      authCmd      `cmd:"" help:"Inspect authorization."`
 }
 
 // Globals are the global client flags.
@@ -35,18 +39,23 @@ type Globals struct {
 	Address    string           `short:"a" help:"The address of the discovery grpc endpoint." default:"localhost:3001"`
 	Timeout    time.Duration    `help:"The request timeout" default:"15s"`
 	Debug      bool             `short:"d" help:"Log debug output."`
-	Insecure   bool             `help:"use insecure connection without tls." xor:"tls"`
+	Insecure   bool             `help:"use insecure connection without tls." xor:"tls,cert"`
 	ShowConfig king.ShowConfig  `help:"Show used config files"`
 	Version    king.VersionFlag `help:"Show version information"`
 	TokenPath  string           `help:"Authentication token" default:"~/.config/discovery/.token"`
 	OIDC       oidc             `embed:"true" prefix:"oidc-"`
 	CACert     string           `help:"Path to a custom tls ca pem file. Certificates in this file are added to system cert pool." type:"existingfile" xor:"tls"`
+	Cert       string           `help:"Path to a pem encoded client certificate. Without token, the client is authenticated with the certificate." type:"existingfile" xor:"cert"`
+	Key        string           `help:"Path to the pem encoded private key of the client certificate." type:"existingfile"`
 }
 
 type oidc struct {
 	Endpoint         string `help:"OIDC endpoint URL."`
 	ClientID         string `help:"OIDC client ID."`
+	ClientSecret     string `help:"OIDC client secret. If set, access tokens are requested with the client credentials grant instead of using the token in token-path."`
 	ExternalLoginCmd string `help:"If not empty, this command is printed out for the login sub command. The command should create a id_token in token-path."`
+	Flow             string `help:"The login flow: password (resource owner password grant), device (device authorization grant) or pkce (authorization code grant with pkce)." enum:"password,device,pkce" default:"password"`
+	RedirectPort     int    `help:"The local port for the redirect of the pkce flow. If 0, a random port is used." default:"0"`
 }
 
 func (g Globals) ctx() (context.Context, context.CancelFunc) {
@@ -69,12 +78,29 @@ func (g Globals) conn() (*grpc.ClientConn, error) {
 			}
 		}
 
-		creds := credentials.NewClientTLSFromCert(pool, "")
-		dialOption = grpc.WithTransportCredentials(creds)
+		tlsConfig := &tls.Config{
+			RootCAs:    pool,
+			MinVersion: tls.VersionTLS12,
+		}
+
+		if g.Cert != "" || g.Key != "" {
+			if g.Cert == "" || g.Key == "" {
+				return nil, errors.New("--cert and --key are required together")
+			}
+
+			cert, err := tls.LoadX509KeyPair(g.Cert, g.Key)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to load client certificate")
+			}
+
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+
+		dialOption = grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))
 	}
 
 	token, err := g.getToken()
-	if err != nil {
+	if err != nil && g.Cert == "" {
 		return nil, err
 	}
 
@@ -119,9 +145,29 @@ func (g Globals) tokenClient() (discoveryv1.TokenAPIClient, error) {
 	return discoveryv1.NewTokenAPIClient(conn), nil
 }
 
+func (g Globals) auditClient() (discoveryv1.AuditAPIClient, error) {
+	conn, err := g.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	return discoveryv1.NewAuditAPIClient(conn), nil
+}
+
+func (g Globals) authClient() (discoveryv1.AuthAPIClient, error) {
+	conn, err := g.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	return discoveryv1.NewAuthAPIClient(conn), nil
+}
+
 func buildClientInterceptor(token string) func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, grpc.UnaryInvoker, ...grpc.CallOption) error {
 	return func(ctx context.Context, method string, req interface{}, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
-		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "bearer "+token)
+		if token != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "bearer "+token)
+		}
 
 		return invoker(ctx, method, req, reply, cc, opts...)
 	}
@@ -167,10 +213,15 @@ func (g Globals) saveToken(t *auth.Token) error {
 }
 
 // getToken loads or asks for user or machine token. If it is
-// a user token it refreshes it if necessary.
+// a user token it refreshes it if necessary. With a client secret
+// it requests a client credentials token.
 func (g Globals) getToken() (string, error) {
 	var token string
 
+	if g.OIDC.ClientSecret != "" {
+		return g.clientCredentialsToken()
+	}
+
 	t, err := g.loadToken()
 	if err != nil {
 		return "", errors.Wrap(err, "login required")
@@ -211,3 +262,29 @@ func (g Globals) getToken() (string, error) {
 
 	return token, nil
 }
+
+// clientCredentialsToken requests an access token with the client credentials grant.
+func (g Globals) clientCredentialsToken() (string, error) {
+	opts := []auth.ClientOption{auth.WithClientSecret(g.OIDC.ClientSecret)}
+
+	if g.CACert != "" {
+		pool, err := auth.AppendCertsToSystemPool(g.CACert)
+		if err != nil {
+			return "", err
+		}
+
+		opts = append(opts, auth.WithTransport(auth.NewTLSTransportFromCertPool(pool)))
+	}
+
+	cli, err := auth.NewClient(g.OIDC.Endpoint, g.OIDC.ClientID, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	t, err := cli.ClientCredentialsToken()
+	if err != nil {
+		return "", err
+	}
+
+	return t.AccessToken, nil
+}
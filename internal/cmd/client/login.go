@@ -1,8 +1,12 @@
 package client
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
 
 	"github.com/postfinance/discovery/internal/auth"
 )
@@ -18,14 +22,6 @@ func (l loginCmd) Run(g *Globals) error {
 		return nil
 	}
 
-	user := os.Getenv("USER")
-	asker := auth.NewAsker(auth.WithPrompt("Enter username: "), auth.WithDfltUsername(user))
-
-	c, err := asker.Ask(os.Stdin, os.Stdout)
-	if err != nil {
-		return err
-	}
-
 	opts := []auth.ClientOption{}
 
 	if g.CACert != "" {
@@ -44,10 +40,53 @@ func (l loginCmd) Run(g *Globals) error {
 		return err
 	}
 
-	t, err := cli.Token(c.Username, c.Password, os.Stdout)
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	var t *auth.Token
+
+	switch g.OIDC.Flow {
+	case "device":
+		t, err = cli.DeviceToken(ctx, os.Stderr)
+	case "pkce":
+		t, err = cli.AuthCodeToken(ctx, g.OIDC.RedirectPort, func(authURL string) {
+			fmt.Fprintf(os.Stderr, "open the following url to login:\n\n%s\n\n", authURL)
+
+			if err := openBrowser(authURL); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to open browser: %s\n", err)
+			}
+		})
+	default:
+		user := os.Getenv("USER")
+		asker := auth.NewAsker(auth.WithPrompt("Enter username: "), auth.WithDfltUsername(user))
+
+		c, askErr := asker.Ask(os.Stdin, os.Stdout)
+		if askErr != nil {
+			return askErr
+		}
+
+		t, err = cli.Token(c.Username, c.Password, os.Stdout)
+	}
+
 	if err != nil {
 		return err
 	}
 
 	return g.saveToken(t)
 }
+
+// openBrowser opens url with the default browser.
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+
+	return cmd.Start()
+}
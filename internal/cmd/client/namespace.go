@@ -16,11 +16,13 @@ type namespaceCmd struct {
 	List       namespaceList       `cmd:"" help:"List registered namespaces."`
 	Register   namespaceRegister   `cmd:"" help:"Register a namespace."`
 	UnRegister namespaceUnRegister `cmd:""  name:"unregister" help:"Unregister a namespace."`
+	Restore    namespaceRestore    `cmd:"" help:"Restore an unregistered namespace with its services."`
 }
 
 type namespaceList struct {
 	Output  string `short:"o" default:"table" help:"Output formats. Valid formats: json, yaml, csv, table."`
 	Headers bool   `short:"H" help:"Show headers."`
+	Deleted bool   `short:"D" help:"List unregistered namespaces that can be restored."`
 }
 
 //nolint:dupl // it does not the same as serverList command
@@ -33,6 +35,23 @@ func (n namespaceList) Run(g *Globals, l *zap.SugaredLogger, c *kong.Context) er
 	ctx, cancel := g.ctx()
 	defer cancel()
 
+	sw := sfmt.SliceWriter{
+		Writer:    os.Stdout,
+		NoHeaders: !n.Headers,
+	}
+	f := sfmt.ParseFormat(n.Output)
+
+	if n.Deleted {
+		r, err := cli.ListDeletedNamespace(ctx, &discoveryv1.ListDeletedNamespaceRequest{})
+		if err != nil {
+			return err
+		}
+
+		sw.Write(f, convert.DeletedNamespacesFromPB(r.GetNamespaces()))
+
+		return nil
+	}
+
 	r, err := cli.ListNamespace(ctx, &discoveryv1.ListNamespaceRequest{})
 	if err != nil {
 		return err
@@ -40,20 +59,17 @@ func (n namespaceList) Run(g *Globals, l *zap.SugaredLogger, c *kong.Context) er
 
 	namespaces := convert.NamespacesFromPB(r.GetNamespaces())
 
-	sw := sfmt.SliceWriter{
-		Writer:    os.Stdout,
-		NoHeaders: !n.Headers,
-	}
-	f := sfmt.ParseFormat(n.Output)
-
 	sw.Write(f, namespaces)
 
 	return nil
 }
 
 type namespaceRegister struct {
-	Name         string `arg:"true" help:"Namespace name name." required:"true"`
-	ExportConfig string `short:"e" help:"Configures how services get exported. Possible values: blackbox,standard and disabled." enum:"blackbox,standard,disabled" default:"standard"`
+	Name         string           `arg:"true" help:"Namespace name name." required:"true"`
+	ExportConfig string           `short:"e" help:"Configures how services get exported. Possible values: blackbox,standard and disabled." enum:"blackbox,standard,disabled" default:"standard"`
+	Labels       discovery.Labels `short:"l" help:"Labels for the namespace." mapsep:","`
+	Public       bool             `help:"Services in public namespaces are readable by all users and machines."`
+	Replicas     int              `short:"r" help:"The number of servers services in the namespace are distributed to (0 means the default of the discovery server)."`
 }
 
 func (n namespaceRegister) Run(g *Globals, l *zap.SugaredLogger, c *kong.Context) error {
@@ -65,22 +81,17 @@ func (n namespaceRegister) Run(g *Globals, l *zap.SugaredLogger, c *kong.Context
 	ctx, cancel := g.ctx()
 	defer cancel()
 
-	var e discovery.ExportConfig
-
-	switch n.ExportConfig {
-	case "standard":
-		e = discovery.Standard
-	case "blackbox":
-		e = discovery.Blackbox
-	case "disabled":
-		e = discovery.Disabled
-	default:
-		return errors.New("unsupported export configuration")
+	e, err := exportConfig(n.ExportConfig)
+	if err != nil {
+		return err
 	}
 
 	_, err = cli.RegisterNamespace(ctx, &discoveryv1.RegisterNamespaceRequest{
-		Name:   n.Name,
-		Export: int32(e),
+		Name:     n.Name,
+		Export:   int32(e),
+		Labels:   n.Labels,
+		Public:   n.Public,
+		Replicas: uint32(n.Replicas),
 	})
 
 	return err
@@ -105,3 +116,41 @@ func (n namespaceUnRegister) Run(g *Globals, l *zap.SugaredLogger, c *kong.Conte
 
 	return err
 }
+
+type namespaceRestore struct {
+	Name string `arg:"true" help:"Namespace name." required:"true"`
+}
+
+func (n namespaceRestore) Run(g *Globals, l *zap.SugaredLogger, c *kong.Context) error {
+	cli, err := g.namespaceClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := g.ctx()
+	defer cancel()
+
+	r, err := cli.RestoreNamespace(ctx, &discoveryv1.RestoreNamespaceRequest{
+		Name: n.Name,
+	})
+	if err != nil {
+		return err
+	}
+
+	l.Infow("namespace restored", "name", n.Name, "services", r.GetServices())
+
+	return nil
+}
+
+func exportConfig(s string) (discovery.ExportConfig, error) {
+	switch s {
+	case "standard":
+		return discovery.Standard, nil
+	case "blackbox":
+		return discovery.Blackbox, nil
+	case "disabled":
+		return discovery.Disabled, nil
+	default:
+		return discovery.Disabled, errors.New("unsupported export configuration")
+	}
+}
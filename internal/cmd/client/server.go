@@ -2,7 +2,9 @@ package client
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/alecthomas/kong"
@@ -18,6 +20,10 @@ type serverCmd struct {
 	List       serverList       `cmd:"" help:"List registered servers."`
 	Register   serverRegister   `cmd:"" help:"Register a server."`
 	UnRegister serverUnRegister `cmd:""  name:"unregister" help:"Unregister a server."`
+	Cordon     serverCordon     `cmd:"" help:"Cordon a server. It keeps its services but gets no new services."`
+	Uncordon   serverUncordon   `cmd:"" help:"Make a cordoned or draining server active again."`
+	Drain      serverDrain      `cmd:"" help:"Drain a server. It gets no new services and its services are moved to other servers gradually."`
+	Rebalance  serverRebalance  `cmd:"" help:"Redistribute all services on the servers. Only services whose servers change are written."`
 }
 
 type serverList struct {
@@ -54,8 +60,10 @@ func (s serverList) Run(g *Globals, l *zap.SugaredLogger, c *kong.Context) error
 }
 
 type serverRegister struct {
-	Name   string            `arg:"true" help:"Server name." required:"true"`
-	Labels map[string]string `short:"l" help:"Labels" mapsep:","`
+	Name       string            `arg:"true" help:"Server name." required:"true"`
+	Labels     map[string]string `short:"l" help:"Labels" mapsep:","`
+	Weight     int               `short:"w" help:"The relative capacity of the server. Services are distributed proportionally to the weights of the servers." default:"1"`
+	MaxTargets int               `help:"The maximum number of services of the server. If it is reached, services are distributed to other servers. 0 means unlimited." default:"0"`
 }
 
 func (s serverRegister) Run(g *Globals, l *zap.SugaredLogger, c *kong.Context) error {
@@ -70,8 +78,10 @@ func (s serverRegister) Run(g *Globals, l *zap.SugaredLogger, c *kong.Context) e
 	defer cancel()
 
 	_, err = cli.RegisterServer(ctx, &discoveryv1.RegisterServerRequest{
-		Name:   s.Name,
-		Labels: s.Labels,
+		Name:       s.Name,
+		Labels:     s.Labels,
+		Weight:     int64(s.Weight),
+		MaxTargets: int64(s.MaxTargets),
 	})
 
 	return err
@@ -98,3 +108,134 @@ func (s serverUnRegister) Run(g *Globals, l *zap.SugaredLogger, c *kong.Context)
 
 	return err
 }
+
+type serverCordon struct {
+	Name string `arg:"true" help:"Server name." required:"true"`
+}
+
+func (s serverCordon) Run(g *Globals, l *zap.SugaredLogger, c *kong.Context) error {
+	cli, err := g.serverClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := g.ctx()
+	defer cancel()
+
+	_, err = cli.CordonServer(ctx, &discoveryv1.CordonServerRequest{
+		Name: s.Name,
+	})
+
+	return err
+}
+
+type serverUncordon struct {
+	Name string `arg:"true" help:"Server name." required:"true"`
+}
+
+func (s serverUncordon) Run(g *Globals, l *zap.SugaredLogger, c *kong.Context) error {
+	cli, err := g.serverClient()
+	if err != nil {
+		return err
+	}
+
+	l.Infow("increasing command timeout", "timeout", registerTimout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), registerTimout)
+	defer cancel()
+
+	_, err = cli.UncordonServer(ctx, &discoveryv1.UncordonServerRequest{
+		Name: s.Name,
+	})
+
+	return err
+}
+
+type serverDrain struct {
+	Name string `arg:"true" help:"Server name." required:"true"`
+	Rate int    `help:"The number of services moved to other servers per minute." default:"100"`
+}
+
+func (s serverDrain) Run(g *Globals, l *zap.SugaredLogger, c *kong.Context) error {
+	cli, err := g.serverClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := g.ctx()
+	defer cancel()
+
+	_, err = cli.DrainServer(ctx, &discoveryv1.DrainServerRequest{
+		Name: s.Name,
+		Rate: int64(s.Rate),
+	})
+
+	return err
+}
+
+type serverRebalance struct {
+	DryRun bool `help:"Only show the moved services per server."`
+}
+
+func (s serverRebalance) Run(g *Globals, l *zap.SugaredLogger, c *kong.Context) error {
+	cli, err := g.serverClient()
+	if err != nil {
+		return err
+	}
+
+	l.Infow("increasing command timeout", "timeout", registerTimout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), registerTimout)
+	defer cancel()
+
+	r, err := cli.Rebalance(ctx, &discoveryv1.RebalanceRequest{
+		DryRun: s.DryRun,
+	})
+	if err != nil {
+		return err
+	}
+
+	moves := make([]serverMoves, 0, len(r.GetServers()))
+	for _, m := range r.GetServers() {
+		moves = append(moves, serverMoves{m})
+	}
+
+	sw := sfmt.SliceWriter{
+		Writer: os.Stdout,
+	}
+
+	sw.Write(sfmt.ParseFormat("table"), moves)
+
+	for _, f := range r.GetFailed() {
+		l.Warnw("service cannot be distributed", "service", f)
+	}
+
+	if s.DryRun {
+		fmt.Printf("%d services would be moved\n", r.GetMoved())
+		return nil
+	}
+
+	fmt.Printf("%d services moved\n", r.GetMoved())
+
+	return nil
+}
+
+// serverMoves are the moved services of a server.
+type serverMoves struct {
+	*discoveryv1.ServerMoves
+}
+
+// Header creates the header for csv or table output.
+func (m serverMoves) Header() []string {
+	return []string{"SERVER", "ADDED", "REMOVED", "TARGETS"}
+}
+
+// Row creates a row for csv or table output.
+func (m serverMoves) Row() []string {
+	return []string{
+		m.GetServer(),
+		strconv.FormatUint(uint64(m.GetAdded()), 10),
+		strconv.FormatUint(uint64(m.GetRemoved()), 10),
+		strconv.FormatUint(uint64(m.GetTargets()), 10),
+	}
+}
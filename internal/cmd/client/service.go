@@ -15,20 +15,23 @@ import (
 	"github.com/sethvargo/go-retry"
 	"github.com/zbindenren/sfmt"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
 	"gopkg.in/yaml.v3"
 	"k8s.io/apimachinery/pkg/labels"
 )
 
 const (
-	retryInterval = 1 * time.Second
-	maxRetries    = 3
+	retryInterval   = 1 * time.Second
+	maxRetries      = 3
+	importBatchSize = 500
 )
 
 type serviceCmd struct {
 	List       serviceList       `cmd:"" help:"List registered services."`
 	Register   serviceRegister   `cmd:"" help:"Register a service."`
 	UnRegister serviceUnRegister `cmd:"" help:"Unregister a service by ID or endpoint URL." name:"unregister"`
-	Import     serviceImport     `cmd:"" help:"Import services from yaml file."`
+	Heartbeat  serviceHeartbeat  `cmd:"" help:"Renew the lease of services registered with a ttl."`
+	Import     serviceImport     `cmd:"" help:"Import services from yaml file. Services are registered one by one, a failed service does not prevent the others."`
 }
 
 type serviceList struct {
@@ -97,11 +100,13 @@ func (s serviceList) Run(g *Globals, l *zap.SugaredLogger, c *kong.Context) erro
 }
 
 type serviceRegister struct {
-	Endpoints []string         `short:"e" help:"The service endpoint URLs." required:"true"`
+	Endpoints []string         `short:"e" help:"The service endpoint URLs. Multiple endpoints are registered one by one, a failed endpoint does not prevent the others." required:"true"`
 	Name      string           `arg:"true" required:"true" help:"The service name. This will represent the job name in prometheus." env:"DISCOVERY_NAME"`
 	Labels    discovery.Labels `short:"l" help:"Labels for the service." mapsep:","`
 	Namespace string           `short:"n" help:"The namespace for the service" default:"default" required:"true"`
 	Selector  string           `short:"s" help:"Kubernetes style selectors (key=value) to select servers with specific labels."`
+	TTL       time.Duration    `short:"t" help:"Unregister the service, if it is not renewed with 'service heartbeat' within ttl (0 means no ttl)." name:"ttl"`
+	Replicas  int              `short:"r" help:"The number of servers the service is distributed to (0 means the replicas of the namespace)."`
 }
 
 func (s serviceRegister) Run(g *Globals, l *zap.SugaredLogger, c *kong.Context) error {
@@ -114,52 +119,74 @@ func (s serviceRegister) Run(g *Globals, l *zap.SugaredLogger, c *kong.Context)
 		return errors.New("name cannot be empty")
 	}
 
-	var (
-		lastErr error
-		b       = retry.WithMaxRetries(maxRetries, retry.NewExponential(retryInterval))
-	)
+	req := &discoveryv1.RegisterServicesRequest{}
 
 	for _, ep := range s.Endpoints {
-		ctx, cancel := g.ctx()
+		req.Services = append(req.Services, &discoveryv1.RegisterServiceRequest{
+			Name:      s.Name,
+			Namespace: s.Namespace,
+			Endpoint:  ep,
+			Labels:    s.Labels,
+			Selector:  s.Selector,
+			Ttl:       ttlToPB(s.TTL),
+			Replicas:  uint32(s.Replicas),
+		})
+	}
 
-		lbls := s.Labels
-		id := ""
+	ctx, cancel := g.ctx()
+	defer cancel()
 
-		if err := retry.Do(ctx, b, func(ctx context.Context) error {
-			r, err := cli.RegisterService(ctx, &discoveryv1.RegisterServiceRequest{
-				Name:      s.Name,
-				Namespace: s.Namespace,
-				Endpoint:  ep,
-				Labels:    lbls,
-				Selector:  s.Selector,
-			})
-			if err != nil {
-				l.Errorw("retry register", "service", ep, "err", err)
-				return retry.RetryableError(err)
-			}
+	r, err := registerServices(ctx, l, cli, req)
+	if err != nil {
+		return err
+	}
 
-			id = r.GetService().GetId()
+	var failed bool
 
-			return nil
-		}); err != nil {
-			lastErr = err
-			l.Errorw("failed to register", "service", ep, "err", err)
+	for i, res := range r.GetResults() {
+		ep := req.GetServices()[i].GetEndpoint()
+
+		if res.GetCode() != uint32(codes.OK) {
+			failed = true
+
+			l.Errorw("failed to register", "service", ep, "code", codes.Code(res.GetCode()).String(), "err", res.GetError())
 
 			continue
 		}
 
-		cancel()
-
-		l.Infow("service registered", "id", id)
+		l.Infow("service registered", "id", res.GetService().GetId())
 	}
 
-	if lastErr != nil {
+	if failed {
 		return errors.New("register service failed")
 	}
 
 	return nil
 }
 
+// registerServices calls RegisterServices and retries on errors.
+func registerServices(ctx context.Context, l *zap.SugaredLogger, cli discoveryv1.ServiceAPIClient,
+	req *discoveryv1.RegisterServicesRequest) (*discoveryv1.RegisterServicesResponse, error) {
+	var (
+		resp *discoveryv1.RegisterServicesResponse
+		b    = retry.WithMaxRetries(maxRetries, retry.NewExponential(retryInterval))
+	)
+
+	err := retry.Do(ctx, b, func(ctx context.Context) error {
+		r, err := cli.RegisterServices(ctx, req)
+		if err != nil {
+			l.Errorw("retry register", "services", len(req.GetServices()), "err", err)
+			return retry.RetryableError(err)
+		}
+
+		resp = r
+
+		return nil
+	})
+
+	return resp, err
+}
+
 type serviceUnRegister struct {
 	Endpoints  []string `arg:"true" optional:"true" help:"The service endpoint URLs or IDs." env:"DISCOVERY_ENDPOINTS"`
 	Namespace  string   `short:"n" help:"The namespace for the service" xor:"X"`
@@ -221,6 +248,59 @@ func (s serviceUnRegister) Run(g *Globals, l *zap.SugaredLogger, c *kong.Context
 	return nil
 }
 
+type serviceHeartbeat struct {
+	Endpoints []string `arg:"true" help:"The service endpoint URLs or IDs." env:"DISCOVERY_ENDPOINTS"`
+	Namespace string   `short:"n" help:"The namespace for the service" default:"default" required:"true"`
+}
+
+func (s serviceHeartbeat) Run(g *Globals, l *zap.SugaredLogger, c *kong.Context) error {
+	cli, err := g.serviceClient()
+	if err != nil {
+		return err
+	}
+
+	var (
+		lastErr error
+		b       = retry.WithMaxRetries(maxRetries, retry.NewExponential(retryInterval))
+	)
+
+	for _, ep := range s.Endpoints {
+		ctx, cancel := g.ctx()
+
+		var expires time.Time
+
+		if err := retry.Do(ctx, b, func(ctx context.Context) error {
+			r, err := cli.Heartbeat(ctx, &discoveryv1.HeartbeatRequest{
+				Namespace: s.Namespace,
+				Id:        ep,
+			})
+			if err != nil {
+				l.Errorw("retry heartbeat", "service", ep, "err", err)
+				return retry.RetryableError(err)
+			}
+
+			expires = convert.TimeFromPB(r.GetExpiresAt())
+
+			return nil
+		}); err != nil {
+			lastErr = err
+			l.Errorw("failed to renew lease", "service", ep, "namespace", s.Namespace, "err", err)
+
+			continue
+		}
+
+		cancel()
+
+		l.Infow("lease renewed", "service", ep, "expires", expires)
+	}
+
+	if lastErr != nil {
+		return errors.New("heartbeat failed")
+	}
+
+	return nil
+}
+
 func (s serviceUnRegister) unRegisterUnresolved(g *Globals, l *zap.SugaredLogger, cli discoveryv1.ServiceAPIClient) error {
 	var lastErr error
 
@@ -356,26 +436,49 @@ func (s serviceImport) Run(g *Globals, l *zap.SugaredLogger, c *kong.Context) er
 
 	failed := discovery.Services{}
 
-	for j := range services {
-		s := services[j]
-		l.Debugw("import serivce", s.KeyVals()...)
-
-		_, err := cli.RegisterService(ctx, &discoveryv1.RegisterServiceRequest{
-			Name:        s.Name,
-			Endpoint:    s.Endpoint.String(),
-			Description: s.Description,
-			Labels:      s.Labels,
-			Namespace:   s.Namespace,
-			Selector:    s.Selector,
-		})
+	for start := 0; start < len(services); start += importBatchSize {
+		end := start + importBatchSize
+		if end > len(services) {
+			end = len(services)
+		}
+
+		batch := services[start:end]
+		req := &discoveryv1.RegisterServicesRequest{}
+
+		for j := range batch {
+			s := batch[j]
+			l.Debugw("import serivce", s.KeyVals()...)
+
+			req.Services = append(req.Services, &discoveryv1.RegisterServiceRequest{
+				Name:        s.Name,
+				Endpoint:    s.Endpoint.String(),
+				Description: s.Description,
+				Labels:      s.Labels,
+				Namespace:   s.Namespace,
+				Selector:    s.Selector,
+				Ttl:         ttlToPB(s.TTL),
+			})
+		}
+
+		r, err := registerServices(ctx, l, cli, req)
 		if err != nil {
-			failed = append(failed, s)
-			msg := s.KeyVals()
-			msg = append(msg, "err", err)
-			l.Errorw("failed to import", msg...)
+			l.Errorw("failed to import", "services", len(batch), "err", err)
+
+			failed = append(failed, batch...)
 
 			continue
 		}
+
+		for j, res := range r.GetResults() {
+			if res.GetCode() == uint32(codes.OK) {
+				continue
+			}
+
+			failed = append(failed, batch[j])
+			msg := batch[j].KeyVals()
+			msg = append(msg, "err", res.GetError())
+			l.Errorw("failed to import", msg...)
+		}
 	}
 
 	if len(failed) > 0 {
@@ -398,3 +501,11 @@ func (s serviceImport) Run(g *Globals, l *zap.SugaredLogger, c *kong.Context) er
 
 	return nil
 }
+
+func ttlToPB(ttl time.Duration) string {
+	if ttl == 0 {
+		return ""
+	}
+
+	return ttl.String()
+}
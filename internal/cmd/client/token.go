@@ -2,23 +2,29 @@ package client
 
 import (
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/alecthomas/kong"
 	"github.com/postfinance/discovery/internal/server/convert"
 	discoveryv1 "github.com/postfinance/discovery/pkg/discoverypb/postfinance/discovery/v1"
+	"github.com/zbindenren/sfmt"
 	"go.uber.org/zap"
 )
 
 type tokenCmd struct {
 	Create tokenCreate `cmd:"" help:"Create an access token."`
 	Info   tokenInfo   `cmd:"" help:"Get information about created tokens."`
+	List   tokenList   `cmd:"" help:"List issued machine tokens."`
+	Revoke tokenRevoke `cmd:"" help:"Revoke a machine token."`
 }
 
 type tokenCreate struct {
 	ID         string        `arg:"" short:"i" help:"An ID that can identify token (i.e: username)" required:"true"`
 	Expiry     time.Duration `short:"e" default:"0" help:"How long (duration) should the token be valid. 0 is forever."`
 	Namespaces []string      `short:"n" help:"The namespaces the token has access to." required:"true"`
+	Scope      string        `help:"The token scope. Tokens with scope sd can only list target groups for prometheus http service discovery." enum:",sd" default:""`
+	Servers    []string      `short:"s" name:"server" help:"The servers a scrape token (scope sd) can list target groups for. Required for scrape tokens."`
 }
 
 func (t tokenCreate) Run(g *Globals, l *zap.SugaredLogger, c *kong.Context) error {
@@ -34,6 +40,8 @@ func (t tokenCreate) Run(g *Globals, l *zap.SugaredLogger, c *kong.Context) erro
 		Expires:    t.Expiry.String(),
 		Id:         t.ID,
 		Namespaces: t.Namespaces,
+		Scope:      t.Scope,
+		Servers:    t.Servers,
 	})
 	if err != nil {
 		return err
@@ -73,8 +81,69 @@ func (t tokenInfo) Run(g *Globals, l *zap.SugaredLogger, c *kong.Context) error
 	}
 
 	fmt.Println("id:", i.GetId())
+	fmt.Println("token id:", i.GetTokenId())
+
+	if i.GetScope() != "" {
+		fmt.Println("scope:", i.GetScope())
+		fmt.Println("servers:", i.GetServers())
+	}
+
 	fmt.Println("namespaces:", i.GetNamespaces())
 	fmt.Println("expiry:", expiryStr)
 
 	return nil
 }
+
+type tokenList struct {
+	Output  string `short:"o" default:"table" help:"Output formats. Valid formats: json, yaml, csv, table."`
+	Headers bool   `short:"H" help:"Show headers."`
+}
+
+func (t tokenList) Run(g *Globals, l *zap.SugaredLogger, c *kong.Context) error {
+	cli, err := g.tokenClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := g.ctx()
+	defer cancel()
+
+	r, err := cli.List(ctx, &discoveryv1.ListRequest{})
+	if err != nil {
+		return err
+	}
+
+	sw := sfmt.SliceWriter{
+		Writer:    os.Stdout,
+		NoHeaders: !t.Headers,
+	}
+
+	sw.Write(sfmt.ParseFormat(t.Output), convert.TokensFromPB(r.GetTokens()))
+
+	return nil
+}
+
+type tokenRevoke struct {
+	TokenID string `arg:"" help:"The token id (see token list or token info)." required:"true"`
+}
+
+func (t tokenRevoke) Run(g *Globals, l *zap.SugaredLogger, c *kong.Context) error {
+	cli, err := g.tokenClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := g.ctx()
+	defer cancel()
+
+	r, err := cli.Revoke(ctx, &discoveryv1.RevokeRequest{
+		TokenId: t.TokenID,
+	})
+	if err != nil {
+		return err
+	}
+
+	l.Infow("token revoked", "token-id", t.TokenID, "id", r.GetToken().GetId())
+
+	return nil
+}
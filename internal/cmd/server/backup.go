@@ -0,0 +1,129 @@
+package server
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/postfinance/discovery/internal/backup"
+	"go.uber.org/zap"
+)
+
+type backupCmd struct {
+	Output string `short:"o" help:"The archive file. If '-', the archive is written to stdout." default:"-"`
+	Format string `help:"The archive format. With auto, the format is derived from the file extension (.yaml or .yml for yaml, json otherwise)." enum:"auto,json,yaml" default:"auto"`
+}
+
+func (b backupCmd) Run(g *Globals, l *zap.SugaredLogger) error {
+	be, err := g.backend()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := be.Close(); err != nil {
+			l.Errorw("failed to close store backend", "err", err)
+		}
+	}()
+
+	a, err := backup.Create(be)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+
+	if b.Output != "-" {
+		f, err := os.OpenFile(filepath.Clean(b.Output), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+
+		w = f
+	}
+
+	if err := a.Write(w, archiveFormat(b.Format, b.Output)); err != nil {
+		return err
+	}
+
+	l.Infow("backup created",
+		"file", b.Output,
+		"namespaces", len(a.Namespaces),
+		"servers", len(a.Servers),
+		"services", len(a.Services),
+		"tokens", len(a.Tokens),
+	)
+
+	return nil
+}
+
+type restoreCmd struct {
+	Input    string `arg:"true" help:"The archive file. If '-', the archive is read from stdin."`
+	Format   string `help:"The archive format. With auto, the format is derived from the file extension (.yaml or .yml for yaml, json otherwise)." enum:"auto,json,yaml" default:"auto"`
+	Conflict string `help:"How to handle objects that already exist: fail (nothing is restored), skip (existing objects are kept) or overwrite." enum:"fail,skip,overwrite" default:"fail"`
+}
+
+func (r restoreCmd) Run(g *Globals, l *zap.SugaredLogger) error {
+	var rd io.Reader = os.Stdin
+
+	if r.Input != "-" {
+		f, err := os.Open(filepath.Clean(r.Input))
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+
+		rd = f
+	}
+
+	a, err := backup.Read(rd, archiveFormat(r.Format, r.Input))
+	if err != nil {
+		return err
+	}
+
+	be, err := g.backend()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := be.Close(); err != nil {
+			l.Errorw("failed to close store backend", "err", err)
+		}
+	}()
+
+	if a.Version < 2 {
+		l.Warnw("the archive contains no tokens: revocations missing in the target store are lost and the revoked tokens are accepted again", "version", a.Version)
+	}
+
+	res, err := backup.Restore(be, a, backup.Policy(r.Conflict))
+	if err != nil {
+		return err
+	}
+
+	l.Infow("backup restored",
+		"file", r.Input,
+		"created", a.Created,
+		"namespaces", res.Namespaces.Restored,
+		"servers", res.Servers.Restored,
+		"services", res.Services.Restored,
+		"tokens", res.Tokens.Restored,
+		"skipped-namespaces", res.Namespaces.Skipped,
+		"skipped-servers", res.Servers.Skipped,
+		"skipped-services", res.Services.Skipped,
+		"skipped-tokens", res.Tokens.Skipped,
+	)
+
+	return nil
+}
+
+func archiveFormat(format, path string) backup.Format {
+	if format != "auto" {
+		return backup.Format(format)
+	}
+
+	return backup.FormatFromPath(path)
+}
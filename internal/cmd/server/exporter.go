@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -36,11 +37,21 @@ func (e exporterCmd) Run(g *Globals, l *zap.SugaredLogger, app *kong.Context, re
 			Register(app.Model.Name, registry).
 			List()...)
 
+	if g.Backend == "bolt" {
+		return errors.New("the bolt backend can only be used by one process, export the services with 'server --export-server' instead")
+	}
+
 	b, err := g.backend()
 	if err != nil {
 		return err
 	}
 
+	defer func() {
+		if err := b.Close(); err != nil {
+			l.Errorw("failed to close store backend", "err", err)
+		}
+	}()
+
 	if err := os.MkdirAll(e.Directory, 0o700); err != nil {
 		return fmt.Errorf("failed to create directory %s: %w", e.Directory, err)
 	}
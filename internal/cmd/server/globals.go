@@ -1,20 +1,28 @@
 package server
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
+	"fmt"
 	"os"
 	"regexp"
 	"time"
 
+	"github.com/postfinance/discovery/internal/bolt"
+	etcdtxn "github.com/postfinance/discovery/internal/etcd"
 	"github.com/postfinance/profiler"
 	"github.com/postfinance/store"
 	"github.com/postfinance/store/etcd"
 	"github.com/zbindenren/king"
+	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
 // Globals are the global server flags.
 type Globals struct {
-	Etcd `prefix:"etcd-"`
+	Backend string `help:"The store backend: etcd or bolt (an embedded single node store, that can only be used by one process, i.e. one discovery server with --export-server and no separate exporter)." enum:"etcd,bolt" default:"etcd"`
+	Etcd    `prefix:"etcd-"`
+	Bolt    boltFlags `embed:"true" prefix:"bolt-"`
 	// EnvHelp    king.EnvHelpFlag `help:"Show context-sensitive help about environment variables"`
 	Debug      bool             `help:"Show debug output"`
 	ShowConfig king.ShowConfig  `help:"Show used config files"`
@@ -39,16 +47,28 @@ type Etcd struct {
 	RequestTimeout   time.Duration `help:"Etcd request timeout" default:"5s"`
 }
 
+type boltFlags struct {
+	Path string `help:"The database file of the bolt backend. It can only be used by one process at a time." default:"discovery.db"`
+}
+
 var (
 	isValidPrefix = regexp.MustCompile(`^/[a-z-]+$`)
 )
 
+func (g Globals) backend() (store.Backend, error) {
+	if g.Backend == "bolt" {
+		return bolt.New(g.Bolt.Path)
+	}
+
+	return g.Etcd.backend()
+}
+
 func (e Etcd) backend() (store.Backend, error) {
 	if !isValidPrefix.MatchString(e.Prefix) {
 		return nil, errors.New("store prefix must start with '/' followed by at least one letter in the range 'a-z'")
 	}
 
-	return etcd.New(
+	b, err := etcd.New(
 		etcd.WithEndpoints(e.Endpoints),
 		etcd.WithUsername(e.User),
 		etcd.WithPrefix(e.Prefix),
@@ -63,6 +83,81 @@ func (e Etcd) backend() (store.Backend, error) {
 		etcd.WithRequestTimeout(e.RequestTimeout),
 		etcd.WithAutoSyncInterval(e.AutoSyncInterval),
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	// the store backend has no transactions, they are written with a separate client
+	tlsConfig, err := e.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   e.Endpoints,
+		Username:    e.User,
+		Password:    e.Password,
+		DialTimeout: e.DialTimeout,
+		TLS:         tlsConfig,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create etcd client: %w", err)
+	}
+
+	return etcdtxn.New(b, client, e.Prefix, e.RequestTimeout), nil
+}
+
+// tlsConfig returns the client TLS configuration or nil, if no certificates are configured.
+func (e Etcd) tlsConfig() (*tls.Config, error) {
+	cert, key, ca := []byte(e.Cert), []byte(e.Key), []byte(e.CA)
+
+	for _, f := range []struct {
+		name string
+		data *[]byte
+	}{
+		{e.CertFile, &cert},
+		{e.KeyFile, &key},
+		{e.CAFile, &ca},
+	} {
+		if f.name == "" {
+			continue
+		}
+
+		data, err := os.ReadFile(f.name)
+		if err != nil {
+			return nil, err
+		}
+
+		*f.data = data
+	}
+
+	if len(cert) == 0 && len(key) == 0 && len(ca) == 0 {
+		return nil, nil //nolint:nilnil // no TLS
+	}
+
+	c := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if len(cert) > 0 || len(key) > 0 {
+		pair, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load etcd certificate: %w", err)
+		}
+
+		c.Certificates = []tls.Certificate{pair}
+	}
+
+	if len(ca) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("failed to load etcd CA")
+		}
+
+		c.RootCAs = pool
+	}
+
+	return c, nil
 }
 
 type profilerFlags struct {
@@ -3,44 +3,82 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
 	"regexp"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/alecthomas/kong"
 	"github.com/postfinance/discovery/internal/auth"
+	"github.com/postfinance/discovery/internal/exporter"
+	"github.com/postfinance/discovery/internal/hash"
 	"github.com/postfinance/discovery/internal/server"
+	"github.com/postfinance/store"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/zbindenren/king"
 	"go.uber.org/zap"
 )
 
+const exporterRestartDelay = 10 * time.Second
+
 // CLI is the server command.
 type CLI struct {
 	Globals
 	Server   serverCmd   `cmd:"" help:"Start discovery grpc server" default:"1"`
 	Exporter exporterCmd `cmd:"" help:"Start exporter server"`
+	Backup   backupCmd   `cmd:"" help:"Write all namespaces, servers and services to an archive."`
+	Restore  restoreCmd  `cmd:"" help:"Restore namespaces, servers and services from an archive."`
 }
 
 type serverCmd struct {
-	GRPCListen  string    `short:"l" help:"GRPC gateway listen adddress" default:"localhost:3001"`
-	HTTPListen  string    `help:"HTTP listen adddress" default:"localhost:3002"`
-	Replicas    int       `help:"The number of service replicas." default:"1"`
-	TokenIssuer string    `help:"The jwt token issuer name. If you change this, alle issued tokens are invalid." default:"discovery.postfinance.ch"`
-	TokenSecret string    `help:"The secret key to issue jwt machine tokens. If you change this, alle issued tokens are invalid." required:"true"`
-	OIDC        oidcFlags `embed:"true" prefix:"oidc-"`
-	CACert      string    `help:"Path to a custom tls ca pem file. Certificates in this file are added to system cert pool." type:"existingfile"`
+	GRPCListen     string        `short:"l" help:"GRPC gateway listen adddress" default:"localhost:3001"`
+	HTTPListen     string        `help:"HTTP listen adddress" default:"localhost:3002"`
+	Replicas       int           `help:"The number of service replicas." default:"1"`
+	Distribution   string        `help:"The strategy to distribute services on servers (jump, rendezvous or bounded-load). Changing it moves services on the next reregistration." enum:"jump,rendezvous,bounded-load" default:"jump"`
+	TokenIssuer    string        `help:"The jwt token issuer name. If you change this, alle issued tokens are invalid." default:"discovery.postfinance.ch"`
+	TokenSecret    string        `help:"The secret key to issue HS256 signed jwt machine tokens. If you change this, alle tokens signed with the secret are invalid."`
+	TokenKey       string        `help:"Path to a pem encoded RSA, ECDSA or Ed25519 private key to sign jwt machine tokens. If set, it is used instead of the token secret." type:"existingfile"`
+	TokenLegacy    bool          `help:"Accept machine tokens issued by earlier versions, that mixed up the token issuer and the token secret. Deprecated: they will be rejected in the next major release." default:"true" negatable:""`
+	TokenVerifyKey []string      `help:"Paths to pem files with additional public keys, certificates or private keys to verify jwt machine tokens (i.e. previous signing keys)."`
+	OIDC           oidcFlags     `embed:"true" prefix:"oidc-"`
+	CACert         string        `help:"Path to a custom tls ca pem file. Certificates in this file are added to system cert pool." type:"existingfile"`
+	TrashRetention time.Duration `help:"The duration unregistered namespaces can be restored. If 0, namespaces are deleted immediately." default:"168h"`
+	PolicyFile     string        `help:"Path to a yaml policy file that maps roles to allowed api methods, namespaces and servers. The file is reloaded on changes." type:"existingfile"`
+	AuditRetention time.Duration `help:"The duration audit records of mutating api calls are kept. If 0, no audit records are written." default:"720h"`
+	TLS            tlsFlags      `embed:"true" prefix:"tls-"`
+	Export         exportFlags   `embed:"true" prefix:"export-"`
+}
+
+type exportFlags struct {
+	Server         string        `help:"If set, the services of this server are exported in the server process (i.e. with the bolt backend, that can only be used by one process)."`
+	Directory      string        `help:"The destination directory of the exported services." default:"/tmp/discovery"`
+	ResyncInterval time.Duration `help:"The interval in that the exporter resyncs all services to filesystem." default:"1h"`
+}
+
+type tlsFlags struct {
+	Cert             string   `help:"Path to a pem encoded certificate of the grpc server. If set, the grpc server only accepts tls connections." type:"existingfile"`
+	Key              string   `help:"Path to the pem encoded private key of the grpc server certificate." type:"existingfile"`
+	ClientCA         string   `name:"client-ca" help:"Path to a pem file with the ca certificates to verify client certificates. Clients with a valid certificate are authenticated without token." type:"existingfile"`
+	ClientNamespaces []string `name:"client-namespaces" help:"Namespaces of clients whose certificate common name or dns name matches the pattern (i.e. *.example.com=default). Clients without matching pattern have no namespaces." placeholder:"PATTERN=NAMESPACE"`
 }
 
 type oidcFlags struct {
-	Endpoint      string   `help:"OIDC endpoint URL." required:"true"`
-	ClientID      string   `help:"OIDC client ID." required:"true"`
-	Roles         []string `help:"The the roles that are allowed to change servers and namespaces and to issue machine tokens." required:"true"`
-	UsernameClaim string   `name:"username-claim" help:"The URL to the oidc server." default:"username"`
-	RolesClaim    string   `name:"roles-claim" help:"The URL to the oidc server." default:"roles"`
+	Endpoint              string   `help:"OIDC endpoint URL." required:"true"`
+	ClientID              string   `help:"OIDC client ID." required:"true"`
+	Roles                 []string `help:"The the roles that are allowed to change servers and namespaces and to issue machine tokens. Ignored if a policy file is configured."`
+	UsernameClaim         string   `name:"username-claim" help:"The URL to the oidc server." default:"username"`
+	RolesClaim            string   `name:"roles-claim" help:"The URL to the oidc server." default:"roles"`
+	NamespacesClaim       string   `name:"namespaces-claim" help:"The claim with the namespaces a user has access to."`
+	Audience              string   `help:"The audience of oidc client credentials access tokens. If set, services can authenticate with access tokens of the oidc provider. It must differ from the client id."`
+	ClientNamespacesClaim string   `name:"client-namespaces-claim" help:"The claim with the namespaces a client credentials token has access to." default:"namespaces"`
+	RoleNamespaces        []string `name:"role-namespaces" help:"Namespaces of users with a role (i.e. team-a=team-a-*). If neither a namespaces claim nor role namespaces are configured, users have access to all namespaces." placeholder:"ROLE=NAMESPACE"`
 }
 
 //nolint:interfacer // kong does not work with interfaces
@@ -64,6 +102,12 @@ func (s serverCmd) Run(g *Globals, l *zap.SugaredLogger, app *kong.Context, regi
 		return err
 	}
 
+	defer func() {
+		if err := b.Close(); err != nil {
+			l.Errorw("failed to close store backend", "err", err)
+		}
+	}()
+
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
@@ -72,6 +116,14 @@ func (s serverCmd) Run(g *Globals, l *zap.SugaredLogger, app *kong.Context, regi
 		return err
 	}
 
+	if s.Export.Server != "" {
+		if err := os.MkdirAll(s.Export.Directory, 0o700); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", s.Export.Directory, err)
+		}
+
+		go s.Export.run(ctx, b, l.Named("exporter"))
+	}
+
 	return srv.Run(ctx)
 }
 
@@ -87,17 +139,132 @@ func (s serverCmd) config(registry prometheus.Registerer) (server.Config, error)
 		transport = auth.NewTLSTransportFromCertPool(pool)
 	}
 
+	if s.PolicyFile == "" && len(s.OIDC.Roles) == 0 {
+		return server.Config{}, errors.New("either --oidc-roles or --policy-file is required")
+	}
+
+	if s.OIDC.Audience != "" && s.OIDC.Audience == s.OIDC.ClientID {
+		return server.Config{}, errors.New("--oidc-audience must differ from --oidc-client-id")
+	}
+
+	if s.TokenSecret == "" && s.TokenKey == "" {
+		return server.Config{}, errors.New("either --token-secret or --token-key is required")
+	}
+
+	roleNamespaces, err := parseNamespaceMappings(s.OIDC.RoleNamespaces, "ROLE")
+	if err != nil {
+		return server.Config{}, err
+	}
+
+	clientNamespaces, err := parseNamespaceMappings(s.TLS.ClientNamespaces, "PATTERN")
+	if err != nil {
+		return server.Config{}, err
+	}
+
+	tlsConfig, err := s.TLS.config()
+	if err != nil {
+		return server.Config{}, err
+	}
+
+	claimConfig := auth.NewClaimConfig(s.OIDC.UsernameClaim, s.OIDC.RolesClaim,
+		auth.WithNamespacesClaim(s.OIDC.NamespacesClaim),
+		auth.WithRoleNamespaces(roleNamespaces),
+		auth.WithClientNamespacesClaim(s.OIDC.ClientNamespacesClaim),
+	)
+
+	distributor, err := hash.NewDistributor(s.Distribution)
+	if err != nil {
+		return server.Config{}, err
+	}
+
+	var keys *auth.KeySet
+
+	if s.TokenKey != "" || len(s.TokenVerifyKey) > 0 {
+		ks, err := auth.LoadKeySet(s.TokenKey, s.TokenVerifyKey...)
+		if err != nil {
+			return server.Config{}, fmt.Errorf("failed to load token keys: %w", err)
+		}
+
+		keys = ks
+	}
+
 	return server.Config{
 		PrometheusRegistry: registry,
 		NumReplicas:        s.Replicas,
+		Distributor:        distributor,
+		TrashRetention:     s.TrashRetention,
+		AuditRetention:     s.AuditRetention,
 		GRPCListenAddr:     s.GRPCListen,
 		HTTPListenAddr:     s.HTTPListen,
 		TokenIssuer:        s.TokenIssuer,
 		TokenSecretKey:     s.TokenSecret,
+		TokenKeys:          keys,
+		TokenLegacy:        s.TokenLegacy,
 		OIDCClient:         s.OIDC.ClientID,
 		OIDCRoles:          s.OIDC.Roles,
+		PolicyFile:         s.PolicyFile,
 		OIDCURL:            s.OIDC.Endpoint,
-		ClaimConfig:        auth.NewClaimConfig(s.OIDC.UsernameClaim, s.OIDC.RolesClaim),
+		OIDCAudience:       s.OIDC.Audience,
+		ClaimConfig:        claimConfig,
 		Transport:          transport,
+		TLSConfig:          tlsConfig,
+		CertConfig:         auth.NewCertConfig(clientNamespaces),
 	}, nil
 }
+
+// config returns the tls configuration of the grpc server or nil, if no certificate is configured.
+func (t tlsFlags) config() (*tls.Config, error) {
+	if t.Cert == "" && t.Key == "" {
+		if t.ClientCA != "" {
+			return nil, errors.New("--tls-client-ca requires --tls-cert and --tls-key")
+		}
+
+		return nil, nil
+	}
+
+	if t.Cert == "" || t.Key == "" {
+		return nil, errors.New("--tls-cert and --tls-key are required together")
+	}
+
+	return auth.NewServerTLSConfig(t.Cert, t.Key, t.ClientCA)
+}
+
+// parseNamespaceMappings parses KEY=NAMESPACE mappings.
+func parseNamespaceMappings(mappings []string, key string) (map[string][]string, error) {
+	m := make(map[string][]string)
+
+	for _, mapping := range mappings {
+		parts := strings.SplitN(mapping, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid namespace mapping '%s': expected %s=NAMESPACE", mapping, key)
+		}
+
+		m[parts[0]] = append(m[parts[0]], parts[1])
+	}
+
+	return m, nil
+}
+
+// run runs the exporter until context ctx is canceled. The exporter is restarted on errors,
+// i.e. if the server is not yet registered.
+func (e exportFlags) run(ctx context.Context, b store.Backend, l *zap.SugaredLogger) {
+	for {
+		exp := exporter.New(b, l, exporter.Config{
+			Directory:      e.Directory,
+			ResyncInterval: e.ResyncInterval,
+		})
+
+		err := exp.Start(ctx, e.Server)
+		if ctx.Err() != nil {
+			return
+		}
+
+		l.Errorw("exporter stopped", "server", e.Server, "err", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(exporterRestartDelay):
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Package etcd adds transactions to the etcd store backend.
+package etcd
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"github.com/postfinance/store"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Backend is the etcd store backend with transactions. Reads, writes and watches are
+// served by the store backend, transactions by an etcd client connected to the same
+// cluster.
+type Backend struct {
+	store.Backend
+	kv      clientv3.KV
+	closer  func() error
+	prefix  string
+	timeout time.Duration
+}
+
+var _ store.Backend = (*Backend)(nil)
+
+// New creates a backend with transactions. The keys are prefixed with prefix like in the
+// store backend. The backend closes client, when it is closed.
+func New(backend store.Backend, client *clientv3.Client, prefix string, timeout time.Duration) *Backend {
+	return &Backend{
+		Backend: backend,
+		kv:      client.KV,
+		closer:  client.Close,
+		prefix:  prefix,
+		timeout: timeout,
+	}
+}
+
+// Txn puts entries and deletes keys in one etcd transaction, if the values of the compared
+// keys are unchanged. A compared entry without value must not exist.
+func (b *Backend) Txn(cmps, puts []store.Entry, dels []string) (bool, error) {
+	ifs := make([]clientv3.Cmp, 0, len(cmps))
+
+	for _, c := range cmps {
+		k := b.key(c.Key)
+
+		if c.Value == nil {
+			ifs = append(ifs, clientv3.Compare(clientv3.CreateRevision(k), "=", 0))
+			continue
+		}
+
+		ifs = append(ifs, clientv3.Compare(clientv3.Value(k), "=", string(c.Value)))
+	}
+
+	ops := make([]clientv3.Op, 0, len(puts)+len(dels))
+
+	for _, e := range puts {
+		ops = append(ops, clientv3.OpPut(b.key(e.Key), string(e.Value)))
+	}
+
+	for _, k := range dels {
+		ops = append(ops, clientv3.OpDelete(b.key(k)))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+
+	resp, err := b.kv.Txn(ctx).If(ifs...).Then(ops...).Commit()
+	if err != nil {
+		return false, err
+	}
+
+	return resp.Succeeded, nil
+}
+
+// Close closes the store backend and the etcd client.
+func (b *Backend) Close() error {
+	if err := b.Backend.Close(); err != nil {
+		return err
+	}
+
+	return b.closer()
+}
+
+func (b *Backend) key(k string) string {
+	return path.Join(b.prefix, k)
+}
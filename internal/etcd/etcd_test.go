@@ -0,0 +1,172 @@
+package etcd
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/postfinance/discovery/internal/repo"
+	"github.com/postfinance/store"
+	"github.com/postfinance/store/hash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func TestTxn(t *testing.T) {
+	kv := &fakeKV{data: map[string]string{}}
+	b := newTestBackend(t, kv)
+
+	var _ repo.Txn = b
+
+	ok, err := b.Txn([]store.Entry{{Key: "a"}}, []store.Entry{{Key: "a", Value: []byte("1")}}, nil)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"/discovery/a": "1"}, kv.data)
+
+	ok, err = b.Txn([]store.Entry{{Key: "a"}}, []store.Entry{{Key: "a", Value: []byte("2")}}, nil)
+	require.NoError(t, err)
+	assert.False(t, ok, "a exists")
+
+	ok, err = b.Txn([]store.Entry{{Key: "a", Value: []byte("2")}}, nil, []string{"a"})
+	require.NoError(t, err)
+	assert.False(t, ok, "a changed")
+	assert.Equal(t, "1", kv.data["/discovery/a"])
+
+	ok, err = b.Txn([]store.Entry{{Key: "a", Value: []byte("1")}}, []store.Entry{{Key: "b", Value: []byte("1")}}, []string{"a"})
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"/discovery/b": "1"}, kv.data)
+}
+
+func TestLock(t *testing.T) {
+	kv := &fakeKV{data: map[string]string{}}
+
+	// the lock repo reads from the store backend, which is updated by the transactions
+	kv.mirror = newTestBackend(t, kv).Backend
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		holders []string
+	)
+
+	for _, owner := range []string{"owner1", "owner2", "owner3", "owner4"} {
+		owner := owner
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			ok, err := repo.NewLock(&Backend{Backend: kv.mirror, kv: kv, prefix: "/discovery", timeout: time.Second}, owner).Acquire("rebalance", time.Minute)
+			assert.NoError(t, err)
+
+			if ok {
+				mu.Lock()
+				holders = append(holders, owner)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Len(t, holders, 1, "only one owner holds the lock")
+}
+
+func newTestBackend(t *testing.T, kv *fakeKV) *Backend {
+	mem, err := hash.New(hash.WithPrefix("/discovery"))
+	require.NoError(t, err)
+
+	return &Backend{
+		Backend: mem,
+		kv:      kv,
+		closer:  func() error { return nil },
+		prefix:  "/discovery",
+		timeout: time.Second,
+	}
+}
+
+// fakeKV evaluates transactions like etcd: the comparisons and operations are applied
+// atomically. Successful transactions are mirrored to a store backend.
+type fakeKV struct {
+	clientv3.KV
+	mu     sync.Mutex
+	data   map[string]string
+	mirror store.Backend
+}
+
+func (f *fakeKV) Txn(context.Context) clientv3.Txn {
+	return &fakeTxn{kv: f}
+}
+
+type fakeTxn struct {
+	kv   *fakeKV
+	cmps []clientv3.Cmp
+	ops  []clientv3.Op
+}
+
+func (t *fakeTxn) If(cs ...clientv3.Cmp) clientv3.Txn {
+	t.cmps = append(t.cmps, cs...)
+	return t
+}
+
+func (t *fakeTxn) Then(ops ...clientv3.Op) clientv3.Txn {
+	t.ops = append(t.ops, ops...)
+	return t
+}
+
+func (t *fakeTxn) Else(...clientv3.Op) clientv3.Txn {
+	return t
+}
+
+func (t *fakeTxn) Commit() (*clientv3.TxnResponse, error) {
+	t.kv.mu.Lock()
+	defer t.kv.mu.Unlock()
+
+	for i := range t.cmps {
+		c := t.cmps[i]
+		v, exists := t.kv.data[string(c.KeyBytes())]
+
+		switch c.Target {
+		case pb.Compare_CREATE:
+			if exists != (c.TargetUnion.(*pb.Compare_CreateRevision).CreateRevision != 0) {
+				return &clientv3.TxnResponse{}, nil
+			}
+		case pb.Compare_VALUE:
+			if !exists || v != string(c.ValueBytes()) {
+				return &clientv3.TxnResponse{}, nil
+			}
+		default:
+			panic("unsupported comparison")
+		}
+	}
+
+	for _, op := range t.ops {
+		k := string(op.KeyBytes())
+
+		switch {
+		case op.IsPut():
+			t.kv.data[k] = string(op.ValueBytes())
+
+			if t.kv.mirror != nil {
+				if _, err := t.kv.mirror.Put(&store.Entry{Key: k[len("/discovery/"):], Value: op.ValueBytes()}); err != nil {
+					return nil, err
+				}
+			}
+		case op.IsDelete():
+			delete(t.kv.data, k)
+
+			if t.kv.mirror != nil {
+				if _, err := t.kv.mirror.Del(k[len("/discovery/"):]); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return &clientv3.TxnResponse{Succeeded: true}, nil
+}
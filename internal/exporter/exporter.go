@@ -148,7 +148,10 @@ func (e *Exporter) Start(ctx context.Context, server string) error {
 }
 
 func (e *Exporter) handleService(event *repo.ServiceEvent) {
-	ignore := !event.Service.HasServer(e.server) && event.Event == repo.Change
+	onServer := event.Service.HasServer(e.server)
+	// services moved to other servers, i.e. by draining, have to be deleted
+	moved := !onServer && event.Event == repo.Change && e.destinations.getService(event.Namespace, event.ID) != nil
+	ignore := !onServer && !moved && event.Event == repo.Change
 	msg := append([]interface{}{
 		"event", event.Event.String(),
 		"ignore", ignore,
@@ -171,6 +174,11 @@ func (e *Exporter) handleService(event *repo.ServiceEvent) {
 
 	switch event.Event {
 	case repo.Change:
+		if moved {
+			_ = e.destinations.delService(event.Namespace, event.ID)
+			break
+		}
+
 		existing := e.destinations.getService(event.Namespace, event.ID)
 		// handle path changes
 		if existing != nil && existing.Name != event.Service.Name {
@@ -209,7 +217,8 @@ func (e *Exporter) handleServer(event *repo.ServerEvent) {
 			return
 		}
 
-		if event.Server.State == discovery.Active {
+		// services are moved away from draining servers by service events
+		if event.Server.State == discovery.Active || event.Server.State == discovery.Cordoned || event.Server.State == discovery.Draining {
 			e.log.Debug("sync services")
 
 			e.destinations.reset()
@@ -85,6 +85,13 @@ func TestExporter(t *testing.T) {
 	})
 	assertFileNotContains(t, filepath.Join(dir, "server1/default/initial.json"), "initial33.pnet.ch")
 
+	// moved to another server, i.e. by draining
+	serviceGetter.addEvent(&repo.ServiceEvent{
+		Event:   repo.Change,
+		Service: newService("i2", "initial", "https://initial2.pnet.ch", "other-server1"),
+	})
+	assertFileNotContains(t, filepath.Join(dir, "server1/default/initial.json"), "initial2.pnet.ch")
+
 	serviceGetter.addEvent(&repo.ServiceEvent{
 		Event:   repo.Delete,
 		Service: newService("i1", "changedjob", "https://initial1.pnet.ch"),
@@ -0,0 +1,303 @@
+package hash
+
+import (
+	"fmt"
+	"hash/crc64"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Distribution strategies.
+const (
+	JumpDistribution        = "jump"
+	RendezvousDistribution  = "rendezvous"
+	BoundedLoadDistribution = "bounded-load"
+)
+
+const (
+	// DefaultLoadFactor is the default load factor of the bounded load distributor.
+	DefaultLoadFactor = 1.25
+	// DefaultVirtualNodes is the default number of points of a server on the hash ring.
+	DefaultVirtualNodes = 100
+)
+
+//nolint:gochecknoglobals // the table is read only
+var ecmaTable = crc64.MakeTable(crc64.ECMA)
+
+// Load returns the number of keys currently assigned to the server with index i.
+type Load func(i int) int
+
+// Node is a server keys are distributed on. Keys are distributed proportionally to the
+// weights of the nodes. A weight <= 0 is treated as 1.
+type Node struct {
+	Name   string
+	Weight int
+}
+
+// Distributor distributes keys on servers.
+type Distributor interface {
+	// Distribute returns the indexes of n nodes for key. The nodes have to be sorted and
+	// unique. If n is larger than the number of nodes, all indexes are returned. Load is
+	// only called by distributors that take the current assignment into account.
+	Distribute(key string, nodes []Node, n int, load Load) []int
+}
+
+// Nodes returns nodes with weight 1 for servers.
+func Nodes(servers ...string) []Node {
+	nodes := make([]Node, 0, len(servers))
+
+	for _, s := range servers {
+		nodes = append(nodes, Node{Name: s, Weight: 1})
+	}
+
+	return nodes
+}
+
+func (n Node) weight() int {
+	if n.Weight <= 0 {
+		return 1
+	}
+
+	return n.Weight
+}
+
+// NewDistributor returns the distributor for a distribution strategy.
+func NewDistributor(strategy string) (Distributor, error) {
+	switch strategy {
+	case JumpDistribution:
+		return New(crc64.New(ecmaTable)), nil
+	case RendezvousDistribution:
+		return Rendezvous{}, nil
+	case BoundedLoadDistribution:
+		return NewBoundedLoad(DefaultLoadFactor, DefaultVirtualNodes), nil
+	default:
+		return nil, fmt.Errorf("unknown distribution strategy '%s'", strategy)
+	}
+}
+
+// Distribute returns the node chosen by the jump hash and its successors. A node with weight w
+// gets w consecutive buckets.
+func (j *Jump) Distribute(key string, nodes []Node, n int, _ Load) []int {
+	n = limit(n, len(nodes))
+
+	result := make([]int, 0, n)
+
+	if n == 0 {
+		return result
+	}
+
+	buckets := []int{}
+
+	for i := range nodes {
+		for w := 0; w < nodes[i].weight(); w++ {
+			buckets = append(buckets, i)
+		}
+	}
+
+	chosen := make([]bool, len(nodes))
+
+	for b := j.HashString(key, len(buckets)); len(result) < n; b++ {
+		i := buckets[b%len(buckets)]
+
+		if !chosen[i] {
+			chosen[i] = true
+			result = append(result, i)
+		}
+	}
+
+	sort.Ints(result)
+
+	return result
+}
+
+// Rendezvous implements highest random weight hashing. Every node gets a score per key
+// and the n nodes with the highest scores are chosen. Adding or removing a node only
+// moves the keys that are assigned to it.
+type Rendezvous struct{}
+
+// Distribute returns the n nodes with the highest weighted scores for key.
+func (Rendezvous) Distribute(key string, nodes []Node, n int, _ Load) []int {
+	n = limit(n, len(nodes))
+
+	scores := make([]float64, len(nodes))
+	indexes := make([]int, len(nodes))
+
+	for i := range nodes {
+		scores[i] = score(key, nodes[i])
+		indexes[i] = i
+	}
+
+	sort.Slice(indexes, func(a, b int) bool {
+		return scores[indexes[a]] > scores[indexes[b]]
+	})
+
+	result := indexes[:n]
+	sort.Ints(result)
+
+	return result
+}
+
+// BoundedLoad implements consistent hashing with bounded loads
+// (https://arxiv.org/abs/1608.01350). Keys are placed on the first nodes clockwise
+// on a hash ring that are not loaded more than factor times their weighted share of
+// the total load.
+type BoundedLoad struct {
+	factor       float64
+	virtualNodes int
+	mu           sync.Mutex
+	nodes        []Node // nodes of the cached ring
+	points       []point
+}
+
+// NewBoundedLoad creates a bounded load distributor. Factor has to be > 1, virtualNodes is the
+// number of points of a node with weight 1 on the ring.
+func NewBoundedLoad(factor float64, virtualNodes int) *BoundedLoad {
+	if factor <= 1 {
+		factor = DefaultLoadFactor
+	}
+
+	if virtualNodes < 1 {
+		virtualNodes = DefaultVirtualNodes
+	}
+
+	return &BoundedLoad{
+		factor:       factor,
+		virtualNodes: virtualNodes,
+	}
+}
+
+type point struct {
+	hash uint64
+	node int
+}
+
+// Distribute returns n nodes for key whose load is below their capacity. If load is nil,
+// all nodes are considered empty.
+func (b *BoundedLoad) Distribute(key string, nodes []Node, n int, load Load) []int {
+	n = limit(n, len(nodes))
+
+	result := make([]int, 0, n)
+
+	if n == 0 {
+		return result
+	}
+
+	if load == nil {
+		load = func(int) int { return 0 }
+	}
+
+	loads := make([]int, len(nodes))
+	total, weights := 0, 0
+
+	for i := range nodes {
+		loads[i] = load(i)
+		total += loads[i]
+		weights += nodes[i].weight()
+	}
+
+	capacity := func(i int) int {
+		return int(math.Ceil(b.factor * float64(total+n) * float64(nodes[i].weight()) / float64(weights)))
+	}
+
+	ring := b.ring(nodes)
+	h := crc64.Checksum([]byte(key), ecmaTable)
+	start := sort.Search(len(ring), func(i int) bool { return ring[i].hash >= h })
+	chosen := make([]bool, len(nodes))
+
+	// the second pass ignores the capacity if there are not enough nodes below capacity
+	for pass := 0; pass < 2 && len(result) < n; pass++ {
+		for i := 0; i < len(ring) && len(result) < n; i++ {
+			p := ring[(start+i)%len(ring)]
+
+			if chosen[p.node] || (pass == 0 && loads[p.node] >= capacity(p.node)) {
+				continue
+			}
+
+			chosen[p.node] = true
+			result = append(result, p.node)
+		}
+	}
+
+	sort.Ints(result)
+
+	return result
+}
+
+// ring returns the sorted points of all nodes. The ring of the last nodes is cached.
+func (b *BoundedLoad) ring(nodes []Node) []point {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if equal(b.nodes, nodes) {
+		return b.points
+	}
+
+	ring := make([]point, 0, len(nodes)*b.virtualNodes)
+
+	for i := range nodes {
+		for v := 0; v < b.virtualNodes*nodes[i].weight(); v++ {
+			ring = append(ring, point{
+				hash: crc64.Checksum([]byte(nodes[i].Name+"#"+strconv.Itoa(v)), ecmaTable),
+				node: i,
+			})
+		}
+	}
+
+	sort.Slice(ring, func(i, j int) bool {
+		return ring[i].hash < ring[j].hash
+	})
+
+	b.nodes = append([]Node{}, nodes...)
+	b.points = ring
+
+	return ring
+}
+
+func equal(a, b []Node) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// limit limits the number of replicas n to [0, servers].
+func limit(n, servers int) int {
+	if n > servers {
+		return servers
+	}
+
+	if n < 0 {
+		return 0
+	}
+
+	return n
+}
+
+// score returns the weighted rendezvous score -weight/ln(u) of a node for key, where u is the
+// uniformly distributed hash of key and node in (0, 1).
+func score(key string, node Node) float64 {
+	h := mix(crc64.Checksum([]byte(key), ecmaTable) ^ crc64.Checksum([]byte(node.Name), ecmaTable))
+	u := (float64(h>>11) + 0.5) / (1 << 53)
+
+	return -float64(node.weight()) / math.Log(u)
+}
+
+// mix is the finalizer of murmur3. It spreads the bits of similar keys.
+func mix(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb53a85ec1a87
+	h ^= h >> 33
+
+	return h
+}
@@ -0,0 +1,263 @@
+package hash
+
+import (
+	"fmt"
+	"hash/crc64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	simulationKeys    = 10000
+	simulationServers = 10
+)
+
+// assignment maps keys to server names.
+type assignment map[string][]string
+
+// simulation distributes keys on servers like the registry does: keys are (re)assigned one
+// after the other and the load of a server is the number of other keys assigned to it.
+type simulation struct {
+	d        Distributor
+	keys     []string
+	replicas int
+}
+
+func newSimulation(d Distributor, replicas int) simulation {
+	keys := make([]string, simulationKeys)
+
+	for i := range keys {
+		keys[i] = fmt.Sprintf("http://host%d.example.com:%d/metrics", i/10, 9100+i%10)
+	}
+
+	return simulation{
+		d:        d,
+		keys:     keys,
+		replicas: replicas,
+	}
+}
+
+// run reassigns all keys on servers starting with assignment prev.
+func (s simulation) run(servers []Node, prev assignment) assignment {
+	loads := map[string]int{}
+	current := assignment{}
+
+	for k, names := range prev {
+		current[k] = names
+
+		for _, n := range names {
+			loads[n]++
+		}
+	}
+
+	for _, k := range s.keys {
+		own := map[string]bool{}
+
+		for _, n := range current[k] {
+			own[n] = true
+		}
+
+		load := func(i int) int {
+			if own[servers[i].Name] {
+				return loads[servers[i].Name] - 1
+			}
+
+			return loads[servers[i].Name]
+		}
+
+		names := []string{}
+
+		for _, i := range s.d.Distribute(k, servers, s.replicas, load) {
+			names = append(names, servers[i].Name)
+		}
+
+		for _, n := range current[k] {
+			loads[n]--
+		}
+
+		for _, n := range names {
+			loads[n]++
+		}
+
+		current[k] = names
+	}
+
+	return current
+}
+
+// balance returns the maximum ratio of the load of a server to its weighted share of the
+// total load.
+func balance(a assignment, servers []Node) float64 {
+	loads := map[string]int{}
+	total, weights := 0, 0
+
+	for _, names := range a {
+		for _, n := range names {
+			loads[n]++
+			total++
+		}
+	}
+
+	for _, n := range servers {
+		weights += n.weight()
+	}
+
+	max := 0.0
+
+	for _, n := range servers {
+		share := float64(total) * float64(n.weight()) / float64(weights)
+
+		if r := float64(loads[n.Name]) / share; r > max {
+			max = r
+		}
+	}
+
+	return max
+}
+
+// movement returns the fraction of assignments that changed between a and b.
+func movement(a, b assignment, replicas int) float64 {
+	moved := 0
+
+	for k, names := range a {
+		old := map[string]bool{}
+
+		for _, n := range names {
+			old[n] = true
+		}
+
+		for _, n := range b[k] {
+			if !old[n] {
+				moved++
+			}
+		}
+	}
+
+	return float64(moved) / float64(len(a)*replicas)
+}
+
+func servers(n int) []Node {
+	s := make([]string, n)
+
+	for i := range s {
+		s[i] = fmt.Sprintf("prometheus%02d", i)
+	}
+
+	return Nodes(s...)
+}
+
+func TestDistributors(t *testing.T) {
+	tt := []struct {
+		strategy string
+		replicas int
+		// maximum ratio of max load to average load
+		maxBalance float64
+		// maximum fraction of moved assignments when a server is added or the first server is
+		// removed, the optimum is 1/11 and 1/10
+		maxAddMovement    float64
+		maxRemoveMovement float64
+	}{
+		// jump hash only handles servers removed at the end, all other keys move
+		{JumpDistribution, 1, 1.15, 0.15, 1},
+		{JumpDistribution, 2, 1.15, 0.2, 1},
+		{RendezvousDistribution, 1, 1.15, 0.15, 0.15},
+		{RendezvousDistribution, 2, 1.15, 0.15, 0.15},
+		{BoundedLoadDistribution, 1, DefaultLoadFactor + 0.01, 0.2, 0.2},
+		{BoundedLoadDistribution, 2, DefaultLoadFactor + 0.01, 0.2, 0.2},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(fmt.Sprintf("%s replicas=%d", tc.strategy, tc.replicas), func(t *testing.T) {
+			d, err := NewDistributor(tc.strategy)
+			require.NoError(t, err)
+
+			sim := newSimulation(d, tc.replicas)
+			all := servers(simulationServers + 1)
+			initial := sim.run(all[:simulationServers], nil)
+
+			for _, names := range initial {
+				require.Len(t, names, tc.replicas)
+
+				if tc.replicas > 1 {
+					require.NotEqual(t, names[0], names[1], "replicas are on distinct servers")
+				}
+			}
+
+			b := balance(initial, all[:simulationServers])
+			assert.LessOrEqual(t, b, tc.maxBalance, "balance")
+
+			added := sim.run(all, initial)
+			addMovement := movement(initial, added, tc.replicas)
+			assert.LessOrEqual(t, addMovement, tc.maxAddMovement, "movement on add")
+
+			removed := sim.run(all[1:simulationServers], initial)
+			removeMovement := movement(initial, removed, tc.replicas)
+			assert.LessOrEqual(t, removeMovement, tc.maxRemoveMovement, "movement on remove")
+
+			t.Logf("balance=%.3f add-movement=%.3f remove-movement=%.3f", b, addMovement, removeMovement)
+		})
+	}
+}
+
+func TestDistribute(t *testing.T) {
+	s := servers(3)
+
+	for _, strategy := range []string{JumpDistribution, RendezvousDistribution, BoundedLoadDistribution} {
+		d, err := NewDistributor(strategy)
+		require.NoError(t, err)
+
+		assert.Equal(t, []int{0, 1, 2}, d.Distribute("key", s, 5, nil), strategy)
+		assert.Empty(t, d.Distribute("key", s, 0, nil), strategy)
+		assert.Empty(t, d.Distribute("key", nil, 1, nil), strategy)
+		assert.Equal(t, d.Distribute("key", s, 2, nil), d.Distribute("key", s, 2, nil), strategy)
+	}
+
+	t.Run("jump is compatible with hash string", func(t *testing.T) {
+		h := New(crc64.New(crc64.MakeTable(0xC96C5795D7870F42)))
+		i := h.HashString("localhost", len(s))
+		assert.Equal(t, []int{i}, h.Distribute("localhost", s, 1, nil))
+	})
+
+	t.Run("weighted", func(t *testing.T) {
+		nodes := []Node{{Name: "large", Weight: 4}, {Name: "medium", Weight: 2}, {Name: "small"}}
+
+		for _, strategy := range []string{JumpDistribution, RendezvousDistribution, BoundedLoadDistribution} {
+			d, err := NewDistributor(strategy)
+			require.NoError(t, err)
+
+			for _, replicas := range []int{1, 2} {
+				a := newSimulation(d, replicas).run(nodes, nil)
+				b := balance(a, nodes)
+
+				t.Logf("%s replicas=%d balance=%.3f", strategy, replicas, b)
+
+				if replicas == 1 {
+					// with 2 replicas, the large node gets at most one replica of every
+					// key and the smaller nodes get the rest
+					assert.LessOrEqual(t, b, 1.25, strategy)
+				}
+			}
+		}
+	})
+
+	t.Run("bounded load skips full servers", func(t *testing.T) {
+		d := NewBoundedLoad(DefaultLoadFactor, DefaultVirtualNodes)
+		first := d.Distribute("key", s, 1, nil)[0]
+
+		got := d.Distribute("key", s, 1, func(i int) int {
+			if i == first {
+				return 100
+			}
+
+			return 0
+		})
+		assert.NotEqual(t, []int{first}, got)
+	})
+
+	_, err := NewDistributor("round-robin")
+	assert.Error(t, err)
+}
@@ -5,10 +5,12 @@ package hash
 import (
 	"hash"
 	"io"
+	"sync"
 )
 
 // Jump is a consistent hasher.
 type Jump struct {
+	mu     sync.Mutex
 	hasher hash.Hash64
 }
 
@@ -41,6 +43,9 @@ func (j *Jump) Hash(key uint64, buckets int) int {
 // HashString takes string as key instead of an int. It uses the configured
 // hash.Hash64 hasher to create an integer from the string key.
 func (j *Jump) HashString(key string, buckets int) int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
 	j.hasher.Reset()
 
 	_, err := io.WriteString(j.hasher, key)
@@ -0,0 +1,204 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/postfinance/discovery"
+)
+
+// CordonServer cordons a server. A cordoned server keeps its services but gets no new services.
+func (r *Registry) CordonServer(name string) (*discovery.Server, error) {
+	return r.setServerState(name, discovery.Cordoned, 0)
+}
+
+// DrainServer drains a server. A draining server gets no new services and rate services
+// per drain interval are moved to other servers.
+func (r *Registry) DrainServer(name string, rate int) (*discovery.Server, error) {
+	if rate <= 0 {
+		return nil, fmt.Errorf("drain rate has to be positive : %w", ErrValidation)
+	}
+
+	return r.setServerState(name, discovery.Draining, rate)
+}
+
+// UncordonServer makes a cordoned or draining server active again and redistributes
+// all services.
+func (r *Registry) UncordonServer(name string) (*discovery.Server, error) {
+	s, err := r.serverRepo.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.State == discovery.Active {
+		return s, nil
+	}
+
+	if _, err := r.setServerState(name, discovery.Joining, 0); err != nil {
+		return nil, err
+	}
+
+	if _, err := r.rebalance(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to rebalance services: %w", err)
+	}
+
+	return r.setServerState(name, discovery.Active, 0)
+}
+
+// StartDrainer moves the services of draining servers to other servers every interval.
+// Only one discovery server drains at a time.
+func (r *Registry) StartDrainer(ctx context.Context, interval time.Duration) {
+	r.log.Infow("starting drainer", "interval", interval)
+
+	ticker := time.NewTicker(interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.log.Info("stopping drainer")
+
+			return
+		case <-ticker.C:
+			if err := r.drainIfLeader(2 * interval); err != nil {
+				r.log.Errorw("failed to drain servers", "err", err)
+			}
+		}
+	}
+}
+
+// drainIfLeader drains the servers, if the drainer lock is held by this discovery server.
+// The lock is not released, so that the drainer keeps running on the same discovery server
+// as long as it renews the lock within ttl.
+func (r *Registry) drainIfLeader(ttl time.Duration) error {
+	ok, err := r.lockRepo.Acquire(drainerLock, ttl)
+	if err != nil {
+		return fmt.Errorf("failed to acquire lock %s: %w", drainerLock, err)
+	}
+
+	if !ok {
+		r.log.Debug("drainer runs on another discovery server")
+		return nil
+	}
+
+	return r.drain()
+}
+
+// drain moves up to DrainRate services of every draining server to other servers. Nothing
+// is moved while services are rebalanced.
+func (r *Registry) drain() error {
+	servers, err := r.serverRepo.List("")
+	if err != nil {
+		return err
+	}
+
+	r.rebalanceMu.Lock()
+	defer r.rebalanceMu.Unlock()
+
+	ok, err := r.lockRepo.Acquire(rebalanceLock, lockTTL)
+	if err != nil {
+		return fmt.Errorf("failed to acquire lock %s: %w", rebalanceLock, err)
+	}
+
+	if !ok {
+		r.log.Debug("services are rebalanced by another discovery server")
+		return nil
+	}
+
+	defer r.unlock(rebalanceLock)
+
+	r.serverMetrics.undrainable.Reset()
+
+	for _, d := range servers {
+		if d.State != discovery.Draining {
+			continue
+		}
+
+		services := r.serviceCache.listByServer(d.Name, "")
+		if len(services) == 0 {
+			r.log.Debugw("server is drained", "server", d.Name)
+			continue
+		}
+
+		// services that cannot be moved are skipped, so that they do not block the others
+		moved, undrainable := 0, 0
+
+		for _, s := range services {
+			if moved == d.DrainRate {
+				break
+			}
+
+			if err := r.move(s, d.Name); err != nil {
+				r.log.Warnw("failed to move service", append(s.KeyVals(), "server", d.Name, "err", err)...)
+				undrainable++
+
+				continue
+			}
+
+			moved++
+		}
+
+		r.serverMetrics.undrainable.WithLabelValues(d.Name).Set(float64(undrainable))
+
+		r.log.Infow("draining server", "server", d.Name, "moved", moved, "undrainable", undrainable, "remaining", r.serviceCache.load(d.Name, ""))
+	}
+
+	return nil
+}
+
+// move distributes service s on the servers matching its selector without server.
+func (r *Registry) move(s discovery.Service, server string) error {
+	candidates, err := r.candidates(s.Selector)
+	if err != nil {
+		return err
+	}
+
+	candidates = candidates.Filter(func(c discovery.Server) bool {
+		return c.Name != server
+	})
+
+	k := cacheKey(s.Namespace, s.ID)
+
+	servers, err := r.distribute(s, candidates, func(server string) int {
+		return r.serviceCache.load(server, k)
+	})
+	if err != nil {
+		return err
+	}
+
+	prev := s
+	s.Servers = servers.Names()
+
+	saved, ok, err := r.serviceRepo.SaveBatch(discovery.Services{s}, discovery.Services{prev})
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return errConflict
+	}
+
+	r.serviceCache.put(saved[0])
+
+	return nil
+}
+
+// setServerState sets the state and the drain rate of a registered server. The state of a
+// leaving server cannot be changed.
+func (r *Registry) setServerState(name string, state discovery.ServerState, drainRate int) (*discovery.Server, error) {
+	s, err := r.serverRepo.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.State == discovery.Leaving {
+		return nil, fmt.Errorf("server %s is leaving : %w", name, ErrValidation)
+	}
+
+	s.State = state
+	s.DrainRate = drainRate
+
+	r.log.Infow("set server state", s.KeyVals()...)
+
+	return r.serverRepo.Save(*s)
+}
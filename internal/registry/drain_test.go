@@ -0,0 +1,244 @@
+package registry
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/postfinance/discovery"
+	"github.com/postfinance/discovery/internal/repo"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestDrain(t *testing.T) {
+	c := newBackend(t)
+
+	r, err := New(c, prometheus.NewRegistry(), zap.NewNop().Sugar(), 1)
+	require.NoError(t, err)
+
+	for _, name := range []string{"server1", "server2", "server3"} {
+		_, err := r.RegisterServer(name, discovery.Labels{"name": name})
+		require.NoError(t, err)
+	}
+
+	_, err = r.RegisterNamespace(discovery.Namespace{Name: "default"})
+	require.NoError(t, err)
+
+	register := func(from, to int) {
+		for i := from; i < to; i++ {
+			_, err := r.RegisterService(*discovery.MustNewService("test", fmt.Sprintf("http://host%d.example.com", i)))
+			require.NoError(t, err)
+		}
+	}
+
+	register(0, 30)
+
+	// services that can only be registered on server1
+	for i := 0; i < 3; i++ {
+		svc := discovery.MustNewService("pinned", fmt.Sprintf("http://pinned%d.example.com", i))
+		svc.Selector = "name=server1"
+
+		_, err := r.RegisterService(*svc)
+		require.NoError(t, err)
+	}
+
+	load := func(server string) int {
+		l, err := r.ListServiceByServer(server, "")
+		require.NoError(t, err)
+
+		return len(l)
+	}
+
+	initial := load("server1")
+	require.NotZero(t, initial)
+
+	t.Run("cordon", func(t *testing.T) {
+		s, err := r.CordonServer("server1")
+		require.NoError(t, err)
+		assert.Equal(t, discovery.Cordoned, s.State)
+
+		register(30, 60)
+		register(0, 30)
+		assert.Equal(t, initial, load("server1"), "no new services")
+
+		plan, err := r.PlanRebalance()
+		require.NoError(t, err)
+		assert.Empty(t, plan.Moves, "services are kept")
+
+		s, err = r.RegisterServer("server1", discovery.Labels{"name": "server1"})
+		require.NoError(t, err)
+		assert.Equal(t, discovery.Cordoned, s.State, "registration keeps the state")
+		assert.Equal(t, initial, load("server1"))
+	})
+
+	t.Run("server joins", func(t *testing.T) {
+		targets := func(server string) []string {
+			l, err := r.ListServiceByServer(server, "")
+			require.NoError(t, err)
+
+			ids := []string{}
+			for _, s := range l {
+				ids = append(ids, s.ID)
+			}
+
+			return ids
+		}
+
+		before := targets("server1")
+
+		_, err := r.RegisterServer("joined", nil)
+		require.NoError(t, err)
+		assert.NotEmpty(t, targets("joined"), "services are moved to the new server")
+		assert.ElementsMatch(t, before, targets("server1"), "targets of the cordoned server are kept")
+
+		require.NoError(t, r.UnRegisterServer("joined"))
+		assert.ElementsMatch(t, before, targets("server1"))
+	})
+
+	t.Run("drain", func(t *testing.T) {
+		_, err := r.DrainServer("server1", 0)
+		assert.True(t, IsValidationError(err))
+
+		s, err := r.DrainServer("server1", 5)
+		require.NoError(t, err)
+		assert.Equal(t, discovery.Draining, s.State)
+		assert.Equal(t, 5, s.DrainRate)
+
+		other := repo.NewLock(c, "other")
+
+		for _, lock := range []string{drainerLock, rebalanceLock} {
+			ok, err := other.Acquire(lock, time.Minute)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			require.NoError(t, r.drainIfLeader(time.Minute))
+			assert.Equal(t, initial, load("server1"), "locked by another server")
+
+			require.NoError(t, other.Release(lock))
+		}
+
+		require.NoError(t, r.drainIfLeader(time.Minute))
+		assert.Equal(t, initial-5, load("server1"), "rate services are moved")
+
+		for i := 0; i < initial/5+1; i++ {
+			require.NoError(t, r.drain())
+		}
+
+		assert.Equal(t, 3, load("server1"), "only undrainable services are left")
+		assert.Equal(t, 3.0, testutil.ToFloat64(r.serverMetrics.undrainable.WithLabelValues("server1")))
+
+		l, err := r.ListService("", "")
+		require.NoError(t, err)
+		assert.Len(t, l, 63)
+
+		for _, s := range l {
+			assert.Len(t, s.Servers, 1)
+		}
+	})
+
+	t.Run("uncordon", func(t *testing.T) {
+		s, err := r.UncordonServer("server1")
+		require.NoError(t, err)
+		assert.Equal(t, discovery.Active, s.State)
+		assert.Zero(t, s.DrainRate)
+		assert.NotZero(t, load("server1"), "services are redistributed")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := r.CordonServer("server4")
+		assert.ErrorIs(t, err, repo.ErrNotFound)
+
+		s := discovery.NewServer("server4", nil)
+		s.State = discovery.Leaving
+
+		_, err = r.serverRepo.Save(*s)
+		require.NoError(t, err)
+
+		_, err = r.CordonServer("server4")
+		assert.True(t, IsValidationError(err))
+	})
+}
+
+func TestDrainConcurrent(t *testing.T) {
+	c := newBackend(t)
+
+	r1, err := New(c, prometheus.NewRegistry(), zap.NewNop().Sugar(), 1)
+	require.NoError(t, err)
+
+	r2, err := New(c, prometheus.NewRegistry(), zap.NewNop().Sugar(), 1)
+	require.NoError(t, err)
+
+	for _, name := range []string{"server1", "server2", "server3"} {
+		_, err := r1.RegisterServer(name, nil)
+		require.NoError(t, err)
+	}
+
+	_, err = r1.RegisterNamespace(discovery.Namespace{Name: "default"})
+	require.NoError(t, err)
+
+	t.Run("locks", func(t *testing.T) {
+		for _, lock := range []string{drainerLock, rebalanceLock} {
+			var (
+				wg   sync.WaitGroup
+				held int32
+			)
+
+			for _, r := range []*Registry{r1, r2, r1, r2} {
+				r := r
+
+				wg.Add(1)
+
+				go func() {
+					defer wg.Done()
+
+					ok, err := r.lockRepo.Acquire(lock, time.Minute)
+					assert.NoError(t, err)
+
+					if ok {
+						atomic.AddInt32(&held, 1)
+					}
+				}()
+			}
+
+			wg.Wait()
+
+			ok1, err := r1.lockRepo.Acquire(lock, time.Minute)
+			require.NoError(t, err)
+
+			ok2, err := r2.lockRepo.Acquire(lock, time.Minute)
+			require.NoError(t, err)
+
+			assert.NotEqual(t, ok1, ok2, "lock %s is held by one registry", lock)
+			assert.NotZero(t, held)
+
+			r1.unlock(lock)
+			r2.unlock(lock)
+		}
+	})
+
+	t.Run("move", func(t *testing.T) {
+		svc, err := r1.RegisterService(*discovery.MustNewService("test", "http://host.example.com"))
+		require.NoError(t, err)
+		require.Len(t, svc.Servers, 1)
+
+		stale := *svc
+
+		require.NoError(t, r1.move(stale, stale.Servers[0]))
+
+		moved, err := r1.serviceRepo.Get(svc.ID, svc.Namespace)
+		require.NoError(t, err)
+		require.NotEqual(t, stale.Servers, moved.Servers)
+
+		assert.ErrorIs(t, r2.move(stale, moved.Servers[0]), errConflict, "service was moved by r1")
+
+		current, err := r1.serviceRepo.Get(svc.ID, svc.Namespace)
+		require.NoError(t, err)
+		assert.Equal(t, moved.Servers, current.Servers)
+	})
+}
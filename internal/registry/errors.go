@@ -5,9 +5,11 @@ import "errors"
 // Common errors
 var (
 	ErrNoServersFound    = errors.New("no servers found")
+	ErrServersFull       = errors.New("all servers reached their maximum number of targets")
 	ErrNamespaceNotFound = errors.New("namespace not found")
 	ErrValidation        = errors.New("validation error")
 	ErrContainsServices  = errors.New("server has registered services")
+	ErrLocked            = errors.New("locked by another discovery server")
 )
 
 // IsServersNotFound returns true on service registration when
@@ -16,13 +18,25 @@ func IsServersNotFound(err error) bool {
 	return err == ErrNoServersFound
 }
 
+// IsServersFull returns true on service registration when all
+// suitable servers reached their maximum number of targets.
+func IsServersFull(err error) bool {
+	return err == ErrServersFull
+}
+
 // IsNamespaceNotFound returns true on service registration when
 // the specified namespace does not exist.
 func IsNamespaceNotFound(err error) bool {
-	return err == ErrNoServersFound
+	return err == ErrNamespaceNotFound
 }
 
 // IsValidationError returns true if a validation error occurred.
 func IsValidationError(err error) bool {
 	return errors.Is(err, ErrValidation)
 }
+
+// IsLocked returns true if a cluster-wide lock is held by another
+// discovery server.
+func IsLocked(err error) bool {
+	return errors.Is(err, ErrLocked)
+}
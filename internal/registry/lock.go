@@ -0,0 +1,60 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// rebalanceLock is the cluster-wide lock held while services are redistributed.
+	rebalanceLock = "rebalance"
+	// drainerLock is held by the discovery server that drains servers.
+	drainerLock = "drainer"
+	// lockTTL is the duration after which the lock of a crashed discovery server expires.
+	lockTTL = time.Minute
+	// lockRetryInterval is the interval between two attempts to acquire a lock.
+	lockRetryInterval = time.Second
+)
+
+// lock acquires the cluster-wide lock name. If the lock is held by another discovery
+// server, it retries until the lock is released, wait elapsed or ctx is done.
+func (r *Registry) lock(ctx context.Context, name string, wait time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	ticker := time.NewTicker(lockRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		ok, err := r.lockRepo.Acquire(name, lockTTL)
+		if err != nil {
+			return fmt.Errorf("failed to acquire lock %s: %w", name, err)
+		}
+
+		if ok {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("lock %s : %w", name, ErrLocked)
+		case <-ticker.C:
+		}
+	}
+}
+
+// unlock releases the cluster-wide lock name.
+func (r *Registry) unlock(name string) {
+	if err := r.lockRepo.Release(name); err != nil {
+		r.log.Warnw("failed to release lock", "lock", name, "err", err)
+	}
+}
+
+// lockOwner returns an identifier that is unique for every registry.
+func lockOwner() string {
+	host, _ := os.Hostname()
+
+	return fmt.Sprintf("%s/%d/%d", host, os.Getpid(), time.Now().UnixNano())
+}
@@ -0,0 +1,359 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/postfinance/discovery"
+	"github.com/postfinance/discovery/internal/repo"
+)
+
+const (
+	// rebalanceBatchSize is the number of services written in one transaction.
+	rebalanceBatchSize = 100
+	// maxRebalanceAttempts is the number of times a rebalance is planned again, if services
+	// are changed concurrently.
+	maxRebalanceAttempts = 5
+)
+
+// errConflict is returned, if a service was changed after the plan was computed.
+var errConflict = errors.New("services changed during rebalance")
+
+// Move is a service whose servers change.
+type Move struct {
+	// Service is the service with its new servers.
+	Service discovery.Service
+	// From are the current servers of the service.
+	From []string
+	// prev is the stored service the move was planned from.
+	prev discovery.Service
+}
+
+// Failure is a service that cannot be distributed. It keeps its current servers.
+type Failure struct {
+	Service discovery.Service
+	Err     error
+}
+
+// ServerMoves are the changes of a server.
+type ServerMoves struct {
+	Name    string
+	Added   int
+	Removed int
+	// Targets is the number of services of the server after the redistribution.
+	Targets int
+}
+
+// Plan is the redistribution of all services on the enabled servers.
+type Plan struct {
+	Moves    []Move
+	Failures []Failure
+	targets  map[string]int
+}
+
+// Servers returns the changes per server sorted by name.
+func (p Plan) Servers() []ServerMoves {
+	moves := map[string]*ServerMoves{}
+
+	get := func(name string) *ServerMoves {
+		if _, ok := moves[name]; !ok {
+			moves[name] = &ServerMoves{Name: name, Targets: p.targets[name]}
+		}
+
+		return moves[name]
+	}
+
+	for name := range p.targets {
+		get(name)
+	}
+
+	for _, m := range p.Moves {
+		for _, name := range diff(m.Service.Servers, m.From) {
+			get(name).Added++
+		}
+
+		for _, name := range diff(m.From, m.Service.Servers) {
+			get(name).Removed++
+		}
+	}
+
+	result := make([]ServerMoves, 0, len(moves))
+
+	for _, m := range moves {
+		result = append(result, *m)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// PlanRebalance computes the distribution of all services on the enabled servers in memory.
+// The plan contains only the services whose servers change.
+func (r *Registry) PlanRebalance() (*Plan, error) {
+	services, err := r.serviceRepo.List("", "")
+	if err != nil {
+		return nil, err
+	}
+
+	// the load of a server is the number of services currently assigned to it and
+	// changes with every distributed service
+	loads := map[string]int{}
+
+	for _, s := range services {
+		for _, name := range s.Servers {
+			loads[name]++
+		}
+	}
+
+	plan := &Plan{}
+	candidates := map[string]discovery.Servers{}
+
+	for i := range services {
+		s := services[i]
+
+		c, ok := candidates[s.Selector]
+		if !ok {
+			c, err = r.candidates(s.Selector)
+			if err != nil {
+				return nil, err
+			}
+
+			candidates[s.Selector] = c
+		}
+
+		load := func(server string) int {
+			if s.HasServer(server) {
+				return loads[server] - 1
+			}
+
+			return loads[server]
+		}
+
+		servers, err := r.distribute(s, c, load)
+		if err != nil {
+			plan.Failures = append(plan.Failures, Failure{Service: s, Err: err})
+			continue
+		}
+
+		names := servers.Names()
+
+		if reflect.DeepEqual(names, s.Servers) {
+			continue
+		}
+
+		for _, name := range s.Servers {
+			loads[name]--
+		}
+
+		for _, name := range names {
+			loads[name]++
+		}
+
+		prev := s
+		s.Servers = names
+
+		plan.Moves = append(plan.Moves, Move{Service: s, From: prev.Servers, prev: prev})
+	}
+
+	plan.targets = map[string]int{}
+
+	for _, c := range candidates {
+		for _, server := range c {
+			plan.targets[server.Name] = loads[server.Name]
+		}
+	}
+
+	return plan, nil
+}
+
+// Rebalance redistributes all services on the enabled servers. Only the services whose servers
+// change are written. Services that cannot be distributed keep their servers.
+//
+// The state of the redistribution is saved, so that it is resumed by ResumeRebalance
+// if it is interrupted. If ctx is canceled, it stops after the current batch. If another
+// discovery server is rebalancing, it waits until the other rebalance is finished and
+// returns ErrLocked, if it does not finish within one minute.
+func (r *Registry) Rebalance(ctx context.Context) (*Plan, error) {
+	return r.rebalance(ctx)
+}
+
+// ResumeRebalance finishes an interrupted redistribution. It returns nil, if no
+// redistribution was interrupted.
+func (r *Registry) ResumeRebalance(ctx context.Context) (*Plan, error) {
+	state, err := r.rebalanceRepo.Get()
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	// the rebalance is resumed by the discovery server holding the lock
+	ok, err := r.lockRepo.Acquire(rebalanceLock, lockTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		r.log.Infow("interrupted rebalance is resumed by another discovery server", "started", state.Started)
+		return nil, nil
+	}
+
+	r.log.Infow("resuming interrupted rebalance", "started", state.Started, "leaving", state.Leaving)
+
+	return r.rebalance(ctx, state.Leaving...)
+}
+
+// rebalance redistributes all services and deletes the leaving servers afterwards. If
+// there are leaving servers, nothing is written if a service cannot be distributed.
+//
+// The moves are written in batches. If a service of a batch was changed since the plan
+// was computed, the batch is not written and the rebalance is planned again. Only one
+// discovery server rebalances at a time.
+func (r *Registry) rebalance(ctx context.Context, leaving ...string) (*Plan, error) {
+	r.rebalanceMu.Lock()
+	defer r.rebalanceMu.Unlock()
+
+	if err := r.lock(ctx, rebalanceLock, r.lockWait); err != nil {
+		return nil, err
+	}
+
+	defer r.unlock(rebalanceLock)
+
+	result := &Plan{}
+
+	for attempt := 1; ; attempt++ {
+		plan, err := r.PlanRebalance()
+		if err != nil {
+			return nil, err
+		}
+
+		result.Failures = plan.Failures
+		result.targets = plan.targets
+
+		if len(leaving) > 0 && len(plan.Failures) > 0 {
+			f := plan.Failures[0]
+			return result, fmt.Errorf("failed to distribute service %s/%s: %w", f.Service.Namespace, f.Service.ID, f.Err)
+		}
+
+		if attempt == 1 {
+			for _, f := range plan.Failures {
+				r.log.Warnw("failed to distribute service", append(f.Service.KeyVals(), "err", f.Err)...)
+			}
+
+			if err := r.rebalanceRepo.Save(discovery.Rebalance{
+				Started: time.Now(),
+				Leaving: leaving,
+			}); err != nil {
+				return nil, fmt.Errorf("failed to save rebalance state: %w", err)
+			}
+		}
+
+		err = r.apply(ctx, plan, result)
+		if err == nil {
+			break
+		}
+
+		if !errors.Is(err, errConflict) || attempt == maxRebalanceAttempts {
+			return result, err
+		}
+
+		r.log.Infow("services changed during rebalance, planning again", "attempt", attempt)
+	}
+
+	for _, name := range leaving {
+		if err := r.serverRepo.Delete(name); err != nil && !errors.Is(err, repo.ErrNotFound) {
+			return result, fmt.Errorf("failed to delete server %s: %w", name, err)
+		}
+	}
+
+	if err := r.rebalanceRepo.Delete(); err != nil {
+		return result, fmt.Errorf("failed to delete rebalance state: %w", err)
+	}
+
+	r.log.Infow("rebalanced services", "moved", len(result.Moves), "failed", len(result.Failures))
+
+	return result, nil
+}
+
+// apply writes the moves of plan in batches and appends the written moves to result. It
+// returns errConflict, if a service of a batch was changed since the plan was computed.
+func (r *Registry) apply(ctx context.Context, plan, result *Plan) error {
+	for start := 0; start < len(plan.Moves); start += rebalanceBatchSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		ok, err := r.lockRepo.Acquire(rebalanceLock, lockTTL)
+		if err != nil {
+			return fmt.Errorf("failed to renew lock: %w", err)
+		}
+
+		if !ok {
+			return fmt.Errorf("lock %s expired : %w", rebalanceLock, ErrLocked)
+		}
+
+		end := start + rebalanceBatchSize
+		if end > len(plan.Moves) {
+			end = len(plan.Moves)
+		}
+
+		batch := plan.Moves[start:end]
+		services := make(discovery.Services, 0, len(batch))
+		prev := make(discovery.Services, 0, len(batch))
+
+		for _, m := range batch {
+			services = append(services, m.Service)
+			prev = append(prev, m.prev)
+		}
+
+		saved, ok, err := r.serviceRepo.SaveBatch(services, prev)
+		if err != nil {
+			return fmt.Errorf("failed to save services: %w", err)
+		}
+
+		if !ok {
+			return errConflict
+		}
+
+		for i := range saved {
+			r.serviceCache.put(saved[i])
+		}
+
+		result.Moves = append(result.Moves, batch...)
+
+		r.log.Infow("rebalancing services", "moved", end, "total", len(plan.Moves))
+	}
+
+	return nil
+}
+
+// diff returns the elements of a that are not in b.
+func diff(a, b []string) []string {
+	result := []string{}
+
+	for _, x := range a {
+		found := false
+
+		for _, y := range b {
+			if x == y {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			result = append(result, x)
+		}
+	}
+
+	return result
+}
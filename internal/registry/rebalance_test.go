@@ -0,0 +1,170 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/postfinance/discovery"
+	"github.com/postfinance/discovery/internal/repo"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRebalance(t *testing.T) {
+	c := newBackend(t)
+
+	r, err := New(c, prometheus.NewRegistry(), zap.NewNop().Sugar(), 1)
+	require.NoError(t, err)
+
+	for _, name := range []string{"server1", "server2"} {
+		_, err := r.RegisterServer(name, nil)
+		require.NoError(t, err)
+	}
+
+	_, err = r.RegisterNamespace(discovery.Namespace{Name: "default"})
+	require.NoError(t, err)
+
+	for i := 0; i < 50; i++ {
+		_, err := r.RegisterService(*discovery.MustNewService("test", fmt.Sprintf("http://host%d.example.com", i)))
+		require.NoError(t, err)
+	}
+
+	servers := func() map[string][]string {
+		l, err := r.ListService("", "")
+		require.NoError(t, err)
+
+		m := map[string][]string{}
+		for _, s := range l {
+			m[s.ID] = s.Servers
+		}
+
+		return m
+	}
+
+	// the server is saved without rebalancing
+	_, err = r.serverRepo.Save(*discovery.NewServer("server3", nil))
+	require.NoError(t, err)
+
+	before := servers()
+
+	t.Run("dry run", func(t *testing.T) {
+		plan, err := r.PlanRebalance()
+		require.NoError(t, err)
+		require.NotEmpty(t, plan.Moves)
+		assert.Empty(t, plan.Failures)
+
+		for _, m := range plan.Moves {
+			assert.NotEqual(t, m.From, m.Service.Servers)
+			assert.Equal(t, before[m.Service.ID], m.From)
+		}
+
+		moves := plan.Servers()
+		require.Len(t, moves, 3)
+		assert.Equal(t, "server3", moves[2].Name)
+		assert.Equal(t, len(plan.Moves), moves[2].Added)
+		assert.Equal(t, moves[2].Added, moves[2].Targets)
+		assert.Equal(t, moves[2].Added, moves[0].Removed+moves[1].Removed)
+
+		assert.Equal(t, before, servers(), "nothing is written")
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		plan, err := r.PlanRebalance()
+		require.NoError(t, err)
+		require.NotEmpty(t, plan.Moves)
+
+		// the service is changed after the plan was computed
+		_, err = r.serviceRepo.Save(plan.Moves[0].prev)
+		require.NoError(t, err)
+
+		err = r.apply(context.Background(), plan, &Plan{})
+		assert.ErrorIs(t, err, errConflict)
+		assert.Equal(t, before, servers(), "nothing is written")
+	})
+
+	t.Run("rebalance", func(t *testing.T) {
+		plan, err := r.Rebalance(context.Background())
+		require.NoError(t, err)
+
+		after := servers()
+		moved := 0
+
+		for id := range before {
+			if !assert.ObjectsAreEqual(before[id], after[id]) {
+				moved++
+			}
+		}
+
+		assert.Equal(t, len(plan.Moves), moved)
+
+		plan, err = r.PlanRebalance()
+		require.NoError(t, err)
+		assert.Empty(t, plan.Moves)
+
+		_, err = r.rebalanceRepo.Get()
+		assert.ErrorIs(t, err, repo.ErrNotFound)
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		s := discovery.NewServer("server3", nil)
+		s.State = discovery.Leaving
+
+		_, err := r.serverRepo.Save(*s)
+		require.NoError(t, err)
+
+		// interrupted before the first service was moved
+		require.NoError(t, r.rebalanceRepo.Save(discovery.Rebalance{
+			Started: time.Now(),
+			Leaving: []string{"server3"},
+		}))
+
+		plan, err := r.ResumeRebalance(context.Background())
+		require.NoError(t, err)
+		require.NotNil(t, plan)
+		assert.NotEmpty(t, plan.Moves)
+
+		for _, s := range servers() {
+			assert.NotContains(t, s, "server3")
+		}
+
+		l, err := r.ListServer("")
+		require.NoError(t, err)
+		assert.Len(t, l, 2)
+
+		plan, err = r.ResumeRebalance(context.Background())
+		require.NoError(t, err)
+		assert.Nil(t, plan, "nothing to resume")
+	})
+
+	t.Run("locked", func(t *testing.T) {
+		other := repo.NewLock(c, "other")
+
+		ok, err := other.Acquire(rebalanceLock, time.Minute)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		r.lockWait = 10 * time.Millisecond
+
+		_, err = r.Rebalance(context.Background())
+		assert.True(t, IsLocked(err))
+
+		require.NoError(t, r.rebalanceRepo.Save(discovery.Rebalance{Started: time.Now()}))
+
+		plan, err := r.ResumeRebalance(context.Background())
+		require.NoError(t, err)
+		assert.Nil(t, plan, "resumed by the other server")
+
+		_, err = r.rebalanceRepo.Get()
+		assert.NoError(t, err)
+
+		require.NoError(t, other.Release(rebalanceLock))
+
+		plan, err = r.ResumeRebalance(context.Background())
+		require.NoError(t, err)
+		assert.NotNil(t, plan)
+	})
+}
@@ -7,7 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"hash/crc64"
-	"reflect"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
@@ -18,23 +18,37 @@ import (
 	"github.com/postfinance/store"
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
+// registerBatchSize is the number of services RegisterServices writes in one transaction.
+const registerBatchSize = 100
+
 // Registry registers server or service.
 type Registry struct {
 	log            *zap.SugaredLogger
 	serverRepo     *repo.Server
 	serviceRepo    *repo.Service
 	namespaceRepo  *repo.Namespace
-	jumpHasher     *hash.Jump
+	leaseRepo      *repo.Lease
+	trashRepo      *repo.Trash
+	rebalanceRepo  *repo.Rebalance
+	rebalanceMu    *sync.Mutex
+	lockRepo       *repo.Lock
+	lockWait       time.Duration
+	trashRetention time.Duration
+	distributor    hash.Distributor
 	idGenerator    func(string) string
 	numReplicas    int
 	servicesCount  *prometheus.GaugeVec
+	expiredCount   *prometheus.CounterVec
+	serverMetrics  serverMetrics
 	namespaceCache namespaceCache
+	serviceCache   *serviceCache
 }
 
 // New creates a new registry.
-func New(backend store.Backend, reg prometheus.Registerer, log *zap.SugaredLogger, numReplicas int) (*Registry, error) {
+func New(backend store.Backend, reg prometheus.Registerer, log *zap.SugaredLogger, numReplicas int, opts ...Option) (*Registry, error) {
 	if numReplicas < 1 {
 		return nil, errors.New("number of replicas has to be >= 1")
 	}
@@ -47,32 +61,77 @@ func New(backend store.Backend, reg prometheus.Registerer, log *zap.SugaredLogge
 		[]string{"server", "namespace"},
 	)
 
-	reg.MustRegister(servicesCount)
+	expiredCount := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "discovery_expired_services_total",
+			Help: "Number of services unregistered because their lease expired.",
+		},
+		[]string{"namespace"},
+	)
+
+	reg.MustRegister(servicesCount, expiredCount)
+
+	serverMetrics := newServerMetrics(reg)
 
 	registry := Registry{
-		log:           log,
-		jumpHasher:    hash.New(crc64.New(crc64.MakeTable(0xC96C5795D7870F42))),
-		idGenerator:   repo.IDGenerator(),
-		numReplicas:   numReplicas,
-		serverRepo:    repo.NewServer(backend),
-		serviceRepo:   repo.NewService(backend),
-		namespaceRepo: repo.NewNamespace(backend),
-		servicesCount: servicesCount,
+		log:            log,
+		distributor:    hash.New(crc64.New(crc64.MakeTable(0xC96C5795D7870F42))),
+		idGenerator:    repo.IDGenerator(),
+		numReplicas:    numReplicas,
+		serverRepo:     repo.NewServer(backend),
+		serviceRepo:    repo.NewService(backend),
+		namespaceRepo:  repo.NewNamespace(backend),
+		leaseRepo:      repo.NewLease(backend),
+		trashRepo:      repo.NewTrash(backend),
+		rebalanceRepo:  repo.NewRebalance(backend),
+		rebalanceMu:    &sync.Mutex{},
+		lockRepo:       repo.NewLock(backend, lockOwner()),
+		lockWait:       lockTTL,
+		trashRetention: DefaultTrashRetention,
+		servicesCount:  servicesCount,
+		expiredCount:   expiredCount,
+		serverMetrics:  serverMetrics,
 		namespaceCache: namespaceCache{
 			m:          &sync.Mutex{},
 			namespaces: map[string]discovery.Namespace{},
 		},
+		serviceCache: newServiceCache(reg),
+	}
+
+	for _, opt := range opts {
+		opt(&registry)
 	}
 
 	if err := registry.initNamespaceCache(); err != nil {
 		return nil, err
 	}
 
+	if err := registry.initServiceCache(); err != nil {
+		return nil, err
+	}
+
 	return &registry, nil
 }
 
+// WithServiceChangeHandler sets a function that is called with the namespace and the previous
+// and current servers of every changed or deleted service. It is called with the service
+// cache locked and must not block.
+func WithServiceChangeHandler(f func(namespace string, servers []string)) Option {
+	return func(r *Registry) {
+		r.serviceCache.onChange = f
+	}
+}
+
+// WithDistributor sets the distributor that assigns services to servers. The default is the
+// jump hash distributor.
+func WithDistributor(d hash.Distributor) Option {
+	return func(r *Registry) {
+		r.distributor = d
+	}
+}
+
 // StartCacheUpdater starts a namespace cache updater. It resyncs cache all reSyncInterval.
-func (r Registry) StartCacheUpdater(ctx context.Context, reSyncInterval time.Duration) {
+func (r *Registry) StartCacheUpdater(ctx context.Context, reSyncInterval time.Duration) {
 	namespaceEventChan := r.namespaceRepo.Chan(ctx, func(err error) {
 		r.log.Fatalw("failed to create namespace watcher", "err", err)
 	})
@@ -106,14 +165,55 @@ func (r Registry) StartCacheUpdater(ctx context.Context, reSyncInterval time.Dur
 	}
 }
 
+// StartServiceCacheUpdater keeps the service cache up to date with service events. It resyncs
+// the cache all reSyncInterval and runs until context ctx is canceled.
+func (r *Registry) StartServiceCacheUpdater(ctx context.Context, reSyncInterval time.Duration) {
+	serviceEventChan := r.serviceRepo.Watch(ctx, func(err error) {
+		r.log.Fatalw("failed to create service watcher", "err", err)
+	})
+
+	// events between initialization and start of the watcher are lost
+	if err := r.initServiceCache(); err != nil {
+		r.log.Errorw("failed to sync service cache", "err", err)
+	}
+
+	ticker := time.NewTicker(reSyncInterval)
+
+	for {
+		select {
+		case s, ok := <-serviceEventChan:
+			if !ok {
+				return
+			}
+
+			switch s.Event {
+			case repo.Change:
+				r.serviceCache.put(s.Service)
+			case repo.Delete:
+				r.serviceCache.del(s.ID, s.Namespace)
+			default:
+				r.log.Errorw("unsupported service event type", "event", s.Event.String())
+			}
+		case <-ctx.Done():
+			r.log.Infow("stopping service cache updater")
+
+			return
+		case <-ticker.C:
+			r.log.Debug("initiating service cache sync")
+
+			if err := r.initServiceCache(); err != nil {
+				r.log.Errorw("failed to sync service cache", "err", err)
+			}
+		}
+	}
+}
+
 // StartServiceCounterUpdater updates service counter metrics every interval. It runs until context ctx
 // is canceled.
-func (r Registry) StartServiceCounterUpdater(ctx context.Context, interval time.Duration) {
+func (r *Registry) StartServiceCounterUpdater(ctx context.Context, interval time.Duration) {
 	r.log.Infow("initializing service counter", "interval", interval)
 
-	if err := r.updateServiceCounter(); err != nil {
-		r.log.Errorw("failed to initialize service counter", "err", err)
-	}
+	r.updateServiceCounter()
 
 	ticker := time.NewTicker(interval)
 
@@ -126,31 +226,89 @@ func (r Registry) StartServiceCounterUpdater(ctx context.Context, interval time.
 		case <-ticker.C:
 			r.log.Debug("updating service counter")
 
-			if err := r.updateServiceCounter(); err != nil {
-				r.log.Errorw("service counter update failed", "err", err)
+			r.updateServiceCounter()
+		}
+	}
+}
+
+// StartLeaseReaper unregisters all services with expired leases every interval. It runs until
+// context ctx is canceled.
+func (r *Registry) StartLeaseReaper(ctx context.Context, interval time.Duration) {
+	r.log.Infow("starting lease reaper", "interval", interval)
+
+	ticker := time.NewTicker(interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.log.Info("stopping lease reaper")
+
+			return
+		case <-ticker.C:
+			r.log.Debug("reaping expired leases")
+
+			if err := r.reapExpiredLeases(time.Now()); err != nil {
+				r.log.Errorw("failed to reap expired leases", "err", err)
 			}
 		}
 	}
 }
 
+// ServerOption configures a registered server.
+type ServerOption func(*discovery.Server)
+
+// WithWeight sets the weight of a server. Services are distributed proportionally to the
+// weights of the servers.
+func WithWeight(w int) ServerOption {
+	return func(s *discovery.Server) {
+		s.Weight = w
+	}
+}
+
+// WithMaxTargets sets the maximum number of services of a server. If a server reached the
+// maximum, new services are distributed to the other servers.
+func WithMaxTargets(n int) ServerOption {
+	return func(s *discovery.Server) {
+		s.MaxTargets = n
+	}
+}
+
 // RegisterServer registers a server.
-func (r *Registry) RegisterServer(name string, labels discovery.Labels) (*discovery.Server, error) {
+func (r *Registry) RegisterServer(name string, labels discovery.Labels, opts ...ServerOption) (*discovery.Server, error) {
 	s := discovery.NewServer(name, labels)
 
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	state := discovery.Active
+
+	// a cordoned or draining server stays cordoned or draining
+	existing, err := r.serverRepo.Get(name)
+	if err != nil && !errors.Is(err, repo.ErrNotFound) {
+		return nil, err
+	}
+
+	if existing != nil && (existing.State == discovery.Cordoned || existing.State == discovery.Draining) {
+		state = existing.State
+		s.State = existing.State
+		s.DrainRate = existing.DrainRate
+	}
+
 	if err := s.Validate(); err != nil {
 		return nil, fmt.Errorf("%s : %w", err, ErrValidation)
 	}
 
-	_, err := r.serverRepo.Save(*s)
+	_, err = r.serverRepo.Save(*s)
 	if err != nil {
 		return nil, fmt.Errorf("failed to save server %s: %w", s.Name, err)
 	}
 
-	if _, err := r.ReRegisterAllServices(); err != nil {
-		return nil, fmt.Errorf("failed to reregister all services: %w", err)
+	if _, err := r.rebalance(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to rebalance services: %w", err)
 	}
 
-	s.State = discovery.Active
+	s.State = state
 	r.log.Infow("register server", s.KeyVals()...)
 
 	ns, err := r.serverRepo.Save(*s)
@@ -168,6 +326,7 @@ func (r *Registry) UnRegisterServer(name string) error {
 		return err
 	}
 
+	state := s.State
 	s.State = discovery.Leaving
 
 	if _, err := r.serverRepo.Save(*s); err != nil {
@@ -176,12 +335,19 @@ func (r *Registry) UnRegisterServer(name string) error {
 
 	r.log.Infow("unregister server", "name", name)
 
-	if _, err := r.ReRegisterAllServices(); err != nil {
-		return fmt.Errorf("failed to reregister all services: %w", err)
-	}
+	// the server is deleted when all its services are moved
+	plan, err := r.rebalance(context.Background(), name)
+	if err != nil {
+		if plan != nil && len(plan.Failures) > 0 {
+			// nothing was moved
+			s.State = state
+
+			if _, serr := r.serverRepo.Save(*s); serr != nil {
+				r.log.Errorw("failed to reset server state", "name", name, "err", serr)
+			}
+		}
 
-	if err := r.serverRepo.Delete(name); err != nil {
-		return fmt.Errorf("failed to delete server %s: %w", name, err)
+		return fmt.Errorf("failed to rebalance services: %w", err)
 	}
 
 	return nil
@@ -192,30 +358,161 @@ func (r *Registry) ListServer(selector string) (discovery.Servers, error) {
 	return r.serverRepo.List(selector)
 }
 
-// RegisterService registers a service.
+// RegisterService registers a service. If the service has a ttl, a lease is created
+// which has to be renewed with Heartbeat before it expires.
 func (r *Registry) RegisterService(s discovery.Service) (*discovery.Service, error) {
-	if err := s.Validate(); err != nil {
-		return nil, fmt.Errorf("%s : %w", err, ErrValidation)
+	svc, err := r.register(s)
+	if err != nil {
+		return nil, err
 	}
 
-	if !r.namespaceCache.hasNamespace(s.Namespace) {
-		return nil, ErrNamespaceNotFound
+	if err := r.updateLease(svc); err != nil {
+		return nil, err
 	}
 
-	r.log.Infow("register service", s.KeyVals()...)
+	return svc, nil
+}
+
+// RegisterServices registers multiple services. All services are validated before the first
+// service is saved. The valid services are saved in transactions of up to
+// registerBatchSize services, i.e. either all services of a transaction are saved or none,
+// but a failed transaction does not roll back the transactions before. It returns the
+// registered service and the error for every service in the same order as services.
+func (r *Registry) RegisterServices(services discovery.Services) ([]*discovery.Service, []error) {
+	result := make([]*discovery.Service, len(services))
+	errs := make([]error, len(services))
+
+	for i := range services {
+		errs[i] = r.validate(services[i])
+	}
+
+	var (
+		candidates = map[string]discovery.Servers{}
+		batch      = discovery.Services{}
+		indexes    = []int{}
+		keys       = map[string]bool{}
+		// pending is the number of services per server in the unsaved batch
+		pending = map[string]int{}
+	)
+
+	flush := func() {
+		saved, err := r.saveAll(batch)
+
+		for j, i := range indexes {
+			if err != nil {
+				errs[i] = err
+				continue
+			}
+
+			result[i] = &saved[j]
+		}
+
+		batch, indexes = discovery.Services{}, []int{}
+		keys, pending = map[string]bool{}, map[string]int{}
+	}
+
+	for i := range services {
+		if errs[i] != nil {
+			continue
+		}
+
+		s := services[i]
+
+		c, ok := candidates[s.Selector]
+		if !ok {
+			var err error
+
+			c, err = r.candidates(s.Selector)
+			if err != nil {
+				errs[i] = err
+				continue
+			}
+
+			candidates[s.Selector] = c
+		}
+
+		// a key must not be written twice in one transaction
+		k := cacheKey(s.Namespace, r.idGenerator(s.Endpoint.String()))
+		old := cacheKey(s.Namespace, s.ID)
+
+		if len(batch) == registerBatchSize || keys[k] || (s.ID != "" && keys[old]) {
+			flush()
+		}
+
+		r.log.Infow("register service", s.KeyVals()...)
+
+		servers, err := r.distribute(s, c, func(server string) int {
+			return r.serviceCache.load(server, k) + pending[server]
+		})
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+
+		s.Servers = servers.Names()
 
-	servers, err := r.get(s.Endpoint.String(), r.numReplicas, s.Selector)
+		for _, server := range s.Servers {
+			if !r.serviceCache.assigned(server, k) {
+				pending[server]++
+			}
+		}
+
+		keys[k] = true
+
+		if s.ID != "" {
+			keys[old] = true
+		}
+
+		batch = append(batch, s)
+		indexes = append(indexes, i)
+	}
+
+	if len(batch) > 0 {
+		flush()
+	}
+
+	for i := range result {
+		if result[i] == nil {
+			continue
+		}
+
+		if err := r.updateLease(result[i]); err != nil {
+			errs[i] = err
+			result[i] = nil
+		}
+	}
+
+	return result, errs
+}
+
+// saveAll saves services with their servers in one transaction.
+func (r *Registry) saveAll(services discovery.Services) (discovery.Services, error) {
+	saved, err := r.serviceRepo.SaveAll(services)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(servers) == 0 {
-		return nil, ErrNoServersFound
+	for i := range saved {
+		if services[i].ID != "" && services[i].ID != saved[i].ID { // endpoint changed
+			r.serviceCache.del(services[i].ID, services[i].Namespace)
+		}
+
+		r.serviceCache.put(saved[i])
 	}
 
-	s.Servers = servers.Names()
+	return saved, nil
+}
+
+// UnRegisterServices removes multiple services by id or endpoint. It returns the error for
+// every service in the same order as idOrEndpoints and namespaces.
+func (r *Registry) UnRegisterServices(idOrEndpoints, namespaces []string) []error {
+	errs := make([]error, len(idOrEndpoints))
 
-	return r.serviceRepo.Save(s)
+	for i := range idOrEndpoints {
+		errs[i] = r.UnRegisterService(idOrEndpoints[i], namespaces[i])
+	}
+
+	return errs
 }
 
 // UnRegisterService removes a service by id or endpoint. If namespace is empty string
@@ -225,43 +522,79 @@ func (r *Registry) UnRegisterService(idOrEndpoint, namespace string) error {
 		namespace = discovery.DefaultNamespace().Name
 	}
 
-	id := idOrEndpoint
+	id := r.id(idOrEndpoint)
+
+	r.log.Infow("unregister service", "id", id, "namespace", namespace)
+
+	if err := r.leaseRepo.Delete(id, namespace); err != nil && !errors.Is(err, repo.ErrNotFound) {
+		return fmt.Errorf("failed to delete lease of service %s: %w", id, err)
+	}
 
-	if strings.Contains(id, ":") {
-		id = r.idGenerator(idOrEndpoint)
+	if err := r.serviceRepo.Delete(id, namespace); err != nil {
+		return err
 	}
 
-	r.log.Infow("unregister service", "id", id, "namespace", namespace)
+	r.serviceCache.del(id, namespace)
 
-	return r.serviceRepo.Delete(id, namespace)
+	return nil
 }
 
-// ReRegisterAllServices reregisters all services.
-func (r *Registry) ReRegisterAllServices() (numChanges int, err error) {
-	allServices, err := r.serviceRepo.List("", "")
+// Heartbeat renews the lease of a service by id or endpoint. If namespace is empty string
+// then discovery.DefaultNamespace is used. If the service was unregistered or its lease
+// expired, repo.ErrNotFound is returned and the lease is not created again. The lease is
+// checked in the store, so that services registered on other discovery servers can be
+// renewed before they are cached. If the lease is changed concurrently, repo.ErrConflict
+// is returned and the heartbeat can be retried.
+func (r *Registry) Heartbeat(idOrEndpoint, namespace string) (*discovery.Lease, error) {
+	if namespace == "" {
+		namespace = discovery.DefaultNamespace().Name
+	}
+
+	id := r.id(idOrEndpoint)
+
+	l, err := r.leaseRepo.Renew(id, namespace)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	for i := range allServices {
-		s := allServices[i]
+	r.log.Debugw("renew lease", l.KeyVals()...)
 
-		ns, err := r.RegisterService(s)
-		if err != nil {
-			return 0, err
-		}
+	return l, nil
+}
 
-		if !reflect.DeepEqual(s.Servers, ns.Servers) {
-			numChanges++
-		}
+// ListService lists all services in namespace by selector. If namespace is empty
+// string, the services of all namespaces are returned.
+func (r *Registry) ListService(namespace, selector string) (discovery.Services, error) {
+	services := r.serviceCache.list(namespace)
+
+	if selector == "" {
+		return services, nil
 	}
 
-	return numChanges, nil
+	sel, err := labels.Parse(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	return services.Filter(discovery.ServiceBySelector(sel)), nil
 }
 
-// ListService lists all services.
-func (r *Registry) ListService(namespace, selector string) (discovery.Services, error) {
-	return r.serviceRepo.List(namespace, selector)
+// ListServiceByServer lists all services in namespace, which are assigned to server. If
+// namespace is empty string, the services of all namespaces are returned.
+func (r *Registry) ListServiceByServer(server, namespace string) (discovery.Services, error) {
+	return r.serviceCache.listByServer(server, namespace), nil
+}
+
+// ListServiceByServerRegexp lists all services in namespace, which are assigned to a server
+// matching re. If namespace is empty string, the services of all namespaces are returned.
+func (r *Registry) ListServiceByServerRegexp(re *regexp.Regexp, namespace string) (discovery.Services, error) {
+	return r.serviceCache.listByServers(re.MatchString, namespace), nil
+}
+
+// WatchServices returns a channel of service change and delete events. Every call starts
+// a new watcher, which stops when ctx is canceled.
+func (r *Registry) WatchServices(ctx context.Context, errorHandler func(error)) <-chan *repo.ServiceEvent {
+	return r.serviceRepo.Watch(ctx, errorHandler)
 }
 
 // RegisterNamespace registers a namespace.
@@ -274,6 +607,8 @@ func (r *Registry) RegisterNamespace(n discovery.Namespace) (*discovery.Namespac
 
 	n.Modified = time.Now()
 
+	old, exists := r.namespaceCache.get(n.Name)
+
 	ns, err := r.namespaceRepo.Save(n)
 	if err != nil {
 		return ns, fmt.Errorf("failed to save namespace %s: %w", n.Name, err)
@@ -281,6 +616,12 @@ func (r *Registry) RegisterNamespace(n discovery.Namespace) (*discovery.Namespac
 
 	r.namespaceCache.add(n)
 
+	if exists && old.Replicas != n.Replicas {
+		if _, err := r.rebalance(context.Background()); err != nil {
+			return nil, fmt.Errorf("failed to rebalance services: %w", err)
+		}
+	}
+
 	return ns, nil
 }
 
@@ -289,14 +630,29 @@ func (r *Registry) ListNamespaces() (discovery.Namespaces, error) {
 	return r.namespaceCache.list(), nil
 }
 
+// Namespace returns the registered namespace with name.
+func (r *Registry) Namespace(name string) (discovery.Namespace, bool) {
+	return r.namespaceCache.get(name)
+}
+
 // UnRegisterNamespace unregisters a namespace.
 func (r *Registry) UnRegisterNamespace(name string) error {
 	r.log.Infow("unregister namespace", "name", name)
 
+	if err := r.moveToTrash(name); err != nil {
+		return err
+	}
+
 	if err := r.serviceRepo.DeleteFromNamespace(name); err != nil {
 		return fmt.Errorf("failed to delete all services in namespace %s: %w", name, err)
 	}
 
+	if err := r.leaseRepo.DeleteFromNamespace(name); err != nil {
+		return fmt.Errorf("failed to delete all leases in namespace %s: %w", name, err)
+	}
+
+	r.serviceCache.delNamespace(name)
+
 	if err := r.namespaceRepo.Delete(name); err != nil {
 		return fmt.Errorf("failed to delete namespace %s: %w", name, err)
 	}
@@ -306,12 +662,131 @@ func (r *Registry) UnRegisterNamespace(name string) error {
 	return nil
 }
 
-func (r *Registry) updateServiceCounter() error {
-	services, err := r.serviceRepo.List("", "")
+// register validates service s and saves it with its assigned servers.
+func (r *Registry) register(s discovery.Service) (*discovery.Service, error) {
+	if err := r.validate(s); err != nil {
+		return nil, err
+	}
+
+	candidates, err := r.candidates(s.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.save(s, candidates)
+}
+
+func (r *Registry) validate(s discovery.Service) error {
+	if err := s.Validate(); err != nil {
+		return fmt.Errorf("%s : %w", err, ErrValidation)
+	}
+
+	if !r.namespaceCache.hasNamespace(s.Namespace) {
+		return ErrNamespaceNotFound
+	}
+
+	return nil
+}
+
+// save saves service s with the servers chosen from candidates.
+func (r *Registry) save(s discovery.Service, candidates discovery.Servers) (*discovery.Service, error) {
+	r.log.Infow("register service", s.KeyVals()...)
+
+	k := cacheKey(s.Namespace, r.idGenerator(s.Endpoint.String()))
+
+	servers, err := r.distribute(s, candidates, func(server string) int {
+		return r.serviceCache.load(server, k)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.Servers = servers.Names()
+
+	svc, err := r.serviceRepo.Save(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.ID != "" && s.ID != svc.ID { // endpoint changed
+		r.serviceCache.del(s.ID, s.Namespace)
+	}
+
+	r.serviceCache.put(*svc)
+
+	return svc, nil
+}
+
+// replicas returns the number of replicas of service s. The replicas of the service override
+// the replicas of its namespace, which override the default.
+func (r *Registry) replicas(s discovery.Service) int {
+	if s.Replicas > 0 {
+		return s.Replicas
+	}
+
+	if ns, ok := r.namespaceCache.get(s.Namespace); ok && ns.Replicas > 0 {
+		return ns.Replicas
+	}
+
+	return r.numReplicas
+}
+
+// updateLease creates a lease for a service with ttl and removes the lease
+// of a service without ttl.
+func (r *Registry) updateLease(svc *discovery.Service) error {
+	if svc.TTL == 0 {
+		if err := r.leaseRepo.Delete(svc.ID, svc.Namespace); err != nil && !errors.Is(err, repo.ErrNotFound) {
+			return fmt.Errorf("failed to delete lease of service %s: %w", svc.ID, err)
+		}
+
+		return nil
+	}
+
+	if _, err := r.leaseRepo.Save(*discovery.NewLease(svc.ID, svc.Namespace, svc.TTL)); err != nil {
+		return fmt.Errorf("failed to save lease of service %s: %w", svc.ID, err)
+	}
+
+	return nil
+}
+
+// id returns the service id for idOrEndpoint.
+func (r *Registry) id(idOrEndpoint string) string {
+	if strings.Contains(idOrEndpoint, ":") {
+		return r.idGenerator(idOrEndpoint)
+	}
+
+	return idOrEndpoint
+}
+
+// reapExpiredLeases unregisters all services whose lease expired before t.
+func (r *Registry) reapExpiredLeases(t time.Time) error {
+	leases, err := r.leaseRepo.List("")
 	if err != nil {
 		return err
 	}
 
+	for _, l := range leases.Expired(t) {
+		r.log.Infow("lease expired", l.KeyVals()...)
+
+		if err := r.serviceRepo.Delete(l.ID, l.Namespace); err != nil && !errors.Is(err, repo.ErrNotFound) {
+			return fmt.Errorf("failed to delete service %s/%s: %w", l.Namespace, l.ID, err)
+		}
+
+		r.serviceCache.del(l.ID, l.Namespace)
+
+		if err := r.leaseRepo.Delete(l.ID, l.Namespace); err != nil && !errors.Is(err, repo.ErrNotFound) {
+			return fmt.Errorf("failed to delete lease %s/%s: %w", l.Namespace, l.ID, err)
+		}
+
+		r.expiredCount.WithLabelValues(l.Namespace).Inc()
+	}
+
+	return nil
+}
+
+func (r *Registry) updateServiceCounter() {
+	services := r.serviceCache.list("")
+
 	r.servicesCount.Reset()
 
 	for i := range services {
@@ -320,12 +795,19 @@ func (r *Registry) updateServiceCounter() error {
 		}
 	}
 
-	return nil
+	servers, err := r.serverRepo.List("")
+	if err != nil {
+		r.log.Errorw("failed to list servers", "err", err)
+		return
+	}
+
+	r.serverMetrics.update(servers.Enabled(), func(server string) int {
+		return r.serviceCache.load(server, "")
+	})
 }
 
-// get gets one or numReplica server for a key via consistent hasher. If numReplica is larger
-// than the number of servers, len(servers) is used.
-func (r *Registry) get(key string, numReplica int, selector string) (discovery.Servers, error) {
+// candidates returns all enabled servers matching selector sorted by name.
+func (r *Registry) candidates(selector string) (discovery.Servers, error) {
 	candidates, err := r.serverRepo.List(selector)
 	if err != nil {
 		return nil, err
@@ -334,34 +816,81 @@ func (r *Registry) get(key string, numReplica int, selector string) (discovery.S
 	candidates = candidates.Enabled()
 	candidates.SortByName()
 
+	return candidates, nil
+}
+
+// distribute returns the servers for service s from candidates. Function load returns the
+// number of services of a server without s. Candidates that reached their maximum number of
+// targets are skipped. Cordoned and draining candidates s is assigned to keep s, the remaining
+// replicas are distributed on the schedulable candidates.
+func (r *Registry) distribute(s discovery.Service, candidates discovery.Servers, load func(server string) int) (discovery.Servers, error) {
+	k := cacheKey(s.Namespace, r.idGenerator(s.Endpoint.String()))
+
+	pinned := candidates.Filter(func(c discovery.Server) bool {
+		return !c.Schedulable() && r.serviceCache.assigned(c.Name, k)
+	})
+
+	replicas := r.replicas(s)
+
+	if len(pinned) >= replicas {
+		return pinned[:replicas], nil
+	}
+
+	candidates = candidates.Filter(func(c discovery.Server) bool {
+		return c.Schedulable()
+	})
+
+	available := candidates.Filter(func(c discovery.Server) bool {
+		return !c.IsFull(load(c.Name))
+	})
+
+	if len(candidates) > 0 && len(available) == 0 {
+		// registered services are not dropped, i.e. when a server is unregistered
+		if !r.serviceCache.has(k) {
+			return nil, ErrServersFull
+		}
+
+		r.log.Warnw("all servers reached their maximum number of targets", s.KeyVals()...)
+
+		available = candidates
+	}
+
+	servers := append(pinned, r.get(s.Endpoint.String(), replicas-len(pinned), available, load)...)
+	if len(servers) == 0 {
+		return nil, ErrNoServersFound
+	}
+
+	servers.SortByName()
+
+	return servers, nil
+}
+
+// get gets one or numReplica server for key from candidates with the configured distributor.
+// If numReplica is larger than the number of candidates, len(candidates) is used.
+func (r *Registry) get(key string, numReplica int, candidates discovery.Servers, load func(server string) int) discovery.Servers {
 	if numReplica > len(candidates) {
 		numReplica = len(candidates)
 	}
 
 	if numReplica == len(candidates) {
-		return candidates, nil
+		return candidates
 	}
 
-	result := make(discovery.Servers, 0, numReplica)
-	i := r.jumpHasher.HashString(key, len(candidates))
+	nodes := make([]hash.Node, 0, len(candidates))
 
-	for {
-		if len(result) == numReplica {
-			break
-		}
+	for _, c := range candidates {
+		nodes = append(nodes, hash.Node{Name: c.Name, Weight: c.EffectiveWeight()})
+	}
 
-		if i >= len(candidates) {
-			i = 0
-		}
+	result := make(discovery.Servers, 0, numReplica)
 
+	for _, i := range r.distributor.Distribute(key, nodes, numReplica, func(i int) int { return load(nodes[i].Name) }) {
 		result = append(result, candidates[i])
-
-		i++
 	}
 
 	result.SortByName()
 
-	return result, nil
+	return result
 }
 
 func (r *Registry) initNamespaceCache() error {
@@ -379,6 +908,19 @@ func (r *Registry) initNamespaceCache() error {
 	return nil
 }
 
+func (r *Registry) initServiceCache() error {
+	started := time.Now()
+
+	services, err := r.serviceRepo.List("", "")
+	if err != nil {
+		return err
+	}
+
+	r.serviceCache.reset(services, started)
+
+	return nil
+}
+
 type namespaceCache struct {
 	m          *sync.Mutex
 	namespaces map[string]discovery.Namespace
@@ -392,6 +934,14 @@ func (n *namespaceCache) hasNamespace(name string) bool {
 	return ok
 }
 
+func (n *namespaceCache) get(name string) (discovery.Namespace, bool) {
+	n.m.Lock()
+	ns, ok := n.namespaces[name]
+	n.m.Unlock()
+
+	return ns, ok
+}
+
 func (n *namespaceCache) reset() {
 	n.m.Lock()
 	n.namespaces = map[string]discovery.Namespace{}
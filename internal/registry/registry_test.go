@@ -1,10 +1,18 @@
 package registry
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/postfinance/discovery"
-	"github.com/postfinance/store/hash"
+	"github.com/postfinance/discovery/internal/bolt"
+	dhash "github.com/postfinance/discovery/internal/hash"
+	"github.com/postfinance/discovery/internal/repo"
+	"github.com/postfinance/store"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -12,8 +20,7 @@ import (
 )
 
 func TestRegistry(t *testing.T) {
-	c, err := hash.New(hash.WithPrefix("/disovery"))
-	require.NoError(t, err)
+	c := newBackend(t)
 
 	r, err := New(c, prometheus.NewRegistry(), zap.NewNop().Sugar(), 2)
 	require.NoError(t, err)
@@ -83,6 +90,82 @@ func TestRegistry(t *testing.T) {
 		assert.Len(t, l, 1)
 	})
 
+	t.Run("register service with ttl", func(t *testing.T) {
+		s := discovery.MustNewService("ttl", "http://ttl.example.com/metrics")
+		s.TTL = time.Minute
+		ns, err := r.RegisterService(*s)
+		require.NoError(t, err)
+		l, err := r.leaseRepo.Get(ns.ID, ns.Namespace)
+		require.NoError(t, err)
+		assert.Equal(t, time.Minute, l.TTL)
+	})
+
+	t.Run("heartbeat", func(t *testing.T) {
+		l, err := r.Heartbeat("http://ttl.example.com/metrics", "")
+		require.NoError(t, err)
+		assert.True(t, l.Expires.After(time.Now()))
+
+		_, err = r.Heartbeat("http://t.example.com/metrics", "")
+		assert.True(t, errors.Is(err, repo.ErrNotFound))
+
+		// another discovery server, that has not cached the service yet
+		other, err := New(c, prometheus.NewRegistry(), zap.NewNop().Sugar(), 2)
+		require.NoError(t, err)
+
+		other.serviceCache.del(l.ID, l.Namespace)
+
+		_, err = other.Heartbeat("http://ttl.example.com/metrics", "")
+		assert.NoError(t, err)
+	})
+
+	t.Run("reap expired leases", func(t *testing.T) {
+		require.NoError(t, r.reapExpiredLeases(time.Now()))
+		l, err := r.serviceRepo.List("", "")
+		require.NoError(t, err)
+		assert.Len(t, l, 2)
+
+		require.NoError(t, r.reapExpiredLeases(time.Now().Add(2*time.Minute)))
+		l, err = r.serviceRepo.List("", "")
+		require.NoError(t, err)
+		assert.Len(t, l, 1)
+		leases, err := r.leaseRepo.List("")
+		require.NoError(t, err)
+		assert.Len(t, leases, 0)
+
+		_, err = r.Heartbeat("http://ttl.example.com/metrics", "")
+		assert.True(t, errors.Is(err, repo.ErrNotFound), "reaped service")
+		leases, err = r.leaseRepo.List("")
+		require.NoError(t, err)
+		assert.Len(t, leases, 0, "no orphaned lease")
+	})
+
+	t.Run("register services", func(t *testing.T) {
+		invalid := discovery.MustNewService("invalid", "http://i.example.com/metrics")
+		invalid.Namespace = "notexist"
+
+		svcs, errs := r.RegisterServices(discovery.Services{
+			*discovery.MustNewService("bulk", "http://b1.example.com/metrics"),
+			*invalid,
+			*discovery.MustNewService("bulk", "http://b2.example.com/metrics"),
+		})
+		require.Len(t, errs, 3)
+		assert.NoError(t, errs[0])
+		assert.True(t, IsNamespaceNotFound(errs[1]))
+		assert.Nil(t, svcs[1])
+		assert.NoError(t, errs[2])
+		assert.NotEmpty(t, svcs[2].Servers)
+	})
+
+	t.Run("unregister services", func(t *testing.T) {
+		errs := r.UnRegisterServices(
+			[]string{"http://b1.example.com/metrics", "http://b2.example.com/metrics", "http://b3.example.com/metrics"},
+			[]string{"", "default", "default"},
+		)
+		assert.NoError(t, errs[0])
+		assert.NoError(t, errs[1])
+		assert.True(t, errors.Is(errs[2], repo.ErrNotFound))
+	})
+
 	t.Run("unregister namespace ", func(t *testing.T) {
 		err := r.UnRegisterNamespace(discovery.DefaultNamespace().Name)
 		require.NoError(t, err)
@@ -91,3 +174,334 @@ func TestRegistry(t *testing.T) {
 		assert.Len(t, l, 0)
 	})
 }
+
+func TestServiceCache(t *testing.T) {
+	c := newBackend(t)
+
+	r, err := New(c, prometheus.NewRegistry(), zap.NewNop().Sugar(), 1)
+	require.NoError(t, err)
+
+	_, err = r.RegisterNamespace(*discovery.DefaultNamespace())
+	require.NoError(t, err)
+	_, err = r.RegisterServer("server1", nil)
+	require.NoError(t, err)
+	_, err = r.RegisterServer("server2", nil)
+	require.NoError(t, err)
+
+	for _, ep := range []string{"http://a.example.com", "http://b.example.com", "http://c.example.com"} {
+		_, err := r.RegisterService(*discovery.MustNewService("test", ep))
+		require.NoError(t, err)
+	}
+
+	t.Run("list by server", func(t *testing.T) {
+		s1, err := r.ListServiceByServer("server1", "default")
+		require.NoError(t, err)
+		s2, err := r.ListServiceByServer("server2", "")
+		require.NoError(t, err)
+		assert.Len(t, append(s1, s2...), 3)
+
+		s, err := r.ListServiceByServer("server1", "other")
+		require.NoError(t, err)
+		assert.Len(t, s, 0)
+	})
+
+	t.Run("updater applies store events", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go r.StartServiceCacheUpdater(ctx, time.Hour)
+
+		// write to store without registry
+		svc := discovery.MustNewService("test", "http://d.example.com")
+		svc.Servers = []string{"server1"}
+		_, err := r.serviceRepo.Save(*svc)
+		require.NoError(t, err)
+
+		assert.Eventually(t, func() bool {
+			l, err := r.ListService("default", "")
+			return err == nil && len(l) == 4
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("unregister", func(t *testing.T) {
+		require.NoError(t, r.UnRegisterService("http://a.example.com", ""))
+		l, err := r.ListService("", "")
+		require.NoError(t, err)
+		assert.Len(t, l, 3)
+	})
+}
+
+func TestDistributor(t *testing.T) {
+	for _, strategy := range []string{dhash.RendezvousDistribution, dhash.BoundedLoadDistribution} {
+		strategy := strategy
+
+		t.Run(strategy, func(t *testing.T) {
+			c := newBackend(t)
+
+			d, err := dhash.NewDistributor(strategy)
+			require.NoError(t, err)
+
+			r, err := New(c, prometheus.NewRegistry(), zap.NewNop().Sugar(), 1, WithDistributor(d))
+			require.NoError(t, err)
+
+			_, err = r.RegisterNamespace(*discovery.DefaultNamespace())
+			require.NoError(t, err)
+			_, err = r.RegisterServer("server1", nil)
+			require.NoError(t, err)
+			_, err = r.RegisterServer("server2", nil)
+			require.NoError(t, err)
+
+			for i := 0; i < 20; i++ {
+				_, err := r.RegisterService(*discovery.MustNewService("test", fmt.Sprintf("http://host%d.example.com", i)))
+				require.NoError(t, err)
+			}
+
+			before, err := r.ListService("", "")
+			require.NoError(t, err)
+
+			for _, server := range []string{"server1", "server2"} {
+				s, err := r.ListServiceByServer(server, "")
+				require.NoError(t, err)
+				assert.LessOrEqual(t, len(s), 15, server)
+			}
+
+			_, err = r.RegisterServer("server3", nil)
+			require.NoError(t, err)
+
+			after, err := r.ListService("", "")
+			require.NoError(t, err)
+			require.Len(t, after, len(before))
+
+			if strategy == dhash.RendezvousDistribution {
+				// services only move to the new server
+				for i := range after {
+					if after[i].Servers[0] != before[i].Servers[0] {
+						assert.Equal(t, []string{"server3"}, after[i].Servers)
+					}
+				}
+
+				return
+			}
+
+			// no server has more than 1.25 times the average load
+			for _, server := range []string{"server1", "server2", "server3"} {
+				s, err := r.ListServiceByServer(server, "")
+				require.NoError(t, err)
+				assert.LessOrEqual(t, len(s), 9, server)
+			}
+		})
+	}
+}
+
+func TestServerCapacity(t *testing.T) {
+	c := newBackend(t)
+
+	r, err := New(c, prometheus.NewRegistry(), zap.NewNop().Sugar(), 1)
+	require.NoError(t, err)
+
+	_, err = r.RegisterNamespace(*discovery.DefaultNamespace())
+	require.NoError(t, err)
+
+	_, err = r.RegisterServer("server1", nil, WithWeight(-1))
+	assert.True(t, IsValidationError(err))
+
+	_, err = r.RegisterServer("server1", nil, WithMaxTargets(2))
+	require.NoError(t, err)
+	_, err = r.RegisterServer("server2", nil, WithWeight(3))
+	require.NoError(t, err)
+
+	for i := 0; i < 20; i++ {
+		_, err := r.RegisterService(*discovery.MustNewService("test", fmt.Sprintf("http://host%d.example.com", i)))
+		require.NoError(t, err)
+	}
+
+	s1, err := r.ListServiceByServer("server1", "")
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(s1), 2)
+
+	t.Run("full servers", func(t *testing.T) {
+		// registered services are moved to full servers
+		require.NoError(t, r.UnRegisterServer("server2"))
+
+		s1, err := r.ListServiceByServer("server1", "")
+		require.NoError(t, err)
+		assert.Len(t, s1, 20)
+
+		_, err = r.RegisterService(s1[0])
+		require.NoError(t, err)
+
+		_, err = r.RegisterService(*discovery.MustNewService("test", "http://new.example.com"))
+		assert.True(t, IsServersFull(err))
+	})
+}
+
+func TestReplicas(t *testing.T) {
+	c := newBackend(t)
+
+	r, err := New(c, prometheus.NewRegistry(), zap.NewNop().Sugar(), 1)
+	require.NoError(t, err)
+
+	for _, name := range []string{"server1", "server2", "server3"} {
+		_, err := r.RegisterServer(name, nil)
+		require.NoError(t, err)
+	}
+
+	_, err = r.RegisterNamespace(discovery.Namespace{Name: "critical", Replicas: 2})
+	require.NoError(t, err)
+	_, err = r.RegisterNamespace(discovery.Namespace{Name: "dev"})
+	require.NoError(t, err)
+
+	register := func(namespace, endpoint string, replicas int) *discovery.Service {
+		s := discovery.MustNewService("test", endpoint)
+		s.Namespace = namespace
+		s.Replicas = replicas
+
+		svc, err := r.RegisterService(*s)
+		require.NoError(t, err)
+
+		return svc
+	}
+
+	assert.Len(t, register("critical", "http://a.example.com", 0).Servers, 2, "namespace replicas")
+	assert.Len(t, register("dev", "http://a.example.com", 0).Servers, 1, "default replicas")
+	assert.Len(t, register("dev", "http://b.example.com", 3).Servers, 3, "service replicas")
+	assert.Len(t, register("critical", "http://c.example.com", 5).Servers, 3, "limited by the number of servers")
+
+	t.Run("namespace change reregisters services", func(t *testing.T) {
+		_, err := r.RegisterNamespace(discovery.Namespace{Name: "critical", Replicas: 1})
+		require.NoError(t, err)
+
+		l, err := r.ListService("critical", "")
+		require.NoError(t, err)
+		require.Len(t, l, 2)
+
+		for _, s := range l {
+			if s.Replicas == 5 {
+				assert.Len(t, s.Servers, 3)
+			} else {
+				assert.Len(t, s.Servers, 1)
+			}
+		}
+	})
+
+	t.Run("reregister all services", func(t *testing.T) {
+		_, err := r.RegisterServer("server4", nil)
+		require.NoError(t, err)
+
+		l, err := r.ListService("dev", "")
+		require.NoError(t, err)
+		require.Len(t, l, 2)
+
+		for _, s := range l {
+			if s.Replicas == 3 {
+				assert.Len(t, s.Servers, 3)
+			} else {
+				assert.Len(t, s.Servers, 1)
+			}
+		}
+	})
+
+	t.Run("negative replicas", func(t *testing.T) {
+		_, err := r.RegisterNamespace(discovery.Namespace{Name: "other", Replicas: -1})
+		assert.True(t, IsValidationError(err))
+	})
+}
+
+func TestRegisterServices(t *testing.T) {
+	c := &txnBackend{Backend: newBackend(t)}
+
+	r, err := New(c, prometheus.NewRegistry(), zap.NewNop().Sugar(), 1)
+	require.NoError(t, err)
+
+	_, err = r.RegisterServer("server1", nil)
+	require.NoError(t, err)
+
+	_, err = r.RegisterNamespace(discovery.Namespace{Name: "default"})
+	require.NoError(t, err)
+
+	t.Run("batches", func(t *testing.T) {
+		services := discovery.Services{}
+		for i := 0; i < 2*registerBatchSize+10; i++ {
+			services = append(services, *discovery.MustNewService("bulk", fmt.Sprintf("http://host%d.example.com", i)))
+		}
+
+		// the same endpoint twice
+		services = append(services, services[0], services[0])
+
+		svcs, errs := r.RegisterServices(services)
+
+		for i := range errs {
+			require.NoError(t, errs[i])
+			assert.Equal(t, []string{"server1"}, svcs[i].Servers)
+		}
+
+		l, err := r.serviceRepo.List("", "")
+		require.NoError(t, err)
+		assert.Len(t, l, 2*registerBatchSize+10)
+	})
+
+	t.Run("failed transaction", func(t *testing.T) {
+		c.err = errors.New("txn failed")
+
+		defer func() { c.err = nil }()
+
+		svcs, errs := r.RegisterServices(discovery.Services{
+			*discovery.MustNewService("failed", "http://failed1.example.com"),
+			*discovery.MustNewService("failed", "http://failed2.example.com"),
+		})
+
+		for i := range errs {
+			assert.ErrorIs(t, errs[i], c.err)
+			assert.Nil(t, svcs[i])
+		}
+
+		l, err := r.serviceRepo.List("", "")
+		require.NoError(t, err)
+		assert.Len(t, l, 2*registerBatchSize+10, "no service is saved")
+	})
+}
+
+// txnBackend checks transactions like etcd: a key must not be written twice in one
+// transaction. If err is set, all transactions fail.
+type txnBackend struct {
+	*bolt.Backend
+	err error
+}
+
+func (b *txnBackend) Txn(cmps, puts []store.Entry, dels []string) (bool, error) {
+	if b.err != nil {
+		return false, b.err
+	}
+
+	keys := map[string]bool{}
+
+	for _, e := range puts {
+		if keys[e.Key] {
+			return false, fmt.Errorf("duplicate key %s", e.Key)
+		}
+
+		keys[e.Key] = true
+	}
+
+	for _, k := range dels {
+		if keys[k] {
+			return false, fmt.Errorf("duplicate key %s", k)
+		}
+
+		keys[k] = true
+	}
+
+	return b.Backend.Txn(cmps, puts, dels)
+}
+
+// newBackend returns a bolt backend, because it supports transactions.
+func newBackend(t *testing.T) *bolt.Backend {
+	t.Helper()
+
+	b, err := bolt.New(filepath.Join(t.TempDir(), "discovery.db"))
+	require.NoError(t, err)
+
+	t.Cleanup(func() { b.Close() })
+
+	return b
+}
@@ -0,0 +1,75 @@
+package registry
+
+import (
+	"github.com/postfinance/discovery"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// serverMetrics exposes the capacity and utilization of servers.
+type serverMetrics struct {
+	weight      *prometheus.GaugeVec
+	targets     *prometheus.GaugeVec
+	utilization *prometheus.GaugeVec
+	share       *prometheus.GaugeVec
+	undrainable *prometheus.GaugeVec
+}
+
+func newServerMetrics(reg prometheus.Registerer) serverMetrics {
+	m := serverMetrics{
+		weight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "discovery_server_weight",
+			Help: "Weight of a server.",
+		}, []string{"server"}),
+		targets: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "discovery_server_targets",
+			Help: "Number of services of a server in all namespaces.",
+		}, []string{"server"}),
+		utilization: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "discovery_server_utilization_ratio",
+			Help: "Number of services of a server divided by its maximum number of targets. Only servers with a maximum are exposed.",
+		}, []string{"server"}),
+		share: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "discovery_server_weighted_share_ratio",
+			Help: "Number of services of a server divided by its weighted share of all services. 1 means the server has exactly its share.",
+		}, []string{"server"}),
+		undrainable: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "discovery_server_undrainable_services",
+			Help: "Number of services of a draining server that could not be moved to other servers in the last drain interval.",
+		}, []string{"server"}),
+	}
+
+	reg.MustRegister(m.weight, m.targets, m.utilization, m.share, m.undrainable)
+
+	return m
+}
+
+// update updates the metrics of servers. Function targets returns the number of services of a server.
+func (m serverMetrics) update(servers discovery.Servers, targets func(server string) int) {
+	m.weight.Reset()
+	m.targets.Reset()
+	m.utilization.Reset()
+	m.share.Reset()
+
+	total, weights := 0, 0
+
+	for _, s := range servers {
+		total += targets(s.Name)
+		weights += s.EffectiveWeight()
+	}
+
+	for _, s := range servers {
+		n := targets(s.Name)
+
+		m.weight.WithLabelValues(s.Name).Set(float64(s.EffectiveWeight()))
+		m.targets.WithLabelValues(s.Name).Set(float64(n))
+
+		if s.MaxTargets > 0 {
+			m.utilization.WithLabelValues(s.Name).Set(float64(n) / float64(s.MaxTargets))
+		}
+
+		if total > 0 {
+			share := float64(total) * float64(s.EffectiveWeight()) / float64(weights)
+			m.share.WithLabelValues(s.Name).Set(float64(n) / share)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package registry
+
+import (
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/postfinance/discovery"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// serviceCache is an in-memory index of all services by namespace and server.
+type serviceCache struct {
+	m        *sync.Mutex
+	services map[string]discovery.Service   // namespace/id -> service
+	servers  map[string]map[string]struct{} // server -> namespace/id
+	lastSync time.Time
+	duration prometheus.Histogram
+	// onChange is called with the namespace and the previous and current servers of
+	// every changed service.
+	onChange func(namespace string, servers []string)
+}
+
+func newServiceCache(reg prometheus.Registerer) *serviceCache {
+	c := &serviceCache{
+		m:        &sync.Mutex{},
+		services: map[string]discovery.Service{},
+		servers:  map[string]map[string]struct{}{},
+		duration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name: "discovery_service_cache_resync_duration_seconds",
+			Help: "Duration of the service cache resynchronization with the store.",
+		}),
+	}
+
+	reg.MustRegister(
+		c.duration,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "discovery_service_cache_size",
+			Help: "Number of services in the service cache.",
+		}, func() float64 {
+			c.m.Lock()
+			defer c.m.Unlock()
+
+			return float64(len(c.services))
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "discovery_service_cache_staleness_seconds",
+			Help: "Seconds since the last successful resynchronization of the service cache.",
+		}, func() float64 {
+			c.m.Lock()
+			defer c.m.Unlock()
+
+			return time.Since(c.lastSync).Seconds()
+		}),
+	)
+
+	return c
+}
+
+// reset replaces all cached services.
+func (c *serviceCache) reset(services discovery.Services, started time.Time) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	previous := c.services
+
+	c.services = map[string]discovery.Service{}
+	c.servers = map[string]map[string]struct{}{}
+
+	for i := range services {
+		c.add(services[i])
+	}
+
+	for k, s := range c.services {
+		if p, ok := previous[k]; !ok || !p.Modified.Equal(s.Modified) || !equalServers(p.Servers, s.Servers) {
+			c.changed(s.Namespace, p.Servers, s.Servers)
+		}
+	}
+
+	for k, p := range previous {
+		if _, ok := c.services[k]; !ok {
+			c.changed(p.Namespace, p.Servers)
+		}
+	}
+
+	c.lastSync = time.Now()
+	c.duration.Observe(c.lastSync.Sub(started).Seconds())
+}
+
+func (c *serviceCache) put(s discovery.Service) {
+	c.m.Lock()
+	c.changed(s.Namespace, c.services[cacheKey(s.Namespace, s.ID)].Servers, s.Servers)
+	c.add(s)
+	c.m.Unlock()
+}
+
+func (c *serviceCache) del(id, namespace string) {
+	k := cacheKey(namespace, id)
+
+	c.m.Lock()
+	c.changed(namespace, c.services[k].Servers)
+	c.remove(k)
+	c.m.Unlock()
+}
+
+func (c *serviceCache) delNamespace(namespace string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	for k, s := range c.services {
+		if s.Namespace == namespace {
+			c.changed(namespace, s.Servers)
+			c.remove(k)
+		}
+	}
+}
+
+// changed calls onChange with the servers of a changed service. It has to be called with
+// lock held.
+func (c *serviceCache) changed(namespace string, servers ...[]string) {
+	if c.onChange == nil {
+		return
+	}
+
+	all := []string{}
+
+	for _, s := range servers {
+		all = append(all, s...)
+	}
+
+	c.onChange(namespace, all)
+}
+
+// list returns all services in namespace. If namespace is empty string, all services are
+// returned. The services are sorted by namespace and id.
+func (c *serviceCache) list(namespace string) discovery.Services {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	services := discovery.Services{}
+
+	for _, s := range c.services {
+		if namespace == "" || s.Namespace == namespace {
+			services = append(services, s)
+		}
+	}
+
+	sortByKey(services)
+
+	return services
+}
+
+// listByServer returns all services of server in namespace. If namespace is empty
+// string, the services of all namespaces are returned.
+func (c *serviceCache) listByServer(server, namespace string) discovery.Services {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	services := discovery.Services{}
+
+	for k := range c.servers[server] {
+		s := c.services[k]
+
+		if namespace == "" || s.Namespace == namespace {
+			services = append(services, s)
+		}
+	}
+
+	sortByKey(services)
+
+	return services
+}
+
+// listByServers returns all services in namespace of the servers for which match returns
+// true. If namespace is empty string, the services of all namespaces are returned.
+func (c *serviceCache) listByServers(match func(string) bool, namespace string) discovery.Services {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	keys := map[string]struct{}{}
+
+	for server, k := range c.servers {
+		if !match(server) {
+			continue
+		}
+
+		for key := range k {
+			keys[key] = struct{}{}
+		}
+	}
+
+	services := discovery.Services{}
+
+	for k := range keys {
+		s := c.services[k]
+
+		if namespace == "" || s.Namespace == namespace {
+			services = append(services, s)
+		}
+	}
+
+	sortByKey(services)
+
+	return services
+}
+
+// has returns true, if the service with key k is cached.
+func (c *serviceCache) has(k string) bool {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	_, ok := c.services[k]
+
+	return ok
+}
+
+// assigned returns true, if the service with key k is assigned to server.
+func (c *serviceCache) assigned(server, k string) bool {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	_, ok := c.servers[server][k]
+
+	return ok
+}
+
+// load returns the number of services assigned to server without the service with key exclude.
+func (c *serviceCache) load(server, exclude string) int {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	keys := c.servers[server]
+
+	if _, ok := keys[exclude]; ok {
+		return len(keys) - 1
+	}
+
+	return len(keys)
+}
+
+// add has to be called with lock held.
+func (c *serviceCache) add(s discovery.Service) {
+	k := cacheKey(s.Namespace, s.ID)
+
+	c.remove(k)
+	c.services[k] = s
+
+	for _, server := range s.Servers {
+		if _, ok := c.servers[server]; !ok {
+			c.servers[server] = map[string]struct{}{}
+		}
+
+		c.servers[server][k] = struct{}{}
+	}
+}
+
+// remove has to be called with lock held.
+func (c *serviceCache) remove(k string) {
+	s, ok := c.services[k]
+	if !ok {
+		return
+	}
+
+	for _, server := range s.Servers {
+		delete(c.servers[server], k)
+
+		if len(c.servers[server]) == 0 {
+			delete(c.servers, server)
+		}
+	}
+
+	delete(c.services, k)
+}
+
+func equalServers(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func cacheKey(namespace, id string) string {
+	return path.Join(namespace, id)
+}
+
+func sortByKey(s discovery.Services) {
+	sort.Slice(s, func(i, j int) bool {
+		return cacheKey(s[i].Namespace, s[i].ID) < cacheKey(s[j].Namespace, s[j].ID)
+	})
+}
@@ -0,0 +1,161 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/postfinance/discovery"
+	"github.com/postfinance/discovery/internal/repo"
+)
+
+// DefaultTrashRetention is the default duration unregistered namespaces can be restored.
+const DefaultTrashRetention = 7 * 24 * time.Hour
+
+// Option configures the registry.
+type Option func(*Registry)
+
+// WithTrashRetention sets the duration unregistered namespaces and their services are
+// kept in the trash. If d is 0, namespaces are deleted immediately.
+func WithTrashRetention(d time.Duration) Option {
+	return func(r *Registry) {
+		r.trashRetention = d
+	}
+}
+
+// ListDeletedNamespaces lists all unregistered namespaces that can be restored.
+func (r *Registry) ListDeletedNamespaces() (discovery.DeletedNamespaces, error) {
+	return r.trashRepo.List()
+}
+
+// RestoreNamespace restores an unregistered namespace with its services. The services are
+// assigned to the currently registered servers. Services that cannot be registered stay in
+// the trash. It returns the number of restored services.
+func (r *Registry) RestoreNamespace(name string) (*discovery.Namespace, int, error) {
+	d, services, err := r.trashRepo.Get(name)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	r.log.Infow("restore namespace", "name", name, "services", len(services))
+
+	ns, ok := r.namespaceCache.get(name)
+	if !ok {
+		n, err := r.RegisterNamespace(d.Namespace)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		ns = *n
+	}
+
+	_, errs := r.RegisterServices(services)
+
+	var (
+		failed   = discovery.Services{}
+		firstErr error
+	)
+
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+
+		if firstErr == nil {
+			firstErr = err
+		}
+
+		failed = append(failed, services[i])
+	}
+
+	restored := len(services) - len(failed)
+
+	if len(failed) > 0 {
+		d.Services = len(failed)
+
+		if err := r.trashRepo.Save(*d, failed); err != nil {
+			return &ns, restored, fmt.Errorf("failed to save not restored services of namespace %s: %w", name, err)
+		}
+
+		return &ns, restored, fmt.Errorf("%d services of namespace %s could not be restored: %w", len(failed), name, firstErr)
+	}
+
+	if err := r.trashRepo.Delete(name); err != nil {
+		return &ns, restored, fmt.Errorf("failed to delete namespace %s from trash: %w", name, err)
+	}
+
+	return &ns, restored, nil
+}
+
+// StartTrashPurger deletes expired namespaces from the trash every interval. It runs until
+// context ctx is canceled.
+func (r *Registry) StartTrashPurger(ctx context.Context, interval time.Duration) {
+	r.log.Infow("starting trash purger", "interval", interval, "retention", r.trashRetention)
+
+	ticker := time.NewTicker(interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.log.Info("stopping trash purger")
+
+			return
+		case <-ticker.C:
+			r.log.Debug("purging trash")
+
+			if err := r.purgeTrash(time.Now()); err != nil {
+				r.log.Errorw("failed to purge trash", "err", err)
+			}
+		}
+	}
+}
+
+// moveToTrash copies the namespace and its services to the trash.
+func (r *Registry) moveToTrash(name string) error {
+	ns, ok := r.namespaceCache.get(name)
+	if !ok {
+		return fmt.Errorf("namespace %s: %w", name, repo.ErrNotFound)
+	}
+
+	if r.trashRetention == 0 {
+		return nil
+	}
+
+	services, err := r.serviceRepo.List(name, "")
+	if err != nil {
+		return fmt.Errorf("failed to list services in namespace %s: %w", name, err)
+	}
+
+	now := time.Now()
+
+	d := discovery.DeletedNamespace{
+		Namespace: ns,
+		Services:  len(services),
+		Deleted:   now,
+		Expires:   now.Add(r.trashRetention),
+	}
+
+	if err := r.trashRepo.Save(d, services); err != nil {
+		return fmt.Errorf("failed to move namespace %s to trash: %w", name, err)
+	}
+
+	return nil
+}
+
+// purgeTrash deletes all namespaces from the trash that expired before t.
+func (r *Registry) purgeTrash(t time.Time) error {
+	namespaces, err := r.trashRepo.List()
+	if err != nil {
+		return err
+	}
+
+	for _, d := range namespaces.Expired(t) {
+		r.log.Infow("purge namespace", "name", d.Namespace.Name, "deleted", d.Deleted)
+
+		if err := r.trashRepo.Delete(d.Namespace.Name); err != nil {
+			return fmt.Errorf("failed to purge namespace %s: %w", d.Namespace.Name, err)
+		}
+	}
+
+	return nil
+}
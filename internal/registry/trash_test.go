@@ -0,0 +1,92 @@
+package registry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/postfinance/discovery"
+	"github.com/postfinance/discovery/internal/repo"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTrash(t *testing.T) {
+	c := newBackend(t)
+
+	r, err := New(c, prometheus.NewRegistry(), zap.NewNop().Sugar(), 1, WithTrashRetention(time.Hour))
+	require.NoError(t, err)
+
+	_, err = r.RegisterServer("server1", nil)
+	require.NoError(t, err)
+
+	_, err = r.RegisterNamespace(discovery.Namespace{Name: "ns1", Export: discovery.Blackbox})
+	require.NoError(t, err)
+
+	for _, ep := range []string{"http://host1:8080/metrics", "http://host2:8080/metrics"} {
+		s := discovery.MustNewService("svc", ep)
+		s.Namespace = "ns1"
+		s.TTL = time.Minute
+
+		_, err := r.RegisterService(*s)
+		require.NoError(t, err)
+	}
+
+	t.Run("unregister", func(t *testing.T) {
+		require.NoError(t, r.UnRegisterNamespace("ns1"))
+
+		services, err := r.ListService("ns1", "")
+		require.NoError(t, err)
+		assert.Empty(t, services)
+
+		deleted, err := r.ListDeletedNamespaces()
+		require.NoError(t, err)
+		require.Len(t, deleted, 1)
+		assert.Equal(t, "ns1", deleted[0].Namespace.Name)
+		assert.Equal(t, 2, deleted[0].Services)
+	})
+
+	t.Run("unregister unknown", func(t *testing.T) {
+		assert.ErrorIs(t, r.UnRegisterNamespace("unknown"), repo.ErrNotFound)
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		ns, count, err := r.RestoreNamespace("ns1")
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+		assert.Equal(t, discovery.Blackbox, ns.Export)
+
+		services, err := r.ListService("ns1", "")
+		require.NoError(t, err)
+		require.Len(t, services, 2)
+		assert.Equal(t, []string{"server1"}, services[0].Servers)
+
+		leases, err := r.leaseRepo.List("ns1")
+		require.NoError(t, err)
+		assert.Len(t, leases, 2)
+
+		deleted, err := r.ListDeletedNamespaces()
+		require.NoError(t, err)
+		assert.Empty(t, deleted)
+
+		_, _, err = r.RestoreNamespace("ns1")
+		assert.ErrorIs(t, err, repo.ErrNotFound)
+	})
+
+	t.Run("purge", func(t *testing.T) {
+		require.NoError(t, r.UnRegisterNamespace("ns1"))
+
+		require.NoError(t, r.purgeTrash(time.Now()))
+
+		deleted, err := r.ListDeletedNamespaces()
+		require.NoError(t, err)
+		assert.Len(t, deleted, 1)
+
+		require.NoError(t, r.purgeTrash(time.Now().Add(2*time.Hour)))
+
+		deleted, err = r.ListDeletedNamespaces()
+		require.NoError(t, err)
+		assert.Empty(t, deleted)
+	})
+}
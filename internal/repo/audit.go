@@ -0,0 +1,108 @@
+package repo
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/postfinance/discovery"
+	"github.com/postfinance/store"
+)
+
+// Audit represents the audit record repository. Records are stored
+// in chronological order.
+type Audit struct {
+	backend store.Backend
+	prefix  string
+}
+
+// NewAudit creates a new audit repo.
+func NewAudit(backend store.Backend) *Audit {
+	return &Audit{
+		backend: backend,
+		prefix:  auditPrefix,
+	}
+}
+
+// Save saves a new audit record. The id of the record is generated from its time.
+func (a *Audit) Save(record discovery.AuditRecord) (*discovery.AuditRecord, error) {
+	suffix := make([]byte, 4)
+
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+
+	// zero padded to sort lexicographically
+	record.ID = fmt.Sprintf("%020d-%s", record.Time.UnixNano(), hex.EncodeToString(suffix))
+
+	if _, err := store.Put(a.backend, path.Join(a.prefix, record.ID), record); err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// List lists all audit records not older than since.
+func (a *Audit) List(since time.Time) (discovery.AuditRecords, error) {
+	records := discovery.AuditRecords{}
+
+	_, err := a.backend.Get(a.prefix+"/", store.WithPrefix(), store.WithHandler(func(k, v []byte) error {
+		record := discovery.AuditRecord{}
+
+		if err := json.Unmarshal(v, &record); err != nil {
+			return err
+		}
+
+		if !record.Time.Before(since) {
+			records = append(records, record)
+		}
+
+		return nil
+	}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ID < records[j].ID
+	})
+
+	return records, nil
+}
+
+// DeleteBefore deletes all audit records older than t. It returns the number
+// of deleted records.
+func (a *Audit) DeleteBefore(t time.Time) (int, error) {
+	keys := []string{}
+
+	_, err := a.backend.Get(a.prefix+"/", store.WithPrefix(), store.WithHandler(func(k, v []byte) error {
+		record := discovery.AuditRecord{}
+
+		if err := json.Unmarshal(v, &record); err != nil {
+			return err
+		}
+
+		if record.Time.Before(t) {
+			keys = append(keys, path.Join(a.prefix, record.ID))
+		}
+
+		return nil
+	}))
+
+	if err != nil {
+		return 0, err
+	}
+
+	for _, k := range keys {
+		if _, err := a.backend.Del(k); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(keys), nil
+}
@@ -0,0 +1,49 @@
+package repo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/postfinance/discovery"
+	"github.com/postfinance/store/hash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudit(t *testing.T) {
+	c, err := hash.New(hash.WithPrefix("/discovery"))
+	require.NoError(t, err)
+
+	r := NewAudit(c)
+	now := time.Now()
+
+	for i := 3; i > 0; i-- {
+		_, err := r.Save(discovery.AuditRecord{
+			Time:   now.Add(-time.Duration(i) * time.Hour),
+			Method: "/postfinance.discovery.v1.ServerAPI/RegisterServer",
+			User:   "admin",
+		})
+		require.NoError(t, err)
+	}
+
+	t.Run("list", func(t *testing.T) {
+		records, err := r.List(time.Time{})
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+		assert.True(t, records[0].Time.Before(records[1].Time))
+
+		records, err = r.List(now.Add(-90 * time.Minute))
+		require.NoError(t, err)
+		assert.Len(t, records, 1)
+	})
+
+	t.Run("delete before", func(t *testing.T) {
+		n, err := r.DeleteBefore(now.Add(-150 * time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		records, err := r.List(time.Time{})
+		require.NoError(t, err)
+		assert.Len(t, records, 2)
+	})
+}
@@ -0,0 +1,167 @@
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/postfinance/discovery"
+	"github.com/postfinance/store"
+)
+
+// maxRenewAttempts is the number of times a concurrently changed lease is renewed again.
+const maxRenewAttempts = 3
+
+// Lease represents the lease repository.
+type Lease struct {
+	backend store.Backend
+	prefix  string
+}
+
+// NewLease creates a new lease repo.
+func NewLease(backend store.Backend) *Lease {
+	return &Lease{
+		backend: backend,
+		prefix:  leasePrefix,
+	}
+}
+
+// Save creates or updates a lease.
+func (l *Lease) Save(lease discovery.Lease) (*discovery.Lease, error) {
+	if _, err := store.Put(l.backend, l.key(lease.Namespace, lease.ID), lease); err != nil {
+		return nil, err
+	}
+
+	return &lease, nil
+}
+
+// Get gets a lease by service id and namespace.
+func (l *Lease) Get(id, namespace string) (*discovery.Lease, error) {
+	var lease discovery.Lease
+
+	_, err := l.backend.Get(l.key(namespace, id), store.WithHandler(func(k, v []byte) error {
+		return json.Unmarshal(v, &lease)
+	}))
+
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, fmt.Errorf("%s/%s: %w", namespace, id, ErrNotFound)
+		}
+
+		return nil, err
+	}
+
+	return &lease, nil
+}
+
+// Renew renews an existing lease by service id and namespace. The renewed lease is only
+// written, if the lease was not changed or deleted in the meantime, otherwise the renewal
+// is retried. If the lease does not exist (anymore), ErrNotFound is returned. If the lease
+// is still changed concurrently after maxRenewAttempts, ErrConflict is returned.
+func (l *Lease) Renew(id, namespace string) (*discovery.Lease, error) {
+	for attempt := 1; ; attempt++ {
+		lease, ok, err := l.renew(id, namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			return lease, nil
+		}
+
+		if attempt == maxRenewAttempts {
+			return nil, fmt.Errorf("lease %s/%s: %w", namespace, id, ErrConflict)
+		}
+	}
+}
+
+// renew renews a lease. It returns false, if the lease was changed concurrently.
+func (l *Lease) renew(id, namespace string) (*discovery.Lease, bool, error) {
+	var current []byte
+
+	_, err := l.backend.Get(l.key(namespace, id), store.WithHandler(func(k, v []byte) error {
+		current = v
+		return nil
+	}))
+
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, false, fmt.Errorf("%s/%s: %w", namespace, id, ErrNotFound)
+		}
+
+		return nil, false, err
+	}
+
+	lease := discovery.Lease{}
+	if err := json.Unmarshal(current, &lease); err != nil {
+		return nil, false, err
+	}
+
+	lease.Renew()
+
+	v, err := json.Marshal(lease)
+	if err != nil {
+		return nil, false, err
+	}
+
+	ok, err := txn(l.backend, []store.Entry{{Key: l.key(namespace, id), Value: current}}, []store.Entry{{Key: l.key(namespace, id), Value: v}}, nil)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	return &lease, true, nil
+}
+
+// Delete removes a lease from repo.
+func (l *Lease) Delete(id, namespace string) error {
+	count, err := l.backend.Del(l.key(namespace, id))
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return fmt.Errorf("%s/%s: %w", namespace, id, ErrNotFound)
+	}
+
+	return nil
+}
+
+// List lists all leases in namespace. If namespace is empty string, all
+// leases are returned.
+func (l *Lease) List(namespace string) (discovery.Leases, error) {
+	leases := discovery.Leases{}
+
+	_, err := l.backend.Get(path.Join(l.prefix, namespace)+"/", store.WithPrefix(), store.WithHandler(func(k, v []byte) error {
+		lease := discovery.Lease{}
+
+		if err := json.Unmarshal(v, &lease); err != nil {
+			return err
+		}
+
+		leases = append(leases, lease)
+
+		return nil
+	}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return leases, nil
+}
+
+// DeleteFromNamespace deletes all leases in namespace.
+func (l *Lease) DeleteFromNamespace(namespace string) error {
+	if namespace == "" {
+		return errors.New("namespace cannot be empty string")
+	}
+
+	_, err := l.backend.Del(path.Join(l.prefix, namespace), store.WithPrefix())
+
+	return err
+}
+
+func (l *Lease) key(namespace, id string) string {
+	return path.Join(l.prefix, namespace, id)
+}
@@ -0,0 +1,87 @@
+package repo
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/postfinance/discovery"
+	"github.com/postfinance/discovery/internal/bolt"
+	"github.com/postfinance/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLease(t *testing.T) {
+	c := newBackend(t)
+
+	r := NewLease(c)
+
+	t.Run("save", func(t *testing.T) {
+		_, err := r.Save(*discovery.NewLease("id1", "default", time.Minute))
+		assert.NoError(t, err)
+		_, err = r.Save(*discovery.NewLease("id2", "default", -time.Minute))
+		assert.NoError(t, err)
+		_, err = r.Save(*discovery.NewLease("id3", "other", time.Minute))
+		assert.NoError(t, err)
+	})
+
+	t.Run("get", func(t *testing.T) {
+		l, err := r.Get("id1", "default")
+		require.NoError(t, err)
+		assert.Equal(t, time.Minute, l.TTL)
+
+		_, err = r.Get("id1", "other")
+		assert.True(t, errors.Is(err, ErrNotFound))
+	})
+
+	t.Run("renew", func(t *testing.T) {
+		before, err := r.Get("id1", "default")
+		require.NoError(t, err)
+
+		l, err := r.Renew("id1", "default")
+		require.NoError(t, err)
+		assert.True(t, l.Expires.After(before.Expires))
+
+		_, err = r.Renew("id4", "default")
+		assert.True(t, errors.Is(err, ErrNotFound), "deleted leases are not created again")
+
+		_, err = r.Get("id4", "default")
+		assert.True(t, errors.Is(err, ErrNotFound))
+
+		_, err = NewLease(conflictBackend{c}).Renew("id1", "default")
+		assert.ErrorIs(t, err, ErrConflict, "changed on every attempt")
+	})
+
+	t.Run("list", func(t *testing.T) {
+		leases, err := r.List("")
+		require.NoError(t, err)
+		assert.Len(t, leases, 3)
+		assert.Len(t, leases.Expired(time.Now()), 1)
+
+		leases, err = r.List("default")
+		require.NoError(t, err)
+		assert.Len(t, leases, 2)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.NoError(t, r.Delete("id2", "default"))
+		assert.True(t, errors.Is(r.Delete("id2", "default"), ErrNotFound))
+	})
+
+	t.Run("delete from namespace", func(t *testing.T) {
+		assert.NoError(t, r.DeleteFromNamespace("other"))
+		leases, err := r.List("")
+		require.NoError(t, err)
+		assert.Len(t, leases, 1)
+	})
+}
+
+// conflictBackend fails every transaction as if the compared keys were changed concurrently.
+type conflictBackend struct {
+	*bolt.Backend
+}
+
+func (conflictBackend) Txn([]store.Entry, []store.Entry, []string) (bool, error) {
+	return false, nil
+}
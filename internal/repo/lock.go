@@ -0,0 +1,99 @@
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+	"path"
+	"time"
+
+	"github.com/postfinance/store"
+)
+
+// Lock represents the repository of locks, which are held by one discovery server
+// at a time. A lock expires, if it is not renewed by its owner.
+type Lock struct {
+	backend store.Backend
+	prefix  string
+	owner   string
+}
+
+type lockState struct {
+	Owner   string    `json:"owner"`
+	Expires time.Time `json:"expires"`
+}
+
+// NewLock creates a new lock repo for owner. Owner has to be unique for every
+// discovery server.
+func NewLock(backend store.Backend, owner string) *Lock {
+	return &Lock{
+		backend: backend,
+		prefix:  lockPrefix,
+		owner:   owner,
+	}
+}
+
+// Acquire acquires or renews lock name for ttl. It returns false, if the lock is held
+// by another owner and has not expired.
+func (l *Lock) Acquire(name string, ttl time.Duration) (bool, error) {
+	current, state, err := l.get(name)
+	if err != nil {
+		return false, err
+	}
+
+	if state != nil && state.Owner != l.owner && time.Now().Before(state.Expires) {
+		return false, nil
+	}
+
+	v, err := json.Marshal(lockState{
+		Owner:   l.owner,
+		Expires: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	// the lock is only written, if nobody else acquired it in the meantime
+	return txn(l.backend, []store.Entry{{Key: l.key(name), Value: current}}, []store.Entry{{Key: l.key(name), Value: v}}, nil)
+}
+
+// Release releases lock name, if it is held by the owner.
+func (l *Lock) Release(name string) error {
+	current, state, err := l.get(name)
+	if err != nil || state == nil || state.Owner != l.owner {
+		return err
+	}
+
+	_, err = txn(l.backend, []store.Entry{{Key: l.key(name), Value: current}}, nil, []string{l.key(name)})
+
+	return err
+}
+
+// get returns the stored value and the state of lock name. Both are nil, if the lock
+// does not exist.
+func (l *Lock) get(name string) ([]byte, *lockState, error) {
+	var current []byte
+
+	_, err := l.backend.Get(l.key(name), store.WithHandler(func(k, v []byte) error {
+		current = v
+		return nil
+	}))
+
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil, nil
+		}
+
+		return nil, nil, err
+	}
+
+	state := lockState{}
+	if err := json.Unmarshal(current, &state); err != nil {
+		return nil, nil, err
+	}
+
+	return current, &state, nil
+}
+
+func (l *Lock) key(name string) string {
+	return path.Join(l.prefix, name)
+}
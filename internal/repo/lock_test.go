@@ -0,0 +1,71 @@
+package repo
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/postfinance/discovery/internal/bolt"
+	"github.com/postfinance/store/hash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLock(t *testing.T) {
+	c := newBackend(t)
+
+	l1 := NewLock(c, "owner1")
+	l2 := NewLock(c, "owner2")
+
+	ok, err := l1.Acquire("test", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = l1.Acquire("test", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok, "renewed by the owner")
+
+	ok, err = l2.Acquire("test", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok, "held by owner1")
+
+	ok, err = l2.Acquire("other", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok, "other lock")
+
+	require.NoError(t, l2.Release("test"))
+
+	ok, err = l2.Acquire("test", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok, "only released by the owner")
+
+	require.NoError(t, l1.Release("test"))
+
+	ok, err = l2.Acquire("test", -time.Second)
+	require.NoError(t, err)
+	assert.True(t, ok, "released")
+
+	ok, err = l1.Acquire("test", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok, "expired")
+}
+
+// newBackend returns a bolt backend, because it supports transactions.
+func newBackend(t *testing.T) *bolt.Backend {
+	t.Helper()
+
+	b, err := bolt.New(filepath.Join(t.TempDir(), "discovery.db"))
+	require.NoError(t, err)
+
+	t.Cleanup(func() { b.Close() })
+
+	return b
+}
+
+func TestLockWithoutTxn(t *testing.T) {
+	c, err := hash.New(hash.WithPrefix("/discovery"))
+	require.NoError(t, err)
+
+	_, err = NewLock(c, "owner1").Acquire("test", time.Minute)
+	assert.ErrorIs(t, err, ErrNoTxn)
+}
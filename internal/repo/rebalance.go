@@ -0,0 +1,62 @@
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+	"path"
+
+	"github.com/postfinance/discovery"
+	"github.com/postfinance/store"
+)
+
+// Rebalance represents the repository of the state of a running redistribution.
+type Rebalance struct {
+	backend store.Backend
+	prefix  string
+}
+
+// NewRebalance creates a new rebalance repo.
+func NewRebalance(backend store.Backend) *Rebalance {
+	return &Rebalance{
+		backend: backend,
+		prefix:  rebalancePrefix,
+	}
+}
+
+// Save saves the state of a running redistribution.
+func (r *Rebalance) Save(state discovery.Rebalance) error {
+	_, err := store.Put(r.backend, r.key(), state)
+
+	return err
+}
+
+// Get gets the state of a running redistribution. If no redistribution is running,
+// ErrNotFound is returned.
+func (r *Rebalance) Get() (*discovery.Rebalance, error) {
+	state := discovery.Rebalance{}
+
+	_, err := r.backend.Get(r.key(), store.WithHandler(func(k, v []byte) error {
+		return json.Unmarshal(v, &state)
+	}))
+
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+// Delete deletes the state of a finished redistribution.
+func (r *Rebalance) Delete() error {
+	_, err := r.backend.Del(r.key())
+
+	return err
+}
+
+func (r *Rebalance) key() string {
+	return path.Join(r.prefix, "state")
+}
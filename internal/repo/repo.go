@@ -7,4 +7,10 @@ const (
 	namespacePrefix = "namespace/v1"
 	serverPrefix    = "server/v1"
 	servicePrefix   = "service/v1"
+	leasePrefix     = "lease/v1"
+	trashPrefix     = "trash/v1"
+	auditPrefix     = "audit/v1"
+	tokenPrefix     = "token/v1"
+	rebalancePrefix = "rebalance/v1"
+	lockPrefix      = "lock/v1"
 )
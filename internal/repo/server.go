@@ -16,6 +16,7 @@ import (
 // Common errors
 var (
 	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("changed concurrently")
 )
 
 // Server represents the server repository.
@@ -1,6 +1,7 @@
 package repo
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -81,6 +82,122 @@ func (s *Service) Save(svc discovery.Service) (*discovery.Service, error) {
 	return &svc, nil
 }
 
+// SaveAll creates or updates services in one transaction. It returns the services with the
+// generated ids in the same order as services.
+func (s *Service) SaveAll(services discovery.Services) (discovery.Services, error) {
+	puts := make([]store.Entry, 0, len(services))
+	dels := []string{}
+	saved := make(discovery.Services, 0, len(services))
+
+	for _, svc := range services {
+		newID := s.idGen(svc.Endpoint.String())
+
+		// check if endpoint got changed
+		if svc.ID != "" && newID != svc.ID {
+			dels = append(dels, s.key(svc.Namespace, svc.ID))
+		}
+
+		svc.ID = newID
+		svc.Modified = time.Now()
+
+		v, err := json.Marshal(svc)
+		if err != nil {
+			return nil, err
+		}
+
+		puts = append(puts, store.Entry{Key: s.key(svc.Namespace, svc.ID), Value: v})
+		saved = append(saved, svc)
+	}
+
+	ok, err := txn(s.backend, nil, puts, dels)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, errors.New("transaction without comparisons failed")
+	}
+
+	return saved, nil
+}
+
+// SaveBatch saves services in one transaction, if the stored services are still equal to
+// prev. The services must not change their endpoints. It returns the saved services and
+// false without saving anything, if a service was changed or deleted since prev was read.
+func (s *Service) SaveBatch(services, prev discovery.Services) (discovery.Services, bool, error) {
+	if len(services) != len(prev) {
+		return nil, false, errors.New("number of services and previous services differ")
+	}
+
+	cmps := make([]store.Entry, 0, len(prev))
+	puts := make([]store.Entry, 0, len(services))
+	saved := make(discovery.Services, 0, len(services))
+
+	for i := range services {
+		// the transaction compares the stored bytes, prev is compared after decoding them,
+		// so that the comparison does not depend on how prev was encoded
+		current, ok, err := s.unchanged(prev[i])
+		if err != nil || !ok {
+			return nil, false, err
+		}
+
+		cmps = append(cmps, store.Entry{Key: s.key(prev[i].Namespace, prev[i].ID), Value: current})
+
+		svc := services[i]
+		svc.Modified = time.Now()
+
+		v, err := json.Marshal(svc)
+		if err != nil {
+			return nil, false, err
+		}
+
+		puts = append(puts, store.Entry{Key: s.key(svc.Namespace, svc.ID), Value: v})
+		saved = append(saved, svc)
+	}
+
+	ok, err := txn(s.backend, cmps, puts, nil)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+
+	return saved, true, nil
+}
+
+// unchanged returns the stored value of service prev and whether it is still equal to prev.
+func (s *Service) unchanged(prev discovery.Service) ([]byte, bool, error) {
+	var current []byte
+
+	_, err := s.backend.Get(s.key(prev.Namespace, prev.ID), store.WithHandler(func(k, v []byte) error {
+		current = v
+		return nil
+	}))
+
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	var svc discovery.Service
+	if err := json.Unmarshal(current, &svc); err != nil {
+		return nil, false, err
+	}
+
+	a, err := json.Marshal(svc)
+	if err != nil {
+		return nil, false, err
+	}
+
+	b, err := json.Marshal(prev)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return current, bytes.Equal(a, b), nil
+}
+
 // Delete removes a service from repo.
 func (s *Service) Delete(id, namespace string) error {
 	count, err := s.backend.Del(s.key(namespace, id))
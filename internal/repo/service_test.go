@@ -83,3 +83,34 @@ func TestService(t *testing.T) {
 		assert.Len(t, servers, 0)
 	})
 }
+
+func TestSaveBatch(t *testing.T) {
+	r := NewService(newBackend(t))
+
+	prev, err := r.Save(*discovery.MustNewService("test", "http://example.com/metrics"))
+	require.NoError(t, err)
+
+	svc := *prev
+	svc.Servers = []string{"server1"}
+
+	saved, ok, err := r.SaveBatch(discovery.Services{svc}, discovery.Services{*prev})
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, []string{"server1"}, saved[0].Servers)
+
+	svc.Servers = []string{"server2"}
+
+	_, ok, err = r.SaveBatch(discovery.Services{svc}, discovery.Services{*prev})
+	require.NoError(t, err)
+	assert.False(t, ok, "changed since prev was read")
+
+	stored, err := r.Get(prev.ID, prev.Namespace)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"server1"}, stored.Servers)
+
+	require.NoError(t, r.Delete(prev.ID, prev.Namespace))
+
+	_, ok, err = r.SaveBatch(discovery.Services{svc}, discovery.Services{saved[0]})
+	require.NoError(t, err)
+	assert.False(t, ok, "deleted since prev was read")
+}
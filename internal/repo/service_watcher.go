@@ -31,9 +31,21 @@ func (s *Service) Chan(ctx context.Context, errorHandler func(error)) <-chan *Se
 		return s.w.c
 	}
 
-	c := make(chan *ServiceEvent, 1)
-	s.w = &serviceWatcher{
-		c: c,
+	s.w = s.watch(ctx, errorHandler)
+
+	return s.w.c
+}
+
+// Watch returns a new read-only channel of service events. Unlike Chan, every call
+// starts a separate watcher. The channel is closed when ctx is canceled.
+func (s *Service) Watch(ctx context.Context, errorHandler func(error)) <-chan *ServiceEvent {
+	return s.watch(ctx, errorHandler).c
+}
+
+func (s *Service) watch(ctx context.Context, errorHandler func(error)) *serviceWatcher {
+	w := &serviceWatcher{
+		ctx: ctx,
+		c:   make(chan *ServiceEvent, 1),
 	}
 
 	watchReady := make(chan struct{})
@@ -42,7 +54,7 @@ func (s *Service) Chan(ctx context.Context, errorHandler func(error)) <-chan *Se
 	}
 
 	go func() {
-		if err := s.backend.Watch(s.prefix, s.w,
+		if err := s.backend.Watch(s.prefix, w,
 			store.WithContext(ctx),
 			store.WithNotifyCreated(notifyCreated),
 			store.WithPrefix(),
@@ -53,11 +65,12 @@ func (s *Service) Chan(ctx context.Context, errorHandler func(error)) <-chan *Se
 
 	<-watchReady
 
-	return c
+	return w
 }
 
 type serviceWatcher struct {
-	c chan *ServiceEvent
+	ctx context.Context
+	c   chan *ServiceEvent
 }
 
 // OnPut implements Watcher interface.
@@ -67,7 +80,7 @@ func (w serviceWatcher) OnPut(k, v []byte) error {
 		return err
 	}
 
-	w.c <- e
+	w.send(e)
 
 	return nil
 }
@@ -79,7 +92,7 @@ func (w serviceWatcher) OnDelete(k, v []byte) error {
 		return err
 	}
 
-	w.c <- e
+	w.send(e)
 
 	return nil
 }
@@ -101,6 +114,15 @@ func (w serviceWatcher) OnDone() error {
 	return nil
 }
 
+// send sends e unless the watch context is canceled, so that a watcher
+// without reader does not block forever.
+func (w serviceWatcher) send(e *ServiceEvent) {
+	select {
+	case w.c <- e:
+	case <-w.ctx.Done():
+	}
+}
+
 func (w serviceWatcher) onChange(k, v []byte) (*ServiceEvent, error) {
 	s := discovery.Service{}
 
@@ -42,3 +42,33 @@ func TestServiceChan(t *testing.T) {
 	assert.Equal(t, Delete, e.Event)
 	assert.NotEmpty(t, e.ID)
 }
+
+func TestServiceWatch(t *testing.T) {
+	c, err := hash.New(hash.WithPrefix("/discovery"))
+	require.NoError(t, err)
+
+	r := NewService(c)
+
+	errHandler := func(err error) {
+		require.NoError(t, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	ch1 := r.Watch(ctx, errHandler)
+	ch2 := r.Watch(context.Background(), errHandler)
+
+	_, err = r.Save(*discovery.MustNewService("test", "http://example.com/metrics"))
+	require.NoError(t, err)
+
+	e := <-ch1
+	assert.Equal(t, Change, e.Event)
+
+	e = <-ch2
+	assert.Equal(t, Change, e.Event)
+
+	cancel()
+
+	_, ok := <-ch1
+	assert.False(t, ok)
+}
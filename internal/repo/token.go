@@ -0,0 +1,95 @@
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/postfinance/discovery"
+	"github.com/postfinance/store"
+)
+
+// Token represents the repository of issued machine tokens.
+type Token struct {
+	backend store.Backend
+	prefix  string
+	w       *tokenWatcher
+}
+
+// NewToken creates a new token repo.
+func NewToken(backend store.Backend) *Token {
+	return &Token{
+		backend: backend,
+		prefix:  tokenPrefix,
+	}
+}
+
+// Save creates or updates a token.
+func (t *Token) Save(token discovery.Token) (*discovery.Token, error) {
+	if _, err := store.Put(t.backend, t.key(token.TokenID), token); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// Get gets a token by its token id.
+func (t *Token) Get(tokenID string) (*discovery.Token, error) {
+	token := discovery.Token{}
+
+	_, err := t.backend.Get(t.key(tokenID), store.WithHandler(func(k, v []byte) error {
+		return json.Unmarshal(v, &token)
+	}))
+
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, fmt.Errorf("%s: %w", tokenID, ErrNotFound)
+		}
+
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// List lists all tokens.
+func (t *Token) List() (discovery.Tokens, error) {
+	tokens := discovery.Tokens{}
+
+	_, err := t.backend.Get(t.prefix+"/", store.WithPrefix(), store.WithHandler(func(k, v []byte) error {
+		token := discovery.Token{}
+
+		if err := json.Unmarshal(v, &token); err != nil {
+			return err
+		}
+
+		tokens = append(tokens, token)
+
+		return nil
+	}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (t *Token) key(tokenID string) string {
+	return path.Join(t.prefix, tokenID)
+}
+
+func tokenFromKey(key []byte) (*discovery.Token, error) {
+	k := string(key)
+	fields := strings.Split(k, "/")
+
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("invalid token path: %s", k)
+	}
+
+	return &discovery.Token{
+		TokenID: fields[len(fields)-1],
+	}, nil
+}
@@ -0,0 +1,55 @@
+package repo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/postfinance/discovery"
+	"github.com/postfinance/store/hash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToken(t *testing.T) {
+	c, err := hash.New(hash.WithPrefix("/discovery"))
+	require.NoError(t, err)
+
+	r := NewToken(c)
+
+	for _, id := range []string{"a1", "b2"} {
+		_, err := r.Save(discovery.Token{
+			TokenID:    id,
+			ID:         "machine-" + id,
+			Namespaces: []string{"default"},
+			IssuedAt:   time.Now(),
+		})
+		require.NoError(t, err)
+	}
+
+	t.Run("get", func(t *testing.T) {
+		tok, err := r.Get("a1")
+		require.NoError(t, err)
+		assert.Equal(t, "machine-a1", tok.ID)
+		assert.False(t, tok.IsRevoked())
+
+		_, err = r.Get("notexisting")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		tok, err := r.Get("b2")
+		require.NoError(t, err)
+
+		tok.RevokedAt = time.Now()
+		_, err = r.Save(*tok)
+		require.NoError(t, err)
+
+		tokens, err := r.List()
+		require.NoError(t, err)
+		require.Len(t, tokens, 2)
+
+		revoked := tokens.Revoked()
+		require.Len(t, revoked, 1)
+		assert.Equal(t, "b2", revoked[0].TokenID)
+	})
+}
@@ -0,0 +1,117 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/postfinance/discovery"
+	"github.com/postfinance/store"
+)
+
+// TokenEvent contains the token and the event (change or delete).
+type TokenEvent struct {
+	discovery.Token
+	Event Event
+}
+
+// Chan returns read-only channel of token events.
+func (t *Token) Chan(ctx context.Context, errorHandler func(error)) <-chan *TokenEvent {
+	if t.w != nil {
+		return t.w.c
+	}
+
+	c := make(chan *TokenEvent, 1)
+	t.w = &tokenWatcher{
+		c: c,
+	}
+
+	watchReady := make(chan struct{})
+	notifyCreated := func() {
+		close(watchReady)
+	}
+
+	go func() {
+		if err := t.backend.Watch(t.prefix, t.w,
+			store.WithContext(ctx),
+			store.WithNotifyCreated(notifyCreated),
+			store.WithPrefix(),
+		); err != nil {
+			errorHandler(err)
+		}
+	}()
+
+	<-watchReady
+
+	return c
+}
+
+type tokenWatcher struct {
+	c chan *TokenEvent
+}
+
+// OnPut implements Watcher interface.
+func (w tokenWatcher) OnPut(k, v []byte) error {
+	e, err := w.onChange(k, v)
+	if err != nil {
+		return err
+	}
+
+	w.c <- e
+
+	return nil
+}
+
+// OnDelete implements Watcher interface.
+func (w tokenWatcher) OnDelete(k, v []byte) error {
+	e, err := w.onDelete(k)
+	if err != nil {
+		return err
+	}
+
+	w.c <- e
+
+	return nil
+}
+
+// BeforeWatch implements Watcher interface.
+func (w tokenWatcher) BeforeWatch() error {
+	return nil
+}
+
+// BeforeLoop implements Watcher interface.
+func (w tokenWatcher) BeforeLoop() error {
+	return nil
+}
+
+// OnDone implements Watcher interface.
+func (w tokenWatcher) OnDone() error {
+	close(w.c)
+
+	return nil
+}
+
+func (w tokenWatcher) onChange(k, v []byte) (*TokenEvent, error) {
+	t := discovery.Token{}
+
+	if err := json.Unmarshal(v, &t); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal token %s: %w", string(k), err)
+	}
+
+	return &TokenEvent{
+		Event: Change,
+		Token: t,
+	}, nil
+}
+
+func (w tokenWatcher) onDelete(k []byte) (*TokenEvent, error) {
+	t, err := tokenFromKey(k)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenEvent{
+		Event: Delete,
+		Token: *t,
+	}, nil
+}
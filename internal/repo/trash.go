@@ -0,0 +1,132 @@
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/postfinance/discovery"
+	"github.com/postfinance/store"
+)
+
+// Trash represents the repository of unregistered namespaces and their services.
+type Trash struct {
+	backend store.Backend
+	prefix  string
+}
+
+// NewTrash creates a new trash repo.
+func NewTrash(backend store.Backend) *Trash {
+	return &Trash{
+		backend: backend,
+		prefix:  trashPrefix,
+	}
+}
+
+// Save moves a deleted namespace and its services to the trash. A previously deleted
+// namespace with the same name is replaced.
+func (t *Trash) Save(namespace discovery.DeletedNamespace, services discovery.Services) error {
+	name := namespace.Namespace.Name
+
+	if err := t.Delete(name); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	for i := range services {
+		if _, err := store.Put(t.backend, t.serviceKey(name, services[i].ID), services[i]); err != nil {
+			return err
+		}
+	}
+
+	// the namespace is saved last, so that only complete entries are listed
+	_, err := store.Put(t.backend, t.namespaceKey(name), namespace)
+
+	return err
+}
+
+// Get gets a deleted namespace with its services by name.
+func (t *Trash) Get(name string) (*discovery.DeletedNamespace, discovery.Services, error) {
+	namespace := discovery.DeletedNamespace{}
+
+	_, err := t.backend.Get(t.namespaceKey(name), store.WithHandler(func(k, v []byte) error {
+		return json.Unmarshal(v, &namespace)
+	}))
+
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil, fmt.Errorf("%s: %w", name, ErrNotFound)
+		}
+
+		return nil, nil, err
+	}
+
+	services := discovery.Services{}
+
+	_, err = t.backend.Get(path.Join(t.prefix, "service", name)+"/", store.WithPrefix(), store.WithHandler(func(k, v []byte) error {
+		svc := discovery.Service{}
+
+		if err := json.Unmarshal(v, &svc); err != nil {
+			return err
+		}
+
+		services = append(services, svc)
+
+		return nil
+	}))
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &namespace, services, nil
+}
+
+// List lists all deleted namespaces.
+func (t *Trash) List() (discovery.DeletedNamespaces, error) {
+	namespaces := discovery.DeletedNamespaces{}
+
+	_, err := t.backend.Get(path.Join(t.prefix, "namespace")+"/", store.WithPrefix(), store.WithHandler(func(k, v []byte) error {
+		namespace := discovery.DeletedNamespace{}
+
+		if err := json.Unmarshal(v, &namespace); err != nil {
+			return err
+		}
+
+		namespaces = append(namespaces, namespace)
+
+		return nil
+	}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return namespaces, nil
+}
+
+// Delete removes a deleted namespace and its services from the trash.
+func (t *Trash) Delete(name string) error {
+	count, err := t.backend.Del(t.namespaceKey(name))
+	if err != nil {
+		return err
+	}
+
+	if _, err := t.backend.Del(path.Join(t.prefix, "service", name)+"/", store.WithPrefix()); err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return fmt.Errorf("%s: %w", name, ErrNotFound)
+	}
+
+	return nil
+}
+
+func (t *Trash) namespaceKey(name string) string {
+	return path.Join(t.prefix, "namespace", name)
+}
+
+func (t *Trash) serviceKey(namespace, id string) string {
+	return path.Join(t.prefix, "service", namespace, id)
+}
@@ -0,0 +1,57 @@
+package repo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/postfinance/discovery"
+	"github.com/postfinance/store/hash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrash(t *testing.T) {
+	c, err := hash.New(hash.WithPrefix("/discovery"))
+	require.NoError(t, err)
+
+	r := NewTrash(c)
+	d := discovery.DeletedNamespace{
+		Namespace: discovery.Namespace{Name: "ns1"},
+		Services:  1,
+		Deleted:   time.Now(),
+	}
+	s := discovery.MustNewService("svc", "http://host1:8080/metrics")
+	s.ID = "id1"
+	s.Namespace = "ns1"
+
+	t.Run("save", func(t *testing.T) {
+		require.NoError(t, r.Save(d, discovery.Services{*s}))
+		// a namespace with a similar name must not be affected
+		require.NoError(t, r.Save(discovery.DeletedNamespace{Namespace: discovery.Namespace{Name: "ns10"}}, nil))
+	})
+
+	t.Run("get", func(t *testing.T) {
+		n, services, err := r.Get("ns1")
+		require.NoError(t, err)
+		assert.Equal(t, 1, n.Services)
+		require.Len(t, services, 1)
+		assert.Equal(t, s.Endpoint.String(), services[0].Endpoint.String())
+
+		_, _, err = r.Get("ns2")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		l, err := r.List()
+		require.NoError(t, err)
+		assert.Len(t, l, 2)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, r.Delete("ns1"))
+		assert.ErrorIs(t, r.Delete("ns1"), ErrNotFound)
+
+		_, _, err := r.Get("ns10")
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,28 @@
+package repo
+
+import (
+	"errors"
+
+	"github.com/postfinance/store"
+)
+
+// ErrNoTxn is returned, if a store backend does not support transactions.
+var ErrNoTxn = errors.New("store backend does not support transactions")
+
+// Txn is implemented by store backends that write several entries atomically.
+type Txn interface {
+	// Txn puts entries and deletes keys, if the current values of the compared keys are
+	// equal to the values in cmps. A compared entry without value must not exist. It returns
+	// false without writing anything, if a comparison fails.
+	Txn(cmps, puts []store.Entry, dels []string) (bool, error)
+}
+
+// txn writes in one transaction. It fails with ErrNoTxn, if backend does not implement Txn.
+func txn(backend store.Backend, cmps, puts []store.Entry, dels []string) (bool, error) {
+	t, ok := backend.(Txn)
+	if !ok {
+		return false, ErrNoTxn
+	}
+
+	return t.Txn(cmps, puts, dels)
+}
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,7 +17,9 @@ import (
 	"github.com/postfinance/discovery/internal/repo"
 	"github.com/postfinance/discovery/internal/server/convert"
 	discoveryv1 "github.com/postfinance/discovery/pkg/discoverypb/postfinance/discovery/v1"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
@@ -25,13 +29,23 @@ type API struct {
 	discoveryv1.UnsafeServerAPIServer    // requires you to implement all gRPC services
 	discoveryv1.UnsafeServiceAPIServer   // requires you to implement all gRPC services
 	discoveryv1.UnsafeTokenAPIServer     // requires you to implement all gRPC services
+	discoveryv1.UnsafeAuditAPIServer     // requires you to implement all gRPC services
+	discoveryv1.UnsafeAuthAPIServer      // requires you to implement all gRPC services
 	r                                    *registry.Registry
 	tokenHandler                         *auth.TokenHandler
+	index                                *changeIndex
+	audit                                *repo.Audit
+	tokens                               *repo.Token
+	denyList                             *auth.DenyList
+	authorizer                           *auth.Authorizer
 }
 
 // RegisterServer registers a server.
 func (a *API) RegisterServer(_ context.Context, req *discoveryv1.RegisterServerRequest) (*discoveryv1.RegisterServerResponse, error) {
-	s, err := a.r.RegisterServer(req.GetName(), req.GetLabels())
+	s, err := a.r.RegisterServer(req.GetName(), req.GetLabels(),
+		registry.WithWeight(int(req.GetWeight())),
+		registry.WithMaxTargets(int(req.GetMaxTargets())),
+	)
 	if err != nil {
 		if registry.IsValidationError(err) {
 			return nil, status.Errorf(codes.InvalidArgument, "%s", err)
@@ -59,6 +73,55 @@ func (a *API) UnregisterServer(_ context.Context, req *discoveryv1.UnregisterSer
 	return &discoveryv1.UnregisterServerResponse{}, nil
 }
 
+// CordonServer cordons a server.
+func (a *API) CordonServer(_ context.Context, req *discoveryv1.CordonServerRequest) (*discoveryv1.CordonServerResponse, error) {
+	s, err := a.r.CordonServer(req.GetName())
+	if err != nil {
+		return nil, serverStateError("cordon", req.GetName(), err)
+	}
+
+	return &discoveryv1.CordonServerResponse{
+		Server: convert.ServerToPB(s),
+	}, nil
+}
+
+// UncordonServer makes a cordoned or draining server active again.
+func (a *API) UncordonServer(_ context.Context, req *discoveryv1.UncordonServerRequest) (*discoveryv1.UncordonServerResponse, error) {
+	s, err := a.r.UncordonServer(req.GetName())
+	if err != nil {
+		return nil, serverStateError("uncordon", req.GetName(), err)
+	}
+
+	return &discoveryv1.UncordonServerResponse{
+		Server: convert.ServerToPB(s),
+	}, nil
+}
+
+// DrainServer drains a server.
+func (a *API) DrainServer(_ context.Context, req *discoveryv1.DrainServerRequest) (*discoveryv1.DrainServerResponse, error) {
+	s, err := a.r.DrainServer(req.GetName(), int(req.GetRate()))
+	if err != nil {
+		return nil, serverStateError("drain", req.GetName(), err)
+	}
+
+	return &discoveryv1.DrainServerResponse{
+		Server: convert.ServerToPB(s),
+	}, nil
+}
+
+func serverStateError(action, name string, err error) error {
+	c := codes.Internal
+
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		c = codes.NotFound
+	case registry.IsValidationError(err):
+		c = codes.InvalidArgument
+	}
+
+	return status.Errorf(c, "could not %s server %s: %s", action, name, err)
+}
+
 // ListServer lists all servers.
 func (a *API) ListServer(_ context.Context, _ *discoveryv1.ListServerRequest) (*discoveryv1.ListServerResponse, error) {
 	s, err := a.r.ListServer("")
@@ -71,44 +134,153 @@ func (a *API) ListServer(_ context.Context, _ *discoveryv1.ListServerRequest) (*
 	}, nil
 }
 
+// Rebalance redistributes all services on the servers. With dry run, only the plan is returned.
+func (a *API) Rebalance(ctx context.Context, req *discoveryv1.RebalanceRequest) (*discoveryv1.RebalanceResponse, error) {
+	var (
+		plan *registry.Plan
+		err  error
+	)
+
+	if req.GetDryRun() {
+		plan, err = a.r.PlanRebalance()
+	} else {
+		plan, err = a.r.Rebalance(ctx)
+	}
+
+	if err != nil {
+		c := codes.Internal
+		if registry.IsLocked(err) {
+			c = codes.Aborted
+		}
+
+		return nil, status.Errorf(c, "could not rebalance services: %s", err)
+	}
+
+	return planToPB(plan), nil
+}
+
+func planToPB(p *registry.Plan) *discoveryv1.RebalanceResponse {
+	resp := &discoveryv1.RebalanceResponse{
+		Moved:  uint32(len(p.Moves)),
+		Failed: make([]string, 0, len(p.Failures)),
+	}
+
+	for _, m := range p.Servers() {
+		resp.Servers = append(resp.Servers, &discoveryv1.ServerMoves{
+			Server:  m.Name,
+			Added:   uint32(m.Added),
+			Removed: uint32(m.Removed),
+			Targets: uint32(m.Targets),
+		})
+	}
+
+	for _, f := range p.Failures {
+		resp.Failed = append(resp.Failed, fmt.Sprintf("%s/%s: %s", f.Service.Namespace, f.Service.ID, f.Err))
+	}
+
+	return resp
+}
+
 // RegisterService registers a service.
 func (a *API) RegisterService(ctx context.Context, req *discoveryv1.RegisterServiceRequest) (*discoveryv1.RegisterServiceResponse, error) {
 	if err := verifyUser(ctx, req.GetNamespace()); err != nil {
 		return nil, err
 	}
 
-	s, err := discovery.NewService(req.GetName(), req.GetEndpoint())
+	s, err := serviceFromRequest(req)
 	if err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, "service with endpoint %s is invalid: %s", req.GetEndpoint(), err)
+		return nil, err
 	}
 
-	s.Labels = req.GetLabels()
-	s.Description = req.GetDescription()
-	s.Selector = req.GetSelector()
+	svc, err := a.r.RegisterService(*s)
+	if err != nil {
+		return nil, registerServiceError(req, err)
+	}
 
-	if req.Namespace != "" {
-		s.Namespace = req.Namespace
+	return &discoveryv1.RegisterServiceResponse{
+		Service: convert.ServiceToPB(svc),
+	}, nil
+}
+
+// RegisterServices registers multiple services.
+func (a *API) RegisterServices(ctx context.Context, req *discoveryv1.RegisterServicesRequest) (*discoveryv1.RegisterServicesResponse, error) {
+	results := make([]*discoveryv1.RegisterServiceResult, len(req.GetServices()))
+	services := make(discovery.Services, 0, len(req.GetServices()))
+	idx := make([]int, 0, len(req.GetServices())) // index of services in request
+
+	for i, r := range req.GetServices() {
+		results[i] = &discoveryv1.RegisterServiceResult{}
+
+		err := verifyUser(ctx, r.GetNamespace())
+		if err != nil {
+			setRegisterResult(results[i], nil, err)
+			continue
+		}
+
+		s, err := serviceFromRequest(r)
+		if err != nil {
+			setRegisterResult(results[i], nil, err)
+			continue
+		}
+
+		services = append(services, *s)
+		idx = append(idx, i)
 	}
 
-	svc, err := a.r.RegisterService(*s)
-	if err != nil {
-		if registry.IsServersNotFound(err) {
-			return nil, status.Errorf(codes.NotFound, "no server found for selector '%s'", req.GetSelector())
+	svcs, errs := a.r.RegisterServices(services)
+
+	for j, i := range idx {
+		var err error
+
+		if errs[j] != nil {
+			err = registerServiceError(req.GetServices()[i], errs[j])
 		}
 
-		if registry.IsNamespaceNotFound(err) {
-			return nil, status.Errorf(codes.NotFound, "namespace '%s' not found", req.GetNamespace())
+		setRegisterResult(results[i], svcs[j], err)
+	}
+
+	return &discoveryv1.RegisterServicesResponse{
+		Results: results,
+	}, nil
+}
+
+// UnregisterServices unregisters multiple services.
+func (a *API) UnregisterServices(ctx context.Context, req *discoveryv1.UnregisterServicesRequest) (*discoveryv1.UnregisterServicesResponse, error) {
+	results := make([]*discoveryv1.UnregisterServiceResult, len(req.GetServices()))
+	ids := make([]string, 0, len(req.GetServices()))
+	namespaces := make([]string, 0, len(req.GetServices()))
+	idx := make([]int, 0, len(req.GetServices())) // index of services in request
+
+	for i, r := range req.GetServices() {
+		results[i] = &discoveryv1.UnregisterServiceResult{
+			Id:        r.GetId(),
+			Namespace: r.GetNamespace(),
 		}
 
-		if registry.IsValidationError(err) {
-			return nil, status.Errorf(codes.InvalidArgument, "%s", err)
+		if err := verifyUser(ctx, r.GetNamespace()); err != nil {
+			setUnregisterResult(results[i], err)
+			continue
 		}
 
-		return nil, status.Errorf(codes.Internal, "could not register service %s in store: %s", req.GetEndpoint(), err)
+		ids = append(ids, r.GetId())
+		namespaces = append(namespaces, r.GetNamespace())
+		idx = append(idx, i)
 	}
 
-	return &discoveryv1.RegisterServiceResponse{
-		Service: convert.ServiceToPB(svc),
+	errs := a.r.UnRegisterServices(ids, namespaces)
+
+	for j, i := range idx {
+		var err error
+
+		if errs[j] != nil {
+			err = unregisterServiceError(req.GetServices()[i], errs[j])
+		}
+
+		setUnregisterResult(results[i], err)
+	}
+
+	return &discoveryv1.UnregisterServicesResponse{
+		Results: results,
 	}, nil
 }
 
@@ -119,29 +291,111 @@ func (a *API) UnRegisterService(ctx context.Context, req *discoveryv1.UnRegister
 	}
 
 	if err := a.r.UnRegisterService(req.GetId(), req.GetNamespace()); err != nil {
+		return nil, unregisterServiceError(req, err)
+	}
+
+	return &discoveryv1.UnRegisterServiceResponse{}, nil
+}
+
+// Heartbeat renews the lease of a service registered with a ttl.
+func (a *API) Heartbeat(ctx context.Context, req *discoveryv1.HeartbeatRequest) (*discoveryv1.HeartbeatResponse, error) {
+	if err := verifyUser(ctx, req.GetNamespace()); err != nil {
+		return nil, err
+	}
+
+	l, err := a.r.Heartbeat(req.GetId(), req.GetNamespace())
+	if err != nil {
 		c := codes.Internal
-		if errors.Is(err, repo.ErrNotFound) {
+
+		switch {
+		case errors.Is(err, repo.ErrNotFound):
 			c = codes.NotFound
+		case errors.Is(err, repo.ErrConflict):
+			c = codes.Aborted
 		}
 
-		return nil, status.Errorf(c, "could not unregister service %s in namespace %s: %s", req.GetId(), req.GetNamespace(), err)
+		return nil, status.Errorf(c, "could not renew lease of service %s in namespace %s: %s", req.GetId(), req.GetNamespace(), err)
 	}
 
-	return &discoveryv1.UnRegisterServiceResponse{}, nil
+	return &discoveryv1.HeartbeatResponse{
+		ExpiresAt: convert.TimeToPB(&l.Expires),
+	}, nil
 }
 
-// ListService lists all services.
-func (a *API) ListService(_ context.Context, req *discoveryv1.ListServiceRequest) (*discoveryv1.ListServiceResponse, error) {
+// ListService lists all services the user can read.
+func (a *API) ListService(ctx context.Context, req *discoveryv1.ListServiceRequest) (*discoveryv1.ListServiceResponse, error) {
+	readable, err := a.verifyRead(ctx, req.GetNamespace())
+	if err != nil {
+		return nil, err
+	}
+
 	s, err := a.r.ListService(req.GetNamespace(), "")
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "could not list services: %s", err)
 	}
 
 	return &discoveryv1.ListServiceResponse{
-		Services: convert.ServicesToPB(s),
+		Services: convert.ServicesToPB(s.Filter(serviceInNamespace(readable))),
 	}, nil
 }
 
+// WatchServices streams a snapshot of all selected services followed by change and delete events.
+// The revisions of the responses are counted per stream, a stream cannot be resumed.
+func (a *API) WatchServices(req *discoveryv1.WatchServicesRequest, stream discoveryv1.ServiceAPI_WatchServicesServer) error {
+	readable, err := a.verifyRead(stream.Context(), req.GetNamespace())
+	if err != nil {
+		return err
+	}
+
+	w, err := newServiceWatch(req, readable)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid selector '%s': %s", req.GetSelector(), err)
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	// only the first error is reported, further errors must not block the watcher
+	errc := make(chan error, 1)
+	events := a.r.WatchServices(ctx, func(err error) {
+		select {
+		case errc <- err:
+		default:
+		}
+	})
+
+	services, err := a.r.ListService(req.GetNamespace(), "")
+	if err != nil {
+		return status.Errorf(codes.Internal, "could not list services: %s", err)
+	}
+
+	if err := stream.Send(w.snapshot(services)); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errc:
+			return status.Errorf(codes.Internal, "service watch failed: %s", err)
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+
+			resp := w.event(e)
+			if resp == nil {
+				continue
+			}
+
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+		}
+	}
+}
+
 // ListTargetGroup converts services to prometheus target groups.
 func (a *API) ListTargetGroup(ctx context.Context, in *discoveryv1.ListTargetGroupRequest) (*discoveryv1.ListTargetGroupResponse, error) {
 	var config discovery.ExportConfig
@@ -157,17 +411,23 @@ func (a *API) ListTargetGroup(ctx context.Context, in *discoveryv1.ListTargetGro
 		return nil, status.Errorf(codes.InvalidArgument, "invalid exporter config: '%s'", in.Config)
 	}
 
-	s, err := a.r.ListService(in.GetNamespace(), "")
+	readable, err := a.verifyRead(ctx, in.GetNamespace())
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "could not list services: %s", err)
+		return nil, err
 	}
 
-	serverFilter, err := regexp.Compile(fmt.Sprintf(`^%s$`, in.Server))
+	if err := a.waitForChange(ctx, in, readable); err != nil {
+		return nil, err
+	}
+
+	s, err := a.listTargets(in, readable)
 	if err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, "invalid regular expression: '%s'", in.Server)
+		return nil, err
 	}
 
-	s = s.Filter(discovery.ServiceByServer(serverFilter))
+	if err := grpc.SetHeader(ctx, metadata.Pairs(indexHeader, strconv.FormatUint(targetIndex(s), 10))); err != nil {
+		return nil, status.Errorf(codes.Internal, "could not set index header: %s", err)
+	}
 
 	t := make([]*discoveryv1.TargetGroup, 0, len(s))
 
@@ -181,12 +441,98 @@ func (a *API) ListTargetGroup(ctx context.Context, in *discoveryv1.ListTargetGro
 	}, nil
 }
 
+// listTargets lists the readable services of the requested server and namespace. The
+// server is a regular expression, that has to match the whole server name.
+func (a *API) listTargets(in *discoveryv1.ListTargetGroupRequest, readable func(string) bool) (discovery.Services, error) {
+	var (
+		s   discovery.Services
+		err error
+	)
+
+	if isServerPattern(in.GetServer()) {
+		re, cerr := regexp.Compile(fmt.Sprintf(`^%s$`, in.GetServer()))
+		if cerr != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid regular expression: '%s'", in.GetServer())
+		}
+
+		s, err = a.r.ListServiceByServerRegexp(re, in.GetNamespace())
+	} else {
+		s, err = a.r.ListServiceByServer(in.GetServer(), in.GetNamespace())
+	}
+
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not list services: %s", err)
+	}
+
+	return s.Filter(serviceInNamespace(readable)), nil
+}
+
+// isServerPattern returns true, if server contains regular expression meta characters.
+func isServerPattern(server string) bool {
+	return regexp.QuoteMeta(server) != server
+}
+
+// indexServer returns the server of the change index for server: patterns wait for
+// changes of all servers.
+func indexServer(server string) string {
+	if isServerPattern(server) {
+		return ""
+	}
+
+	return server
+}
+
+// waitForChange blocks until the services of the requested server and namespace change, if
+// the request contains a wait duration and the index of a previous response.
+func (a *API) waitForChange(ctx context.Context, in *discoveryv1.ListTargetGroupRequest, readable func(string) bool) error {
+	if in.GetWait() == "" || in.GetIndex() == 0 {
+		return nil
+	}
+
+	wait, err := time.ParseDuration(in.GetWait())
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid wait duration '%s': %s", in.GetWait(), err)
+	}
+
+	if wait > maxWait {
+		wait = maxWait
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		// the channel is requested before the services are listed, so no change is missed
+		changed := a.index.wait(indexServer(in.GetServer()), in.GetNamespace())
+
+		s, err := a.listTargets(in, readable)
+		if err != nil {
+			return err
+		}
+
+		if targetIndex(s) != in.GetIndex() {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return nil
+		case <-ctx.Done():
+			return status.Errorf(codes.Canceled, "%s", ctx.Err())
+		}
+	}
+}
+
 // RegisterNamespace registers a server.
 func (a *API) RegisterNamespace(_ context.Context, req *discoveryv1.RegisterNamespaceRequest) (*discoveryv1.RegisterNamespaceResponse, error) {
 	n, err := a.r.RegisterNamespace(discovery.Namespace{
 		Name:     req.Name,
 		Export:   discovery.ExportConfig(req.Export),
+		Labels:   req.GetLabels(),
 		Modified: time.Now(),
+		Public:   req.GetPublic(),
+		Replicas: int(req.GetReplicas()),
 	})
 
 	if err != nil {
@@ -216,6 +562,36 @@ func (a *API) UnregisterNamespace(_ context.Context, req *discoveryv1.Unregister
 	return &discoveryv1.UnregisterNamespaceResponse{}, nil
 }
 
+// ListDeletedNamespace lists all unregistered namespaces that can be restored.
+func (a *API) ListDeletedNamespace(_ context.Context, _ *discoveryv1.ListDeletedNamespaceRequest) (*discoveryv1.ListDeletedNamespaceResponse, error) {
+	namespaces, err := a.r.ListDeletedNamespaces()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not list deleted namespaces: %s", err)
+	}
+
+	return &discoveryv1.ListDeletedNamespaceResponse{
+		Namespaces: convert.DeletedNamespacesToPB(namespaces),
+	}, nil
+}
+
+// RestoreNamespace restores an unregistered namespace with its services.
+func (a *API) RestoreNamespace(_ context.Context, req *discoveryv1.RestoreNamespaceRequest) (*discoveryv1.RestoreNamespaceResponse, error) {
+	n, count, err := a.r.RestoreNamespace(req.GetName())
+	if err != nil {
+		c := codes.Internal
+		if errors.Is(err, repo.ErrNotFound) {
+			c = codes.NotFound
+		}
+
+		return nil, status.Errorf(c, "could not restore namespace %s (%d services restored): %s", req.GetName(), count, err)
+	}
+
+	return &discoveryv1.RestoreNamespaceResponse{
+		Namespace: convert.NamespaceToPB(n),
+		Services:  uint32(count),
+	}, nil
+}
+
 // ListNamespace lists all namespaces.
 func (a *API) ListNamespace(_ context.Context, _ *discoveryv1.ListNamespaceRequest) (*discoveryv1.ListNamespaceResponse, error) {
 	namespaces, err := a.r.ListNamespaces()
@@ -229,7 +605,13 @@ func (a *API) ListNamespace(_ context.Context, _ *discoveryv1.ListNamespaceReque
 }
 
 // Create creates an access token.
-func (a *API) Create(_ context.Context, in *discoveryv1.CreateRequest) (*discoveryv1.CreateResponse, error) {
+func (a *API) Create(ctx context.Context, in *discoveryv1.CreateRequest) (*discoveryv1.CreateResponse, error) {
+	for _, ns := range in.GetNamespaces() {
+		if err := verifyUser(ctx, ns); err != nil {
+			return nil, err
+		}
+	}
+
 	var expiry time.Duration
 
 	if in.GetExpires() != "" {
@@ -241,11 +623,54 @@ func (a *API) Create(_ context.Context, in *discoveryv1.CreateRequest) (*discove
 		expiry = d
 	}
 
-	token, err := a.tokenHandler.Create(in.Id, expiry, in.Namespaces...)
+	var (
+		token string
+		err   error
+	)
+
+	switch in.GetScope() {
+	case "":
+		if len(in.GetServers()) > 0 {
+			return nil, status.Error(codes.InvalidArgument, "servers can only be set for scrape tokens")
+		}
+
+		token, err = a.tokenHandler.Create(in.GetId(), expiry, in.GetNamespaces()...)
+	case auth.ScopeSD:
+		if len(in.GetServers()) == 0 {
+			return nil, status.Error(codes.InvalidArgument, "scrape tokens need at least one server")
+		}
+
+		token, err = a.tokenHandler.CreateScrape(in.GetId(), expiry, in.GetServers(), in.GetNamespaces()...)
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "invalid token scope '%s'", in.GetScope())
+	}
+
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create token: %s", err)
+	}
+
+	claims, err := a.tokenHandler.Claims(token)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to create token: %s", err)
 	}
 
+	t := discovery.Token{
+		TokenID:    claims.ID,
+		ID:         in.GetId(),
+		Namespaces: in.GetNamespaces(),
+		IssuedAt:   claims.IssuedAt.Time,
+		Scope:      claims.Scope,
+		Servers:    claims.Servers,
+	}
+
+	if claims.ExpiresAt != nil {
+		t.ExpiresAt = claims.ExpiresAt.Time
+	}
+
+	if _, err := a.tokens.Save(t); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to save token %s: %s", in.GetId(), err)
+	}
+
 	return &discoveryv1.CreateResponse{
 		Token: token,
 	}, nil
@@ -258,24 +683,252 @@ func (a *API) Info(_ context.Context, in *discoveryv1.InfoRequest) (*discoveryv1
 		return nil, status.Errorf(codes.InvalidArgument, "token %s is not valid: %s", in.GetToken(), err)
 	}
 
+	var scope string
+
+	if u.IsScrape() {
+		scope = auth.ScopeSD
+	}
+
 	return &discoveryv1.InfoResponse{
 		Tokeninfo: &discoveryv1.TokenInfo{
+			Scope:      scope,
 			Id:         u.Username,
 			Namespaces: u.Namespaces,
 			ExpiresAt:  convert.TimeToPB(&u.ExpiresAt),
+			TokenId:    u.TokenID,
+			Servers:    u.Servers,
 		},
 	}, nil
 }
 
+// Revoke revokes a token. Tokens that are not recorded (issued by older versions) are
+// revoked by their id.
+func (a *API) Revoke(_ context.Context, in *discoveryv1.RevokeRequest) (*discoveryv1.RevokeResponse, error) {
+	if in.GetTokenId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "token id cannot be empty")
+	}
+
+	t, err := a.tokens.Get(in.GetTokenId())
+
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		t = &discovery.Token{
+			TokenID: in.GetTokenId(),
+			ID:      in.GetTokenId(),
+		}
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "failed to get token %s: %s", in.GetTokenId(), err)
+	}
+
+	if !t.IsRevoked() {
+		t.RevokedAt = time.Now()
+
+		if _, err := a.tokens.Save(*t); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to revoke token %s: %s", in.GetTokenId(), err)
+		}
+	}
+
+	// the deny list of other instances is updated by the token watcher
+	a.denyList.Add(t.TokenID)
+
+	return &discoveryv1.RevokeResponse{
+		Token: convert.TokenToPB(t),
+	}, nil
+}
+
+// List lists all issued tokens.
+func (a *API) List(_ context.Context, _ *discoveryv1.ListRequest) (*discoveryv1.ListResponse, error) {
+	tokens, err := a.tokens.List()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not list tokens: %s", err)
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].IssuedAt.Before(tokens[j].IssuedAt)
+	})
+
+	return &discoveryv1.ListResponse{
+		Tokens: convert.TokensToPB(tokens),
+	}, nil
+}
+
+func serviceFromRequest(req *discoveryv1.RegisterServiceRequest) (*discovery.Service, error) {
+	s, err := discovery.NewService(req.GetName(), req.GetEndpoint())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "service with endpoint %s is invalid: %s", req.GetEndpoint(), err)
+	}
+
+	s.Labels = req.GetLabels()
+	s.Description = req.GetDescription()
+	s.Selector = req.GetSelector()
+	s.Replicas = int(req.GetReplicas())
+
+	if req.GetTtl() != "" {
+		ttl, err := time.ParseDuration(req.GetTtl())
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid ttl '%s': %s", req.GetTtl(), err)
+		}
+
+		s.TTL = ttl
+	}
+
+	if req.Namespace != "" {
+		s.Namespace = req.Namespace
+	}
+
+	return s, nil
+}
+
+// registerServiceError converts a registry error to a grpc status error.
+func registerServiceError(req *discoveryv1.RegisterServiceRequest, err error) error {
+	if registry.IsServersNotFound(err) {
+		return status.Errorf(codes.NotFound, "no server found for selector '%s'", req.GetSelector())
+	}
+
+	if registry.IsServersFull(err) {
+		return status.Errorf(codes.ResourceExhausted, "all servers for selector '%s' reached their maximum number of targets", req.GetSelector())
+	}
+
+	if registry.IsNamespaceNotFound(err) {
+		return status.Errorf(codes.NotFound, "namespace '%s' not found", req.GetNamespace())
+	}
+
+	if registry.IsValidationError(err) {
+		return status.Errorf(codes.InvalidArgument, "%s", err)
+	}
+
+	return status.Errorf(codes.Internal, "could not register service %s in store: %s", req.GetEndpoint(), err)
+}
+
+// unregisterServiceError converts a registry error to a grpc status error.
+func unregisterServiceError(req *discoveryv1.UnRegisterServiceRequest, err error) error {
+	c := codes.Internal
+	if errors.Is(err, repo.ErrNotFound) {
+		c = codes.NotFound
+	}
+
+	return status.Errorf(c, "could not unregister service %s in namespace %s: %s", req.GetId(), req.GetNamespace(), err)
+}
+
+func setRegisterResult(r *discoveryv1.RegisterServiceResult, svc *discovery.Service, err error) {
+	if err != nil {
+		st := status.Convert(err)
+		r.Code = uint32(st.Code())
+		r.Error = st.Message()
+
+		return
+	}
+
+	r.Service = convert.ServiceToPB(svc)
+}
+
+func setUnregisterResult(r *discoveryv1.UnregisterServiceResult, err error) {
+	if err != nil {
+		st := status.Convert(err)
+		r.Code = uint32(st.Code())
+		r.Error = st.Message()
+	}
+}
+
 func verifyUser(ctx context.Context, namespace string) error {
 	u, ok := auth.UserFromContext(ctx)
 	if !ok {
 		return status.Errorf(codes.Unauthenticated, "unauthententicated user")
 	}
 
-	if u.IsMachine() && !u.HasNamespace(namespace) {
-		return status.Errorf(codes.PermissionDenied, "machine token %s (%s) is not allowed to change service in %s namespace", u.Username, strings.Join(u.Namespaces, ","), namespace)
+	if !u.HasNamespace(namespace) {
+		return status.Errorf(codes.PermissionDenied, "%s token %s (namespaces: %s) is not allowed to access services in namespace '%s'", u.Kind.String(), u.Username, strings.Join(u.Namespaces, ","), namespace)
 	}
 
 	return nil
 }
+
+// verifyRead checks if the user can read services in namespace. If namespace is empty, all
+// namespaces are requested. The returned function reports whether the user can read
+// services in a namespace: namespaces of the user and public namespaces are readable.
+func (a *API) verifyRead(ctx context.Context, namespace string) (func(string) bool, error) {
+	u, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return nil, status.Errorf(codes.Unauthenticated, "unauthententicated user")
+	}
+
+	readable := func(namespace string) bool {
+		if u.HasNamespace(namespace) {
+			return true
+		}
+
+		ns, ok := a.r.Namespace(namespace)
+
+		return ok && ns.Public
+	}
+
+	if namespace != "" && !readable(namespace) {
+		return nil, status.Errorf(codes.PermissionDenied, "%s token %s (namespaces: %s) is not allowed to read services in private namespace '%s'", u.Kind.String(), u.Username, strings.Join(u.Namespaces, ","), namespace)
+	}
+
+	return readable, nil
+}
+
+func serviceInNamespace(readable func(string) bool) discovery.FilterFunc {
+	return func(s discovery.Service) bool {
+		return readable(s.Namespace)
+	}
+}
+
+// ListAuditRecord lists audit records.
+func (a *API) ListAuditRecord(_ context.Context, req *discoveryv1.ListAuditRecordRequest) (*discoveryv1.ListAuditRecordResponse, error) {
+	var since time.Time
+
+	if req.GetSince() != nil {
+		since = convert.TimeFromPB(req.GetSince())
+	}
+
+	records, err := a.audit.List(since)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not list audit records: %s", err)
+	}
+
+	result := discovery.AuditRecords{}
+
+	for _, r := range records {
+		if req.GetUser() != "" && r.User != req.GetUser() {
+			continue
+		}
+
+		if !strings.Contains(r.Method, req.GetMethod()) {
+			continue
+		}
+
+		result = append(result, r)
+	}
+
+	return &discoveryv1.ListAuditRecordResponse{
+		Records: convert.AuditRecordsToPB(result),
+	}, nil
+}
+
+// CanI evaluates if the calling user is allowed to call a method.
+func (a *API) CanI(ctx context.Context, req *discoveryv1.CanIRequest) (*discoveryv1.CanIResponse, error) {
+	if req.GetMethod() == "" {
+		return nil, status.Error(codes.InvalidArgument, "method cannot be empty")
+	}
+
+	u, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return nil, status.Errorf(codes.Unauthenticated, "unauthententicated user")
+	}
+
+	r := auth.Request{
+		Method:     auth.FullMethod(req.GetMethod()),
+		Namespaces: req.GetNamespaces(),
+		Servers:    req.GetServers(),
+	}
+
+	allowed, reason := a.authorizer.Authorize(u, r)
+
+	return &discoveryv1.CanIResponse{
+		Allowed: allowed,
+		Reason:  reason,
+		Method:  r.Method,
+	}, nil
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/postfinance/discovery"
+	"github.com/postfinance/discovery/internal/auth"
+	"github.com/postfinance/discovery/internal/repo"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// auditedMethods are the mutating methods recorded in the audit log. Heartbeats
+// are not recorded.
+var auditedMethods = map[string]bool{
+	"/postfinance.discovery.v1.NamespaceAPI/RegisterNamespace":   true,
+	"/postfinance.discovery.v1.NamespaceAPI/UnregisterNamespace": true,
+	"/postfinance.discovery.v1.NamespaceAPI/RestoreNamespace":    true,
+	"/postfinance.discovery.v1.ServerAPI/RegisterServer":         true,
+	"/postfinance.discovery.v1.ServerAPI/UnregisterServer":       true,
+	"/postfinance.discovery.v1.ServerAPI/CordonServer":           true,
+	"/postfinance.discovery.v1.ServerAPI/UncordonServer":         true,
+	"/postfinance.discovery.v1.ServerAPI/DrainServer":            true,
+	"/postfinance.discovery.v1.ServerAPI/Rebalance":              true,
+	"/postfinance.discovery.v1.ServiceAPI/RegisterService":       true,
+	"/postfinance.discovery.v1.ServiceAPI/UnRegisterService":     true,
+	"/postfinance.discovery.v1.ServiceAPI/RegisterServices":      true,
+	"/postfinance.discovery.v1.ServiceAPI/UnregisterServices":    true,
+	"/postfinance.discovery.v1.TokenAPI/Create":                  true,
+	"/postfinance.discovery.v1.TokenAPI/Revoke":                  true,
+}
+
+// auditLog records mutating api calls.
+type auditLog struct {
+	repo      *repo.Audit
+	l         *zap.SugaredLogger
+	retention time.Duration
+}
+
+// UnaryServerInterceptor records all audited methods with the user from context, the
+// request and the outcome of the call. If the retention is 0, nothing is recorded.
+func (a *auditLog) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if a.retention == 0 || !auditedMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		resp, err := handler(ctx, req)
+
+		a.record(ctx, info.FullMethod, req, err)
+
+		return resp, err
+	}
+}
+
+func (a *auditLog) record(ctx context.Context, method string, req interface{}, callErr error) {
+	r := discovery.AuditRecord{
+		Time:   time.Now(),
+		Method: method,
+		Code:   uint32(status.Code(callErr)),
+	}
+
+	if u, ok := auth.UserFromContext(ctx); ok {
+		r.User = u.Username
+		r.Kind = u.Kind.String()
+	}
+
+	if m, ok := req.(proto.Message); ok {
+		d, err := protojson.Marshal(m)
+		if err != nil {
+			a.l.Errorw("failed to marshal audit request", "method", method, "err", err)
+		}
+
+		r.Request = string(d)
+	}
+
+	if callErr != nil {
+		r.Error = status.Convert(callErr).Message()
+	}
+
+	if _, err := a.repo.Save(r); err != nil {
+		a.l.Errorw("failed to save audit record", "method", method, "user", r.User, "err", err)
+	}
+}
+
+// run deletes audit records older than the retention every interval. It runs until context
+// ctx is canceled.
+func (a *auditLog) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := a.repo.DeleteBefore(time.Now().Add(-a.retention))
+			if err != nil {
+				a.l.Errorw("failed to delete expired audit records", "err", err)
+				continue
+			}
+
+			a.l.Debugw("deleted expired audit records", "count", n)
+		}
+	}
+}
@@ -17,7 +17,10 @@ func NamespaceToPB(n *discovery.Namespace) *discoveryv1.Namespace {
 	pb := &discoveryv1.Namespace{
 		Name:     n.Name,
 		Export:   int32(n.Export),
+		Labels:   n.Labels,
 		Modified: TimeToPB(&n.Modified),
+		Public:   n.Public,
+		Replicas: uint32(n.Replicas),
 	}
 
 	return pb
@@ -28,7 +31,10 @@ func NamespaceFromPB(pb *discoveryv1.Namespace) *discovery.Namespace {
 	n := &discovery.Namespace{
 		Name:     pb.Name,
 		Export:   discovery.ExportConfig(pb.Export),
+		Labels:   pb.GetLabels(),
 		Modified: TimeFromPB(pb.Modified),
+		Public:   pb.GetPublic(),
+		Replicas: int(pb.GetReplicas()),
 	}
 
 	return n
@@ -37,10 +43,13 @@ func NamespaceFromPB(pb *discoveryv1.Namespace) *discovery.Namespace {
 // ServerToPB converts *discovery.Server to *discoveryv1.Server.
 func ServerToPB(s *discovery.Server) *discoveryv1.Server {
 	pb := &discoveryv1.Server{
-		Name:     s.Name,
-		Labels:   s.Labels,
-		Modified: TimeToPB(&s.Modified),
-		State:    int64(s.State),
+		Name:       s.Name,
+		Labels:     s.Labels,
+		Modified:   TimeToPB(&s.Modified),
+		State:      int64(s.State),
+		Weight:     int64(s.Weight),
+		MaxTargets: int64(s.MaxTargets),
+		DrainRate:  int64(s.DrainRate),
 	}
 
 	return pb
@@ -49,10 +58,13 @@ func ServerToPB(s *discovery.Server) *discoveryv1.Server {
 // ServerFromPB converts *discovery.Server to *discoveryv1.Server.
 func ServerFromPB(pb *discoveryv1.Server) *discovery.Server {
 	s := &discovery.Server{
-		Name:     pb.GetName(),
-		Labels:   pb.GetLabels(),
-		Modified: TimeFromPB(pb.Modified),
-		State:    discovery.ServerState(pb.GetState()),
+		Name:       pb.GetName(),
+		Labels:     pb.GetLabels(),
+		Modified:   TimeFromPB(pb.Modified),
+		State:      discovery.ServerState(pb.GetState()),
+		Weight:     int(pb.GetWeight()),
+		MaxTargets: int(pb.GetMaxTargets()),
+		DrainRate:  int(pb.GetDrainRate()),
 	}
 
 	return s
@@ -92,6 +104,11 @@ func ServiceToPB(s *discovery.Service) *discoveryv1.Service {
 		Selector:    s.Selector,
 		Servers:     s.Servers,
 		Modified:    TimeToPB(&s.Modified),
+		Replicas:    uint32(s.Replicas),
+	}
+
+	if s.TTL > 0 {
+		pb.Ttl = s.TTL.String()
 	}
 
 	return pb
@@ -110,6 +127,11 @@ func ServiceFromPB(pb *discoveryv1.Service) *discovery.Service {
 		Selector:    pb.GetSelector(),
 		Servers:     pb.GetServers(),
 		Modified:    TimeFromPB(pb.GetModified()),
+		Replicas:    int(pb.GetReplicas()),
+	}
+
+	if ttl, err := time.ParseDuration(pb.GetTtl()); err == nil {
+		s.TTL = ttl
 	}
 
 	return s
@@ -184,6 +206,170 @@ func NamespacesFromPB(s []*discoveryv1.Namespace) discovery.Namespaces {
 	return result
 }
 
+// DeletedNamespaceToPB converts *discovery.DeletedNamespace to *discoveryv1.DeletedNamespace.
+func DeletedNamespaceToPB(d *discovery.DeletedNamespace) *discoveryv1.DeletedNamespace {
+	return &discoveryv1.DeletedNamespace{
+		Namespace: NamespaceToPB(&d.Namespace),
+		Services:  uint32(d.Services),
+		Deleted:   TimeToPB(&d.Deleted),
+		Expires:   TimeToPB(&d.Expires),
+	}
+}
+
+// DeletedNamespaceFromPB converts *discoveryv1.DeletedNamespace to *discovery.DeletedNamespace.
+func DeletedNamespaceFromPB(pb *discoveryv1.DeletedNamespace) *discovery.DeletedNamespace {
+	d := &discovery.DeletedNamespace{
+		Services: int(pb.GetServices()),
+		Deleted:  TimeFromPB(pb.GetDeleted()),
+		Expires:  TimeFromPB(pb.GetExpires()),
+	}
+
+	if pb.GetNamespace() != nil {
+		d.Namespace = *NamespaceFromPB(pb.GetNamespace())
+	}
+
+	return d
+}
+
+// DeletedNamespacesToPB converts discovery.DeletedNamespaces to slices of *discoveryv1.DeletedNamespace.
+func DeletedNamespacesToPB(s discovery.DeletedNamespaces) []*discoveryv1.DeletedNamespace {
+	result := make([]*discoveryv1.DeletedNamespace, 0, len(s))
+
+	for i := range s {
+		result = append(result, DeletedNamespaceToPB(&s[i]))
+	}
+
+	return result
+}
+
+// DeletedNamespacesFromPB converts slices of *discoveryv1.DeletedNamespace to discovery.DeletedNamespaces.
+func DeletedNamespacesFromPB(s []*discoveryv1.DeletedNamespace) discovery.DeletedNamespaces {
+	result := make(discovery.DeletedNamespaces, 0, len(s))
+
+	for i := range s {
+		result = append(result, *DeletedNamespaceFromPB(s[i]))
+	}
+
+	return result
+}
+
+// AuditRecordToPB converts *discovery.AuditRecord to *discoveryv1.AuditRecord.
+func AuditRecordToPB(a *discovery.AuditRecord) *discoveryv1.AuditRecord {
+	return &discoveryv1.AuditRecord{
+		Id:      a.ID,
+		Time:    TimeToPB(&a.Time),
+		Method:  a.Method,
+		User:    a.User,
+		Kind:    a.Kind,
+		Request: a.Request,
+		Code:    a.Code,
+		Error:   a.Error,
+	}
+}
+
+// AuditRecordFromPB converts *discoveryv1.AuditRecord to *discovery.AuditRecord.
+func AuditRecordFromPB(pb *discoveryv1.AuditRecord) *discovery.AuditRecord {
+	return &discovery.AuditRecord{
+		ID:      pb.GetId(),
+		Time:    TimeFromPB(pb.GetTime()),
+		Method:  pb.GetMethod(),
+		User:    pb.GetUser(),
+		Kind:    pb.GetKind(),
+		Request: pb.GetRequest(),
+		Code:    pb.GetCode(),
+		Error:   pb.GetError(),
+	}
+}
+
+// AuditRecordsToPB converts discovery.AuditRecords to slices of *discoveryv1.AuditRecord.
+func AuditRecordsToPB(s discovery.AuditRecords) []*discoveryv1.AuditRecord {
+	result := make([]*discoveryv1.AuditRecord, 0, len(s))
+
+	for i := range s {
+		result = append(result, AuditRecordToPB(&s[i]))
+	}
+
+	return result
+}
+
+// AuditRecordsFromPB converts slices of *discoveryv1.AuditRecord to discovery.AuditRecords.
+func AuditRecordsFromPB(s []*discoveryv1.AuditRecord) discovery.AuditRecords {
+	result := make(discovery.AuditRecords, 0, len(s))
+
+	for i := range s {
+		result = append(result, *AuditRecordFromPB(s[i]))
+	}
+
+	return result
+}
+
+// TokenToPB converts *discovery.Token to *discoveryv1.IssuedToken. Zero expiry and
+// revocation times are omitted.
+func TokenToPB(t *discovery.Token) *discoveryv1.IssuedToken {
+	pb := &discoveryv1.IssuedToken{
+		TokenId:    t.TokenID,
+		Id:         t.ID,
+		Namespaces: t.Namespaces,
+		IssuedAt:   TimeToPB(&t.IssuedAt),
+		Scope:      t.Scope,
+		Servers:    t.Servers,
+	}
+
+	if !t.ExpiresAt.IsZero() {
+		pb.ExpiresAt = TimeToPB(&t.ExpiresAt)
+	}
+
+	if !t.RevokedAt.IsZero() {
+		pb.RevokedAt = TimeToPB(&t.RevokedAt)
+	}
+
+	return pb
+}
+
+// TokenFromPB converts *discoveryv1.IssuedToken to *discovery.Token.
+func TokenFromPB(pb *discoveryv1.IssuedToken) *discovery.Token {
+	t := &discovery.Token{
+		TokenID:    pb.GetTokenId(),
+		ID:         pb.GetId(),
+		Namespaces: pb.GetNamespaces(),
+		IssuedAt:   TimeFromPB(pb.GetIssuedAt()),
+		Scope:      pb.GetScope(),
+		Servers:    pb.GetServers(),
+	}
+
+	if pb.GetExpiresAt() != nil {
+		t.ExpiresAt = TimeFromPB(pb.GetExpiresAt())
+	}
+
+	if pb.GetRevokedAt() != nil {
+		t.RevokedAt = TimeFromPB(pb.GetRevokedAt())
+	}
+
+	return t
+}
+
+// TokensToPB converts discovery.Tokens to slices of *discoveryv1.IssuedToken.
+func TokensToPB(s discovery.Tokens) []*discoveryv1.IssuedToken {
+	result := make([]*discoveryv1.IssuedToken, 0, len(s))
+
+	for i := range s {
+		result = append(result, TokenToPB(&s[i]))
+	}
+
+	return result
+}
+
+// TokensFromPB converts slices of *discoveryv1.IssuedToken to discovery.Tokens.
+func TokensFromPB(s []*discoveryv1.IssuedToken) discovery.Tokens {
+	result := make(discovery.Tokens, 0, len(s))
+
+	for i := range s {
+		result = append(result, *TokenFromPB(s[i]))
+	}
+
+	return result
+}
+
 // TargetGroupToPB converts *exporter.TargetGroup to discoveryv1.TargetGroup.
 func TargetGroupToPB(t *exporter.TargetGroup) *discoveryv1.TargetGroup {
 	pb := &discoveryv1.TargetGroup{
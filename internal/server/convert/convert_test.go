@@ -12,6 +12,9 @@ import (
 func TestConvertServer(t *testing.T) {
 	expected := discovery.NewServer("name", discovery.Labels{"key": "val"})
 	expected.State = discovery.Joining
+	expected.Weight = 4
+	expected.MaxTargets = 100
+	expected.DrainRate = 10
 	pb := ServerToPB(expected)
 	s := ServerFromPB(pb)
 
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// etagHandler adds an ETag header to successful GET responses of h. If the ETag matches the
+// If-None-Match request header, the body is omitted and 304 Not Modified is returned.
+func etagHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		bw := &bufferedResponseWriter{
+			header: http.Header{},
+			code:   http.StatusOK,
+		}
+
+		h.ServeHTTP(bw, r)
+
+		for k, v := range bw.header {
+			w.Header()[k] = v
+		}
+
+		if bw.code != http.StatusOK {
+			w.WriteHeader(bw.code)
+			_, _ = w.Write(bw.body.Bytes())
+
+			return
+		}
+
+		etag := fmt.Sprintf(`"%x"`, sha256.Sum256(bw.body.Bytes()))
+		w.Header().Set("ETag", etag)
+
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.Header().Del("Content-Length")
+			w.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(bw.body.Bytes())
+	})
+}
+
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, t := range strings.Split(ifNoneMatch, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == etag || t == "*" {
+			return true
+		}
+	}
+
+	return false
+}
+
+type bufferedResponseWriter struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponseWriter) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponseWriter) Write(d []byte) (int, error) {
+	return b.body.Write(d)
+}
+
+func (b *bufferedResponseWriter) WriteHeader(code int) {
+	b.code = code
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"sync"
+
+	"github.com/postfinance/discovery"
+)
+
+// changeIndex notifies blocking ListTargetGroup requests about changes of the services of a
+// server in a namespace. An empty server or namespace stands for all servers or namespaces.
+type changeIndex struct {
+	m      *sync.Mutex
+	notify map[string]chan struct{}
+}
+
+func newChangeIndex() *changeIndex {
+	return &changeIndex{
+		m:      &sync.Mutex{},
+		notify: map[string]chan struct{}{},
+	}
+}
+
+// wait returns a channel which is closed on the next change of the services of server
+// in namespace.
+func (c *changeIndex) wait(server, namespace string) <-chan struct{} {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	k := indexKey(server, namespace)
+
+	ch, ok := c.notify[k]
+	if !ok {
+		ch = make(chan struct{})
+		c.notify[k] = ch
+	}
+
+	return ch
+}
+
+// publish notifies the requests for servers and namespace, for all servers and for
+// all namespaces about a changed service.
+func (c *changeIndex) publish(namespace string, servers []string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	for _, server := range append([]string{""}, servers...) {
+		for _, ns := range []string{namespace, ""} {
+			k := indexKey(server, ns)
+
+			if ch, ok := c.notify[k]; ok {
+				close(ch)
+				delete(c.notify, k)
+			}
+		}
+	}
+}
+
+func indexKey(server, namespace string) string {
+	return server + "/" + namespace
+}
+
+// targetIndex returns the index of services. It is derived from the ids and modification
+// times of the services, therefore all discovery servers return the same index for the
+// same services. It is never 0.
+func targetIndex(services discovery.Services) uint64 {
+	h := fnv.New64a()
+	b := make([]byte, 8)
+
+	for _, s := range services {
+		_, _ = h.Write([]byte(s.Namespace + "/" + s.ID + "/"))
+
+		binary.BigEndian.PutUint64(b, uint64(s.Modified.UnixNano()))
+		_, _ = h.Write(b)
+	}
+
+	if idx := h.Sum64(); idx != 0 {
+		return idx
+	}
+
+	return 1
+}
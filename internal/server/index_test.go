@@ -0,0 +1,49 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/postfinance/discovery"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangeIndex(t *testing.T) {
+	c := newChangeIndex()
+
+	closed := func(ch <-chan struct{}) bool {
+		select {
+		case <-ch:
+			return true
+		default:
+			return false
+		}
+	}
+
+	own := c.wait("server1", "test")
+	allNamespaces := c.wait("server1", "")
+	all := c.wait("", "")
+	otherServer := c.wait("server2", "test")
+	otherNamespace := c.wait("server1", "other")
+
+	// moved from server3 to server1
+	c.publish("test", []string{"server3", "server1"})
+
+	assert.True(t, closed(own))
+	assert.True(t, closed(allNamespaces))
+	assert.True(t, closed(all))
+	assert.False(t, closed(c.wait("server3", "test")), "new channel")
+	assert.False(t, closed(otherServer))
+	assert.False(t, closed(otherNamespace))
+
+	t.Run("index", func(t *testing.T) {
+		s := discovery.Services{{ID: "a", Namespace: "test", Modified: time.Unix(1, 0)}}
+
+		assert.Equal(t, targetIndex(s), targetIndex(discovery.Services{s[0]}))
+		assert.NotZero(t, targetIndex(nil))
+
+		changed := discovery.Services{s[0]}
+		changed[0].Modified = time.Unix(2, 0)
+		assert.NotEqual(t, targetIndex(s), targetIndex(changed))
+	})
+}
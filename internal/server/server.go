@@ -3,6 +3,7 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"embed"
 	"fmt"
 	"net"
@@ -19,7 +20,9 @@ import (
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/postfinance/discovery"
 	"github.com/postfinance/discovery/internal/auth"
+	"github.com/postfinance/discovery/internal/hash"
 	"github.com/postfinance/discovery/internal/registry"
+	"github.com/postfinance/discovery/internal/repo"
 	discoveryv1 "github.com/postfinance/discovery/pkg/discoverypb/postfinance/discovery/v1"
 	"github.com/postfinance/store"
 	"github.com/prometheus/client_golang/prometheus"
@@ -27,6 +30,7 @@ import (
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
@@ -38,6 +42,13 @@ const (
 	httpClientTimeout            = 10 * time.Second
 	cacheSyncInterval            = 1 * time.Minute
 	serviceCounterUpdateInterval = 15 * time.Second
+	leaseReapInterval            = 5 * time.Second
+	trashPurgeInterval           = 10 * time.Minute
+	drainInterval                = 1 * time.Minute
+	auditPurgeInterval           = 10 * time.Minute
+	policyReloadInterval         = 10 * time.Second
+	maxWait                      = 5 * time.Minute
+	indexHeader                  = "x-discovery-index"
 )
 
 //go:embed swagger/*
@@ -57,15 +68,24 @@ type Server struct {
 type Config struct {
 	PrometheusRegistry prometheus.Registerer
 	NumReplicas        int
+	Distributor        hash.Distributor
 	GRPCListenAddr     string
 	HTTPListenAddr     string
 	TokenIssuer        string
 	TokenSecretKey     string
+	TokenKeys          *auth.KeySet
+	TokenLegacy        bool
 	OIDCClient         string
 	OIDCRoles          []string
+	PolicyFile         string
 	OIDCURL            string
+	OIDCAudience       string
 	Transport          http.RoundTripper
 	ClaimConfig        auth.ClaimConfig
+	TLSConfig          *tls.Config
+	CertConfig         auth.CertConfig
+	TrashRetention     time.Duration
+	AuditRetention     time.Duration
 }
 
 // New initializes a new Server.
@@ -125,34 +145,92 @@ func (s *Server) startGRPC(ctx context.Context) error {
 		grpc_recovery.WithRecoveryHandler(panicHandler),
 	}
 
-	tokenHandler := auth.NewTokenHandler(s.config.TokenIssuer, s.config.TokenSecretKey)
+	denyList := &denyListUpdater{
+		repo:     repo.NewToken(s.backend),
+		denyList: auth.NewDenyList(),
+		l:        s.l.Named("denylist"),
+	}
+
+	if err := denyList.sync(); err != nil {
+		return err
+	}
+
+	go denyList.run(ctx, cacheSyncInterval)
+
+	tokenOpts := []auth.TokenHandlerOption{
+		auth.WithDenyList(denyList.denyList),
+		auth.WithKeySet(s.config.TokenKeys),
+	}
+
+	if s.config.TokenLegacy {
+		tokenOpts = append(tokenOpts, auth.WithLegacyTokens(s.l.Named("token")))
+	}
+
+	tokenHandler := auth.NewTokenHandler(s.config.TokenSecretKey, s.config.TokenIssuer, tokenOpts...)
 
 	verifier, err := auth.NewVerifier(s.config.OIDCURL, s.config.OIDCClient, httpClientTimeout, s.config.Transport)
 	if err != nil {
 		return err
 	}
 
+	authOpts := []auth.FuncOption{
+		auth.WithCertConfig(s.config.CertConfig),
+	}
+
+	if s.config.OIDCAudience != "" {
+		clientVerifier, err := auth.NewVerifier(s.config.OIDCURL, s.config.OIDCAudience, httpClientTimeout, s.config.Transport)
+		if err != nil {
+			return err
+		}
+
+		authOpts = append(authOpts, auth.WithClientVerifier(clientVerifier))
+	}
+
+	authorizer := auth.NewAuthorizer(auth.DefaultPolicy(s.config.OIDCRoles...), s.l.Named("authorizer"))
+
+	if s.config.PolicyFile != "" {
+		authorizer, err = auth.NewFileAuthorizer(s.config.PolicyFile, s.l.Named("authorizer"))
+		if err != nil {
+			return fmt.Errorf("failed to load policy: %w", err)
+		}
+
+		go authorizer.Watch(ctx, policyReloadInterval)
+	}
+
+	audit := &auditLog{
+		repo:      repo.NewAudit(s.backend),
+		l:         s.l.Named("audit"),
+		retention: s.config.AuditRetention,
+	}
+
 	// Make sure that log statements internal to gRPC library are logged using the zapLogger as well.
 	// grpc_zap.ReplaceGrpcLoggerV2(s.l.Desugar())
 
-	s.grpcServer = grpc.NewServer(
+	serverOpts := []grpc.ServerOption{
 		grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(
 			grpc_recovery.StreamServerInterceptor(opts...),
 			grpcMetrics.StreamServerInterceptor(),
 			auth.StreamMethodNameInterceptor(),
-			grpc_auth.StreamServerInterceptor(auth.Func(verifier, tokenHandler, s.l.Named("auth"), s.config.ClaimConfig)),
-			auth.StreamAuthorizeInterceptor(s.config.OIDCRoles...),
+			grpc_auth.StreamServerInterceptor(auth.Func(verifier, tokenHandler, s.l.Named("auth"), s.config.ClaimConfig, authOpts...)),
+			authorizer.StreamServerInterceptor(),
 			grpc_zap.StreamServerInterceptor(s.l.Desugar(), grpc_zap.WithLevels(customCodeToLevel)),
 		)),
 		grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(
 			grpc_recovery.UnaryServerInterceptor(opts...),
 			grpcMetrics.UnaryServerInterceptor(),
 			auth.UnaryMethodNameInterceptor(),
-			grpc_auth.UnaryServerInterceptor(auth.Func(verifier, tokenHandler, s.l.Named("auth"), s.config.ClaimConfig)),
-			auth.UnaryAuthorizeInterceptor(s.config.OIDCRoles...),
+			grpc_auth.UnaryServerInterceptor(auth.Func(verifier, tokenHandler, s.l.Named("auth"), s.config.ClaimConfig, authOpts...)),
+			audit.UnaryServerInterceptor(),
+			authorizer.UnaryServerInterceptor(),
 			grpc_zap.UnaryServerInterceptor(s.l.Desugar(), grpc_zap.WithLevels(customCodeToLevel)),
 		)),
-	)
+	}
+
+	if s.config.TLSConfig != nil {
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(s.config.TLSConfig)))
+	}
+
+	s.grpcServer = grpc.NewServer(serverOpts...)
 
 	if err := s.config.PrometheusRegistry.Register(grpcMetrics); err != nil {
 		return err
@@ -168,13 +246,36 @@ func (s *Server) startGRPC(ctx context.Context) error {
 		return err
 	}
 
-	r, err := registry.New(s.backend, s.config.PrometheusRegistry, s.l, s.config.NumReplicas)
+	index := newChangeIndex()
+
+	registryOpts := []registry.Option{
+		registry.WithTrashRetention(s.config.TrashRetention),
+		registry.WithServiceChangeHandler(index.publish),
+	}
+
+	if s.config.Distributor != nil {
+		registryOpts = append(registryOpts, registry.WithDistributor(s.config.Distributor))
+	}
+
+	r, err := registry.New(s.backend, s.config.PrometheusRegistry, s.l, s.config.NumReplicas, registryOpts...)
 	if err != nil {
 		return err
 	}
 
+	if _, err := r.ResumeRebalance(ctx); err != nil {
+		s.l.Errorw("failed to resume rebalance", "err", err)
+	}
+
 	go r.StartCacheUpdater(ctx, cacheSyncInterval)
+	go r.StartServiceCacheUpdater(ctx, cacheSyncInterval)
 	go r.StartServiceCounterUpdater(ctx, serviceCounterUpdateInterval)
+	go r.StartLeaseReaper(ctx, leaseReapInterval)
+	go r.StartTrashPurger(ctx, trashPurgeInterval)
+	go r.StartDrainer(ctx, drainInterval)
+
+	if s.config.AuditRetention > 0 {
+		go audit.run(ctx, auditPurgeInterval)
+	}
 
 	ns, err := r.ListNamespaces()
 	if err != nil {
@@ -194,12 +295,19 @@ func (s *Server) startGRPC(ctx context.Context) error {
 	a := &API{
 		r:            r,
 		tokenHandler: tokenHandler,
+		index:        index,
+		audit:        audit.repo,
+		tokens:       denyList.repo,
+		denyList:     denyList.denyList,
+		authorizer:   authorizer,
 	}
 
 	discoveryv1.RegisterServerAPIServer(s.grpcServer, a)
 	discoveryv1.RegisterServiceAPIServer(s.grpcServer, a)
 	discoveryv1.RegisterNamespaceAPIServer(s.grpcServer, a)
 	discoveryv1.RegisterTokenAPIServer(s.grpcServer, a)
+	discoveryv1.RegisterAuditAPIServer(s.grpcServer, a)
+	discoveryv1.RegisterAuthAPIServer(s.grpcServer, a)
 
 	// grpc reflection support
 	reflection.Register(s.grpcServer)
@@ -220,16 +328,36 @@ func (s *Server) startHTTP(ctx context.Context) error {
 		return err
 	}
 
-	gwmux := runtime.NewServeMux()
+	gwmux := runtime.NewServeMux(runtime.WithOutgoingHeaderMatcher(func(key string) (string, bool) {
+		if key == indexHeader {
+			return key, true
+		}
 
-	err = discoveryv1.RegisterServiceAPIHandlerFromEndpoint(ctx, gwmux, ep, []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())})
-	if err != nil {
-		return err
+		return runtime.MetadataHeaderPrefix + key, true
+	}))
+	dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+
+	// the gateway connects to the own grpc server and only trusts its certificate
+	if s.config.TLSConfig != nil {
+		tlsConfig, err := auth.NewPinnedTLSConfig(s.config.TLSConfig)
+		if err != nil {
+			return err
+		}
+
+		dialOpts = []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))}
 	}
 
-	err = discoveryv1.RegisterServerAPIHandlerFromEndpoint(ctx, gwmux, ep, []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())})
-	if err != nil {
-		return err
+	for _, register := range []func(context.Context, *runtime.ServeMux, string, []grpc.DialOption) error{
+		discoveryv1.RegisterServiceAPIHandlerFromEndpoint,
+		discoveryv1.RegisterServerAPIHandlerFromEndpoint,
+		discoveryv1.RegisterNamespaceAPIHandlerFromEndpoint,
+		discoveryv1.RegisterTokenAPIHandlerFromEndpoint,
+		discoveryv1.RegisterAuditAPIHandlerFromEndpoint,
+		discoveryv1.RegisterAuthAPIHandlerFromEndpoint,
+	} {
+		if err := register(ctx, gwmux, ep, dialOpts); err != nil {
+			return err
+		}
 	}
 
 	mux := http.NewServeMux()
@@ -241,6 +369,8 @@ func (s *Server) startHTTP(ctx context.Context) error {
 
 	mux.Handle("/swagger/", http.FileServer(http.FS(static)))
 	mux.Handle("/metrics", promhttp.HandlerFor(r, promhttp.HandlerOpts{}))
+	mux.Handle("/v1/sd/", etagHandler(gwmux))
+	mux.Handle("/.well-known/jwks.json", jwksHandler(s.config.TokenKeys))
 	mux.Handle("/", gwmux)
 
 	s.httpServer = &http.Server{
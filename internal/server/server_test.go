@@ -0,0 +1,919 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/postfinance/discovery"
+	"github.com/postfinance/discovery/internal/auth"
+	"github.com/postfinance/discovery/internal/bolt"
+	discoveryv1 "github.com/postfinance/discovery/pkg/discoverypb/postfinance/discovery/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	oidcClientID = "discovery"
+	oidcAudience = "discovery-api"
+	rwRole       = "admin"
+)
+
+//nolint:funlen // one test for all rest routes
+func TestRESTGateway(t *testing.T) {
+	oidcServer, userToken, clientToken := newOIDCProvider(t)
+	defer oidcServer.Close()
+
+	c := newBackend(t)
+
+	grpcAddr, httpAddr := freeAddr(t), freeAddr(t)
+	claimConfig := auth.NewClaimConfig("username", "roles",
+		auth.WithRoleNamespaces(map[string][]string{
+			rwRole: {"*"},
+			"team": {"test"},
+		}),
+		auth.WithClientNamespacesClaim("namespaces"),
+	)
+
+	srv, err := New(c, zap.NewNop().Sugar(), Config{
+		PrometheusRegistry: prometheus.NewRegistry(),
+		NumReplicas:        1,
+		GRPCListenAddr:     grpcAddr,
+		HTTPListenAddr:     httpAddr,
+		TokenIssuer:        "discovery.test",
+		TokenSecretKey:     "secret",
+		OIDCClient:         oidcClientID,
+		OIDCRoles:          []string{rwRole},
+		OIDCURL:            oidcServer.URL,
+		OIDCAudience:       oidcAudience,
+		ClaimConfig:        claimConfig,
+		TrashRetention:     time.Hour,
+		AuditRetention:     time.Hour,
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+
+	go func() {
+		errc <- srv.Run(ctx)
+	}()
+
+	defer func() {
+		cancel()
+		assert.NoError(t, <-errc)
+	}()
+
+	rc := restClient{
+		t:    t,
+		base: "http://" + httpAddr,
+	}
+
+	admin := userToken("admin", rwRole)
+
+	require.Eventually(t, func() bool {
+		code, _ := rc.try(http.MethodGet, "/v1/namespaces", admin, nil)
+		return code == http.StatusOK
+	}, 10*time.Second, 50*time.Millisecond)
+
+	t.Run("namespace api", func(t *testing.T) {
+		code, _ := rc.do(http.MethodPost, "/v1/namespaces", admin, map[string]interface{}{"name": "test"})
+		assert.Equal(t, http.StatusOK, code)
+
+		code, body := rc.do(http.MethodGet, "/v1/namespaces", admin, nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, `"test"`)
+		assert.Contains(t, body, `"default"`)
+
+		code, _ = rc.do(http.MethodPost, "/v1/namespaces", userToken("user"), map[string]interface{}{"name": "other"})
+		assert.Equal(t, http.StatusForbidden, code)
+	})
+
+	t.Run("server api", func(t *testing.T) {
+		code, _ := rc.do(http.MethodPost, "/v1/servers", admin, map[string]interface{}{"name": "server1"})
+		assert.Equal(t, http.StatusOK, code)
+
+		code, body := rc.do(http.MethodGet, "/v1/servers", admin, nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, `"server1"`)
+	})
+
+	var machine string
+
+	t.Run("token api", func(t *testing.T) {
+		code, body := rc.do(http.MethodPost, "/v1/tokens", admin, map[string]interface{}{"id": "machine", "namespaces": []string{"test"}})
+		require.Equal(t, http.StatusOK, code)
+
+		resp := struct {
+			Token string `json:"token"`
+		}{}
+		require.NoError(t, json.Unmarshal([]byte(body), &resp))
+		require.NotEmpty(t, resp.Token)
+
+		machine = resp.Token
+
+		code, body = rc.do(http.MethodGet, "/v1/tokens/"+machine, admin, nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, `"machine"`)
+
+		code, body = rc.do(http.MethodPost, "/v1/tokens", admin, map[string]interface{}{"id": "revoked", "namespaces": []string{"test"}})
+		require.Equal(t, http.StatusOK, code)
+		require.NoError(t, json.Unmarshal([]byte(body), &resp))
+
+		revoked := resp.Token
+
+		info := struct {
+			Tokeninfo struct {
+				TokenID string `json:"tokenId"`
+			} `json:"tokeninfo"`
+		}{}
+		code, body = rc.do(http.MethodGet, "/v1/tokens/"+revoked, admin, nil)
+		require.Equal(t, http.StatusOK, code)
+		require.NoError(t, json.Unmarshal([]byte(body), &info))
+		require.NotEmpty(t, info.Tokeninfo.TokenID)
+
+		code, body = rc.do(http.MethodGet, "/v1/tokens", admin, nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, info.Tokeninfo.TokenID)
+
+		code, _ = rc.do(http.MethodGet, "/v1/tokens", machine, nil)
+		assert.Equal(t, http.StatusForbidden, code)
+
+		code, _ = rc.do(http.MethodGet, "/v1/services?namespace=test", revoked, nil)
+		assert.Equal(t, http.StatusOK, code)
+
+		code, _ = rc.do(http.MethodPost, "/v1/tokens/"+info.Tokeninfo.TokenID+"/revoke", admin, map[string]interface{}{})
+		require.Equal(t, http.StatusOK, code)
+
+		code, _ = rc.do(http.MethodGet, "/v1/services?namespace=test", revoked, nil)
+		assert.Equal(t, http.StatusUnauthorized, code)
+
+		code, _ = rc.do(http.MethodGet, "/v1/services?namespace=test", machine, nil)
+		assert.Equal(t, http.StatusOK, code)
+	})
+
+	endpoint := "http://example.com/metrics"
+
+	t.Run("service api", func(t *testing.T) {
+		code, _ := rc.do(http.MethodPost, "/v1/services", machine, map[string]interface{}{
+			"name":      "example",
+			"endpoint":  endpoint,
+			"namespace": "test",
+			"ttl":       "1m",
+		})
+		assert.Equal(t, http.StatusOK, code)
+
+		code, _ = rc.do(http.MethodPost, "/v1/services", machine, map[string]interface{}{
+			"name":     "example",
+			"endpoint": endpoint,
+		})
+		assert.Equal(t, http.StatusForbidden, code)
+
+		code, body := rc.do(http.MethodPost, "/v1/services/test/heartbeat", machine, map[string]interface{}{"id": endpoint})
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, "expiresAt")
+
+		code, body = rc.do(http.MethodGet, "/v1/services?namespace=test", machine, nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, endpoint)
+
+		code, body = rc.do(http.MethodGet, "/v1/sd/server1/test", machine, nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, "example.com")
+
+		code, body = rc.do(http.MethodGet, "/v1/sd/serv.*/test", machine, nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, "example.com", "server is a regular expression")
+
+		code, body = rc.do(http.MethodGet, "/v1/sd/serv/test", machine, nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.NotContains(t, body, "example.com", "regular expression matches the whole server name")
+
+		code, _ = rc.do(http.MethodGet, "/v1/sd/"+url.PathEscape("server[")+"/test", machine, nil)
+		assert.Equal(t, http.StatusBadRequest, code)
+
+		code, _ = rc.do(http.MethodDelete, "/v1/services/test?id="+url.QueryEscape(endpoint), machine, nil)
+		assert.Equal(t, http.StatusOK, code)
+
+		code, _ = rc.do(http.MethodDelete, "/v1/services/test?id="+url.QueryEscape(endpoint), machine, nil)
+		assert.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("namespace scoped user", func(t *testing.T) {
+		team := userToken("teamuser", "team")
+		svc := func(namespace string) map[string]interface{} {
+			return map[string]interface{}{
+				"name":      "scoped",
+				"endpoint":  "http://scoped.example.com/metrics",
+				"namespace": namespace,
+			}
+		}
+
+		code, _ := rc.do(http.MethodPost, "/v1/services", team, svc("test"))
+		assert.Equal(t, http.StatusOK, code)
+
+		code, body := rc.do(http.MethodPost, "/v1/services", team, svc("default"))
+		assert.Equal(t, http.StatusForbidden, code)
+		assert.Contains(t, body, "namespace 'default'")
+
+		code, _ = rc.do(http.MethodPost, "/v1/services", userToken("user"), svc("default"))
+		assert.Equal(t, http.StatusForbidden, code)
+
+		code, _ = rc.do(http.MethodDelete, "/v1/services/test?id="+url.QueryEscape("http://scoped.example.com/metrics"), team, nil)
+		assert.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("namespace scoped reads", func(t *testing.T) {
+		code, _ := rc.do(http.MethodPost, "/v1/namespaces", admin, map[string]interface{}{"name": "shared", "export": 1, "public": true})
+		require.Equal(t, http.StatusOK, code)
+
+		for _, ns := range []string{"default", "shared"} {
+			code, _ = rc.do(http.MethodPost, "/v1/services", admin, map[string]interface{}{
+				"name":      "private",
+				"endpoint":  "http://" + ns + ".example.com/metrics",
+				"namespace": ns,
+			})
+			require.Equal(t, http.StatusOK, code)
+		}
+
+		code, _ = rc.do(http.MethodGet, "/v1/services?namespace=default", machine, nil)
+		assert.Equal(t, http.StatusForbidden, code)
+
+		code, _ = rc.do(http.MethodGet, "/v1/sd/server1/default", machine, nil)
+		assert.Equal(t, http.StatusForbidden, code)
+
+		code, body := rc.do(http.MethodGet, "/v1/services", machine, nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.NotContains(t, body, "default.example.com")
+		assert.Contains(t, body, "shared.example.com")
+
+		code, body = rc.do(http.MethodGet, "/v1/sd/server1/shared", machine, nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, "shared.example.com")
+
+		code, body = rc.do(http.MethodGet, "/v1/services", admin, nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, "default.example.com")
+
+		for _, ns := range []string{"default", "shared"} {
+			code, _ = rc.do(http.MethodDelete, "/v1/services/"+ns+"?id="+url.QueryEscape("http://"+ns+".example.com/metrics"), admin, nil)
+			assert.Equal(t, http.StatusOK, code)
+		}
+	})
+
+	t.Run("bulk service api", func(t *testing.T) {
+		code, body := rc.do(http.MethodPost, "/v1/services/bulk/register", machine, map[string]interface{}{
+			"services": []map[string]interface{}{
+				{"name": "bulk", "endpoint": "http://bulk1.example.com/metrics", "namespace": "test"},
+				{"name": "bulk", "endpoint": "http://bulk2.example.com/metrics", "namespace": "default"},
+			},
+		})
+		assert.Equal(t, http.StatusOK, code)
+
+		resp := struct {
+			Results []struct {
+				Code  int    `json:"code"`
+				Error string `json:"error"`
+			} `json:"results"`
+		}{}
+		require.NoError(t, json.Unmarshal([]byte(body), &resp))
+		require.Len(t, resp.Results, 2)
+		assert.Equal(t, 0, resp.Results[0].Code)
+		assert.Equal(t, 7, resp.Results[1].Code) // permission denied
+
+		code, body = rc.do(http.MethodPost, "/v1/services/bulk/unregister", machine, map[string]interface{}{
+			"services": []map[string]interface{}{
+				{"id": "http://bulk1.example.com/metrics", "namespace": "test"},
+			},
+		})
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, `"code":0`)
+	})
+
+	t.Run("client credentials token", func(t *testing.T) {
+		ci := clientToken("ci", oidcAudience, "test")
+
+		code, _ := rc.do(http.MethodGet, "/v1/services?namespace=test", ci, nil)
+		assert.Equal(t, http.StatusOK, code)
+
+		code, body := rc.do(http.MethodPost, "/v1/services", ci, map[string]interface{}{
+			"name":      "ci",
+			"endpoint":  "http://ci.example.com/metrics",
+			"namespace": "default",
+		})
+		assert.Equal(t, http.StatusForbidden, code)
+		assert.Contains(t, body, "client token ci")
+
+		code, _ = rc.do(http.MethodPost, "/v1/namespaces", ci, map[string]interface{}{"name": "ci"})
+		assert.Equal(t, http.StatusForbidden, code)
+
+		code, _ = rc.do(http.MethodGet, "/v1/services?namespace=test", clientToken("ci", "other-api", "test"), nil)
+		assert.Equal(t, http.StatusForbidden, code, "wrong audience")
+	})
+
+	t.Run("scrape token", func(t *testing.T) {
+		code, body := rc.do(http.MethodPost, "/v1/tokens", admin, map[string]interface{}{
+			"id":         "prometheus",
+			"namespaces": []string{"test"},
+			"scope":      "sd",
+			"servers":    []string{"server1"},
+		})
+		require.Equal(t, http.StatusOK, code)
+
+		resp := struct {
+			Token string `json:"token"`
+		}{}
+		require.NoError(t, json.Unmarshal([]byte(body), &resp))
+
+		scrape := resp.Token
+
+		code, _ = rc.do(http.MethodGet, "/v1/sd/server1/test", scrape, nil)
+		assert.Equal(t, http.StatusOK, code)
+
+		code, _ = rc.do(http.MethodGet, "/v1/sd/server2/test", scrape, nil)
+		assert.Equal(t, http.StatusForbidden, code)
+
+		code, _ = rc.do(http.MethodGet, "/v1/services?namespace=test", scrape, nil)
+		assert.Equal(t, http.StatusForbidden, code)
+
+		code, _ = rc.do(http.MethodPost, "/v1/services", scrape, map[string]interface{}{
+			"name":      "scrape",
+			"endpoint":  "http://scrape.example.com/metrics",
+			"namespace": "test",
+		})
+		assert.Equal(t, http.StatusForbidden, code)
+
+		code, body = rc.do(http.MethodGet, "/v1/tokens/"+scrape, admin, nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, `"scope":"sd"`)
+
+		code, _ = rc.do(http.MethodPost, "/v1/tokens", admin, map[string]interface{}{
+			"id":         "invalid",
+			"namespaces": []string{"test"},
+			"scope":      "write",
+		})
+		assert.Equal(t, http.StatusBadRequest, code)
+
+		code, _ = rc.do(http.MethodPost, "/v1/tokens", admin, map[string]interface{}{
+			"id":         "all-servers",
+			"namespaces": []string{"test"},
+			"scope":      "sd",
+		})
+		assert.Equal(t, http.StatusBadRequest, code, "scrape token without servers")
+	})
+
+	t.Run("http_sd etag and long poll", func(t *testing.T) {
+		code, _ := rc.do(http.MethodPost, "/v1/services", machine, map[string]interface{}{
+			"name":      "example",
+			"endpoint":  "http://example.com:9100/metrics",
+			"namespace": "test",
+		})
+		require.Equal(t, http.StatusOK, code)
+
+		code, _, h := rc.get("/v1/sd/server1/test", machine, nil)
+		require.Equal(t, http.StatusOK, code)
+		etag := h.Get("ETag")
+		require.NotEmpty(t, etag)
+		index := h.Get("X-Discovery-Index")
+		require.NotEmpty(t, index)
+
+		code, body, _ := rc.get("/v1/sd/server1/test", machine, http.Header{"If-None-Match": []string{etag}})
+		assert.Equal(t, http.StatusNotModified, code)
+		assert.Empty(t, body)
+
+		go func() {
+			time.Sleep(200 * time.Millisecond)
+			rc.do(http.MethodPost, "/v1/services", machine, map[string]interface{}{
+				"name":      "example",
+				"endpoint":  "http://example.com:9200/metrics",
+				"namespace": "test",
+			})
+		}()
+
+		start := time.Now()
+		code, body, h = rc.get("/v1/sd/server1/test?wait=10s&index="+index, machine, nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Less(t, time.Since(start), 10*time.Second)
+		assert.Contains(t, body, "example.com:9200")
+		assert.NotEqual(t, index, h.Get("X-Discovery-Index"))
+		assert.NotEqual(t, etag, h.Get("ETag"))
+
+		index = h.Get("X-Discovery-Index")
+		code, _, h = rc.get("/v1/sd/server1/test?wait=100ms&index="+index, machine, nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, index, h.Get("X-Discovery-Index"))
+	})
+
+	t.Run("long poll all namespaces", func(t *testing.T) {
+		conn, err := grpc.Dial(grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		require.NoError(t, err)
+
+		defer conn.Close()
+
+		cli := discoveryv1.NewServiceAPIClient(conn)
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "bearer "+admin)
+
+		list := func(index uint64, wait string) uint64 {
+			var h metadata.MD
+
+			_, err := cli.ListTargetGroup(ctx, &discoveryv1.ListTargetGroupRequest{
+				Server: "server1",
+				Index:  index,
+				Wait:   wait,
+			}, grpc.Header(&h))
+			require.NoError(t, err)
+			require.Len(t, h.Get(indexHeader), 1)
+
+			idx, err := strconv.ParseUint(h.Get(indexHeader)[0], 10, 64)
+			require.NoError(t, err)
+
+			return idx
+		}
+
+		index := list(0, "")
+		assert.Equal(t, index, list(index, "100ms"), "index is stable")
+
+		go func() {
+			time.Sleep(200 * time.Millisecond)
+			rc.do(http.MethodPost, "/v1/services", admin, map[string]interface{}{
+				"name":     "all",
+				"endpoint": "http://all.example.com/metrics",
+			})
+		}()
+
+		start := time.Now()
+		assert.NotEqual(t, index, list(index, "10s"))
+		assert.Less(t, time.Since(start), 5*time.Second)
+
+		code, _ := rc.do(http.MethodDelete, "/v1/services/default?id="+url.QueryEscape("http://all.example.com/metrics"), admin, nil)
+		assert.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("rebalance and server states", func(t *testing.T) {
+		code, _ := rc.do(http.MethodPost, "/v1/servers", admin, map[string]interface{}{"name": "server2"})
+		require.Equal(t, http.StatusOK, code)
+
+		code, body := rc.do(http.MethodPost, "/v1/servers/rebalance", admin, map[string]interface{}{"dry_run": true})
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, `"server":"server2"`)
+
+		code, _ = rc.do(http.MethodPost, "/v1/servers/rebalance", machine, map[string]interface{}{"dry_run": true})
+		assert.Equal(t, http.StatusForbidden, code)
+
+		code, body = rc.do(http.MethodPost, "/v1/servers/server2/cordon", admin, map[string]interface{}{})
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, fmt.Sprintf(`"state":"%d"`, discovery.Cordoned))
+
+		code, _ = rc.do(http.MethodPost, "/v1/servers/server2/drain", admin, map[string]interface{}{"rate": 0})
+		assert.Equal(t, http.StatusBadRequest, code)
+
+		code, body = rc.do(http.MethodPost, "/v1/servers/server2/drain", admin, map[string]interface{}{"rate": 5})
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, fmt.Sprintf(`"state":"%d"`, discovery.Draining))
+		assert.Contains(t, body, `"drainRate":"5"`)
+
+		code, body = rc.do(http.MethodPost, "/v1/servers/server2/uncordon", admin, map[string]interface{}{})
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, fmt.Sprintf(`"state":"%d"`, discovery.Active))
+
+		code, _ = rc.do(http.MethodPost, "/v1/servers/server3/cordon", admin, map[string]interface{}{})
+		assert.Equal(t, http.StatusNotFound, code)
+
+		code, _ = rc.do(http.MethodPost, "/v1/servers/server2/cordon", machine, map[string]interface{}{})
+		assert.Equal(t, http.StatusForbidden, code)
+
+		code, body = rc.do(http.MethodPost, "/v1/servers/rebalance", admin, map[string]interface{}{})
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, `"server":"server1"`)
+
+		code, _ = rc.do(http.MethodDelete, "/v1/servers/server2", admin, nil)
+		assert.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("unregister", func(t *testing.T) {
+		code, _ := rc.do(http.MethodDelete, "/v1/namespaces/test", admin, nil)
+		assert.Equal(t, http.StatusOK, code)
+
+		code, _ = rc.do(http.MethodDelete, "/v1/servers/server1", admin, nil)
+		assert.Equal(t, http.StatusOK, code)
+
+		code, body := rc.do(http.MethodGet, "/v1/namespaces", admin, nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.NotContains(t, body, `"test"`)
+
+		code, body = rc.do(http.MethodGet, "/v1/namespaces/deleted", admin, nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, `"test"`)
+
+		code, _ = rc.do(http.MethodPost, "/v1/namespaces/test/restore", userToken("user"), map[string]interface{}{})
+		assert.Equal(t, http.StatusForbidden, code)
+	})
+
+	t.Run("audit api", func(t *testing.T) {
+		code, body := rc.do(http.MethodGet, "/v1/audit?method=UnregisterServer", admin, nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, `"user":"admin"`)
+		assert.Contains(t, body, `server1`)
+		assert.NotContains(t, body, "RegisterNamespace")
+
+		code, body = rc.do(http.MethodGet, "/v1/audit?user=user", admin, nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, `"code":7`) // denied register namespace
+
+		code, _ = rc.do(http.MethodGet, "/v1/audit", machine, nil)
+		assert.Equal(t, http.StatusForbidden, code)
+	})
+
+	t.Run("swagger", func(t *testing.T) {
+		for _, api := range []string{"namespace_api", "server_api", "service_api", "token_api", "audit_api"} {
+			code, _ := rc.do(http.MethodGet, fmt.Sprintf("/swagger/api/%s.swagger.json", api), "", nil)
+			assert.Equal(t, http.StatusOK, code, api)
+		}
+	})
+
+	t.Run("can-i", func(t *testing.T) {
+		code, body := rc.do(http.MethodGet, "/v1/auth/can-i?method=ServerAPI/RegisterServer", admin, nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, `"allowed":true`)
+
+		code, body = rc.do(http.MethodGet, "/v1/auth/can-i?method=ServerAPI/RegisterServer", machine, nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, `"allowed":false`)
+
+		code, body = rc.do(http.MethodGet, "/v1/auth/can-i?method=ServiceAPI/RegisterService&namespaces=test", machine, nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, `"allowed":true`)
+	})
+
+	t.Run("jwks", func(t *testing.T) {
+		code, body := rc.do(http.MethodGet, "/.well-known/jwks.json", "", nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.JSONEq(t, `{"keys":[]}`, body)
+	})
+}
+
+func TestMutualTLS(t *testing.T) {
+	oidcServer, userToken, _ := newOIDCProvider(t)
+	defer oidcServer.Close()
+
+	c := newBackend(t)
+
+	dir := t.TempDir()
+	ca := newTestCA(t, dir, "ca")
+	ca.issue(t, "server", "localhost", true)
+	ca.issue(t, "client", "host1.example.com", false)
+	newTestCA(t, dir, "other-ca").issue(t, "other", "host1.example.com", false)
+
+	tlsConfig, err := auth.NewServerTLSConfig(filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"), filepath.Join(dir, "ca.crt"))
+	require.NoError(t, err)
+
+	grpcAddr, httpAddr := freeAddr(t), freeAddr(t)
+
+	srv, err := New(c, zap.NewNop().Sugar(), Config{
+		PrometheusRegistry: prometheus.NewRegistry(),
+		NumReplicas:        1,
+		GRPCListenAddr:     grpcAddr,
+		HTTPListenAddr:     httpAddr,
+		TokenIssuer:        "discovery.test",
+		TokenSecretKey:     "secret",
+		OIDCClient:         oidcClientID,
+		OIDCRoles:          []string{rwRole},
+		OIDCURL:            oidcServer.URL,
+		ClaimConfig:        auth.NewClaimConfig("username", "roles"),
+		TLSConfig:          tlsConfig,
+		CertConfig:         auth.NewCertConfig(map[string][]string{"*.example.com": {"default"}}),
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+
+	go func() {
+		errc <- srv.Run(ctx)
+	}()
+
+	defer func() {
+		cancel()
+		assert.NoError(t, <-errc)
+	}()
+
+	rc := restClient{
+		t:    t,
+		base: "http://" + httpAddr,
+	}
+
+	admin := userToken("admin", rwRole)
+
+	require.Eventually(t, func() bool {
+		code, _ := rc.try(http.MethodGet, "/v1/namespaces", admin, nil)
+		return code == http.StatusOK
+	}, 10*time.Second, 50*time.Millisecond, "gateway connects to tls grpc server")
+
+	dial := func(t *testing.T, cert string) discoveryv1.ServiceAPIClient {
+		t.Helper()
+
+		pool, err := auth.LoadCertPool(filepath.Join(dir, "ca.crt"))
+		require.NoError(t, err)
+
+		cfg := &tls.Config{
+			RootCAs:    pool,
+			ServerName: "localhost",
+			MinVersion: tls.VersionTLS12,
+		}
+
+		if cert != "" {
+			c, err := tls.LoadX509KeyPair(filepath.Join(dir, cert+".crt"), filepath.Join(dir, cert+".key"))
+			require.NoError(t, err)
+
+			cfg.Certificates = []tls.Certificate{c}
+		}
+
+		conn, err := grpc.Dial(grpcAddr, grpc.WithTransportCredentials(credentials.NewTLS(cfg)))
+		require.NoError(t, err)
+
+		t.Cleanup(func() { conn.Close() })
+
+		return discoveryv1.NewServiceAPIClient(conn)
+	}
+
+	reqCtx, reqCancel := context.WithTimeout(ctx, 10*time.Second)
+	defer reqCancel()
+
+	t.Run("client certificate", func(t *testing.T) {
+		cli := dial(t, "client")
+
+		_, err := cli.ListService(reqCtx, &discoveryv1.ListServiceRequest{Namespace: "default"})
+		assert.NoError(t, err)
+
+		_, err = cli.ListService(reqCtx, &discoveryv1.ListServiceRequest{Namespace: "other"})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("no client certificate", func(t *testing.T) {
+		_, err := dial(t, "").ListService(reqCtx, &discoveryv1.ListServiceRequest{Namespace: "default"})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("untrusted client certificate", func(t *testing.T) {
+		_, err := dial(t, "other").ListService(reqCtx, &discoveryv1.ListServiceRequest{Namespace: "default"})
+		assert.Error(t, err)
+	})
+}
+
+type testCA struct {
+	dir  string
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newTestCA creates a self signed ca and writes its certificate to dir/name.crt.
+func newTestCA(t *testing.T, dir, name string) testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	writePEM(t, filepath.Join(dir, name+".crt"), "CERTIFICATE", der)
+
+	return testCA{
+		dir:  dir,
+		cert: cert,
+		key:  key,
+	}
+}
+
+// issue writes a certificate and key signed by the ca to dir/name.crt and dir/name.key.
+func (ca testCA) issue(t *testing.T, name, commonName string, server bool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	if server {
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		tmpl.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, key.Public(), ca.key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	writePEM(t, filepath.Join(ca.dir, name+".crt"), "CERTIFICATE", der)
+	writePEM(t, filepath.Join(ca.dir, name+".key"), "PRIVATE KEY", keyDER)
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+}
+
+type restClient struct {
+	t    *testing.T
+	base string
+}
+
+func (c restClient) do(method, path, token string, body interface{}) (int, string) {
+	code, resp, _ := c.send(method, path, token, body, nil)
+	require.NotZero(c.t, code, "%s %s failed", method, path)
+
+	return code, resp
+}
+
+func (c restClient) get(path, token string, header http.Header) (int, string, http.Header) {
+	code, resp, h := c.send(http.MethodGet, path, token, nil, header)
+	require.NotZero(c.t, code, "GET %s failed", path)
+
+	return code, resp, h
+}
+
+// try returns status code 0, if the request could not be sent.
+func (c restClient) try(method, path, token string, body interface{}) (int, string) {
+	code, resp, _ := c.send(method, path, token, body, nil)
+
+	return code, resp
+}
+
+func (c restClient) send(method, path, token string, body interface{}, header http.Header) (int, string, http.Header) {
+	var r io.Reader
+
+	if body != nil {
+		d, err := json.Marshal(body)
+		require.NoError(c.t, err)
+
+		r = bytes.NewReader(d)
+	}
+
+	req, err := http.NewRequest(method, c.base+path, r)
+	require.NoError(c.t, err)
+
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	if token != "" {
+		req.Header.Set("Authorization", "bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, "", nil
+	}
+
+	defer resp.Body.Close()
+
+	d, err := io.ReadAll(resp.Body)
+	require.NoError(c.t, err)
+
+	return resp.StatusCode, string(d), resp.Header
+}
+
+// newOIDCProvider starts a minimal oidc provider and returns functions to issue
+// id tokens and client credentials access tokens for it.
+func newOIDCProvider(t *testing.T) (*httptest.Server, func(username string, roles ...string) string, func(clientID, audience string, namespaces ...string) string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                srv.URL,
+			"authorization_endpoint":                srv.URL + "/auth",
+			"token_endpoint":                        srv.URL + "/token",
+			"jwks_uri":                              srv.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{
+				{
+					"kty": "RSA",
+					"kid": "test",
+					"alg": "RS256",
+					"use": "sig",
+					"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+				},
+			},
+		})
+	})
+
+	sign := func(claims jwt.MapClaims) string {
+		tkn := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		tkn.Header["kid"] = "test"
+
+		s, err := tkn.SignedString(key)
+		require.NoError(t, err)
+
+		return s
+	}
+
+	issue := func(username string, roles ...string) string {
+		return sign(jwt.MapClaims{
+			"iss":      srv.URL,
+			"aud":      oidcClientID,
+			"sub":      username,
+			"exp":      time.Now().Add(time.Hour).Unix(),
+			"iat":      time.Now().Unix(),
+			"username": username,
+			"roles":    roles,
+		})
+	}
+
+	issueClient := func(clientID, audience string, namespaces ...string) string {
+		return sign(jwt.MapClaims{
+			"iss":        srv.URL,
+			"aud":        audience,
+			"sub":        "service-account-" + clientID,
+			"exp":        time.Now().Add(time.Hour).Unix(),
+			"iat":        time.Now().Unix(),
+			"client_id":  clientID,
+			"namespaces": namespaces,
+		})
+	}
+
+	return srv, issue, issueClient
+}
+
+func freeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer l.Close()
+
+	return l.Addr().String()
+}
+
+// newBackend returns a bolt backend, because it supports transactions.
+func newBackend(t *testing.T) *bolt.Backend {
+	t.Helper()
+
+	b, err := bolt.New(filepath.Join(t.TempDir(), "discovery.db"))
+	require.NoError(t, err)
+
+	t.Cleanup(func() { b.Close() })
+
+	return b
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/postfinance/discovery/internal/auth"
+	"github.com/postfinance/discovery/internal/repo"
+	"go.uber.org/zap"
+)
+
+// denyListUpdater keeps the deny list up to date with the revoked tokens in the store.
+type denyListUpdater struct {
+	repo     *repo.Token
+	denyList *auth.DenyList
+	l        *zap.SugaredLogger
+}
+
+// sync replaces the deny list with all revoked tokens.
+func (d *denyListUpdater) sync() error {
+	tokens, err := d.repo.List()
+	if err != nil {
+		return err
+	}
+
+	ids := []string{}
+
+	for _, t := range tokens.Revoked() {
+		ids = append(ids, t.TokenID)
+	}
+
+	d.denyList.Reset(ids...)
+
+	return nil
+}
+
+// run updates the deny list on token events and resyncs it all reSyncInterval. It runs
+// until context ctx is canceled.
+func (d *denyListUpdater) run(ctx context.Context, reSyncInterval time.Duration) {
+	events := d.repo.Chan(ctx, func(err error) {
+		d.l.Fatalw("failed to create token watcher", "err", err)
+	})
+
+	ticker := time.NewTicker(reSyncInterval)
+
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+
+			switch {
+			case e.Event == repo.Change && e.IsRevoked():
+				d.denyList.Add(e.TokenID)
+			default:
+				d.denyList.Remove(e.TokenID)
+			}
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.sync(); err != nil {
+				d.l.Errorw("failed to sync token deny list", "err", err)
+			}
+		}
+	}
+}
+
+// jwksHandler serves the public keys to verify machine tokens as json web key set.
+func jwksHandler(ks *auth.KeySet) http.Handler {
+	data, err := json.Marshal(ks.JWKS())
+	if err != nil {
+		panic(err)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+	})
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"path"
+
+	"github.com/postfinance/discovery"
+	"github.com/postfinance/discovery/internal/repo"
+	"github.com/postfinance/discovery/internal/server/convert"
+	discoveryv1 "github.com/postfinance/discovery/pkg/discoverypb/postfinance/discovery/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// serviceWatch filters the service events of one WatchServices stream. It keeps track of
+// the services sent to the client, because delete events only contain id and namespace.
+type serviceWatch struct {
+	namespace string
+	server    string
+	selector  labels.Selector
+	readable  func(namespace string) bool
+	revision  uint64
+	sent      map[string]struct{}
+}
+
+// newServiceWatch creates a watch for request req. Only services in namespaces that are
+// readable are sent to the client.
+func newServiceWatch(req *discoveryv1.WatchServicesRequest, readable func(namespace string) bool) (*serviceWatch, error) {
+	sel := labels.Everything()
+
+	if req.GetSelector() != "" {
+		s, err := labels.Parse(req.GetSelector())
+		if err != nil {
+			return nil, err
+		}
+
+		sel = s
+	}
+
+	return &serviceWatch{
+		namespace: req.GetNamespace(),
+		server:    req.GetServer(),
+		selector:  sel,
+		readable:  readable,
+		sent:      map[string]struct{}{},
+	}, nil
+}
+
+// snapshot returns the response with all selected services.
+func (w *serviceWatch) snapshot(services discovery.Services) *discoveryv1.WatchServicesResponse {
+	selected := services.Filter(w.matches)
+
+	for i := range selected {
+		w.sent[key(selected[i])] = struct{}{}
+	}
+
+	return w.response(discoveryv1.EventType_EVENT_TYPE_SNAPSHOT, selected...)
+}
+
+// event returns the response for service event e or nil, if e is not relevant
+// for the client.
+func (w *serviceWatch) event(e *repo.ServiceEvent) *discoveryv1.WatchServicesResponse {
+	k := key(e.Service)
+	_, sent := w.sent[k]
+
+	switch {
+	case e.Event == repo.Change && w.matches(e.Service):
+		w.sent[k] = struct{}{}
+
+		return w.response(discoveryv1.EventType_EVENT_TYPE_CHANGE, e.Service)
+	case sent: // deleted or no longer selected
+		delete(w.sent, k)
+
+		return w.response(discoveryv1.EventType_EVENT_TYPE_DELETE, discovery.Service{
+			ID:        e.ID,
+			Namespace: e.Namespace,
+		})
+	default:
+		return nil
+	}
+}
+
+func (w *serviceWatch) matches(s discovery.Service) bool {
+	if w.namespace != "" && s.Namespace != w.namespace {
+		return false
+	}
+
+	if w.server != "" && !s.HasServer(w.server) {
+		return false
+	}
+
+	if !w.readable(s.Namespace) {
+		return false
+	}
+
+	return w.selector.Matches(s.Labels)
+}
+
+func (w *serviceWatch) response(t discoveryv1.EventType, services ...discovery.Service) *discoveryv1.WatchServicesResponse {
+	w.revision++
+
+	pb := make([]*discoveryv1.Service, 0, len(services))
+
+	for i := range services {
+		s := services[i]
+
+		if t == discoveryv1.EventType_EVENT_TYPE_DELETE {
+			pb = append(pb, &discoveryv1.Service{
+				Id:        s.ID,
+				Namespace: s.Namespace,
+			})
+
+			continue
+		}
+
+		pb = append(pb, convert.ServiceToPB(&s))
+	}
+
+	return &discoveryv1.WatchServicesResponse{
+		Revision: w.revision,
+		Type:     t,
+		Services: pb,
+	}
+}
+
+func key(s discovery.Service) string {
+	return path.Join(s.Namespace, s.ID)
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/postfinance/discovery"
+	"github.com/postfinance/discovery/internal/repo"
+	discoveryv1 "github.com/postfinance/discovery/pkg/discoverypb/postfinance/discovery/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceWatch(t *testing.T) {
+	w, err := newServiceWatch(&discoveryv1.WatchServicesRequest{
+		Namespace: "default",
+		Server:    "server1",
+		Selector:  "env=prod",
+	}, func(string) bool { return true })
+	require.NoError(t, err)
+
+	prod := discovery.MustNewService("prod", "http://prod.example.com/metrics")
+	prod.ID = "prod"
+	prod.Servers = []string{"server1"}
+	prod.Labels = discovery.Labels{"env": "prod"}
+
+	test := discovery.MustNewService("test", "http://test.example.com/metrics")
+	test.ID = "test"
+	test.Servers = []string{"server1"}
+	test.Labels = discovery.Labels{"env": "test"}
+
+	t.Run("snapshot", func(t *testing.T) {
+		resp := w.snapshot(discovery.Services{*prod, *test})
+		assert.Equal(t, uint64(1), resp.GetRevision())
+		assert.Equal(t, discoveryv1.EventType_EVENT_TYPE_SNAPSHOT, resp.GetType())
+		require.Len(t, resp.GetServices(), 1)
+		assert.Equal(t, "prod", resp.GetServices()[0].GetId())
+	})
+
+	t.Run("ignore not selected service", func(t *testing.T) {
+		assert.Nil(t, w.event(&repo.ServiceEvent{Service: *test, Event: repo.Change}))
+		assert.Nil(t, w.event(&repo.ServiceEvent{Service: discovery.Service{ID: "test", Namespace: "default"}, Event: repo.Delete}))
+	})
+
+	t.Run("service moved to other server", func(t *testing.T) {
+		moved := *prod
+		moved.Servers = []string{"server2"}
+		resp := w.event(&repo.ServiceEvent{Service: moved, Event: repo.Change})
+		require.NotNil(t, resp)
+		assert.Equal(t, uint64(2), resp.GetRevision())
+		assert.Equal(t, discoveryv1.EventType_EVENT_TYPE_DELETE, resp.GetType())
+	})
+
+	t.Run("change and delete", func(t *testing.T) {
+		resp := w.event(&repo.ServiceEvent{Service: *prod, Event: repo.Change})
+		require.NotNil(t, resp)
+		assert.Equal(t, discoveryv1.EventType_EVENT_TYPE_CHANGE, resp.GetType())
+
+		resp = w.event(&repo.ServiceEvent{Service: discovery.Service{ID: "prod", Namespace: "default"}, Event: repo.Delete})
+		require.NotNil(t, resp)
+		assert.Equal(t, uint64(4), resp.GetRevision())
+		assert.Equal(t, discoveryv1.EventType_EVENT_TYPE_DELETE, resp.GetType())
+	})
+}
+
+func TestServiceWatchReadable(t *testing.T) {
+	w, err := newServiceWatch(&discoveryv1.WatchServicesRequest{}, func(namespace string) bool {
+		return namespace == "team-a"
+	})
+	require.NoError(t, err)
+
+	a := discovery.MustNewService("a", "http://a.example.com/metrics")
+	a.ID = "a"
+	a.Namespace = "team-a"
+
+	b := discovery.MustNewService("b", "http://b.example.com/metrics")
+	b.ID = "b"
+	b.Namespace = "team-b"
+
+	resp := w.snapshot(discovery.Services{*a, *b})
+	require.Len(t, resp.GetServices(), 1)
+	assert.Equal(t, "a", resp.GetServices()[0].GetId())
+
+	assert.Nil(t, w.event(&repo.ServiceEvent{Service: *b, Event: repo.Change}))
+}
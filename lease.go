@@ -0,0 +1,64 @@
+package discovery
+
+import (
+	"time"
+)
+
+// Lease represents the time to live of a registered service. If a lease
+// is not renewed before it expires, the corresponding service gets unregistered.
+type Lease struct {
+	ID        string        `json:"id"`
+	Namespace string        `json:"namespace"`
+	TTL       time.Duration `json:"ttl"`
+	Expires   time.Time     `json:"expires"`
+}
+
+// NewLease creates a new lease for the service with id in namespace. The lease
+// expires ttl from now.
+func NewLease(id, namespace string, ttl time.Duration) *Lease {
+	l := &Lease{
+		ID:        id,
+		Namespace: namespace,
+		TTL:       ttl,
+	}
+
+	l.Renew()
+
+	return l
+}
+
+// Renew extends the lease by its TTL from now.
+func (l *Lease) Renew() {
+	l.Expires = time.Now().Add(l.TTL)
+}
+
+// IsExpired returns true if the lease expired before t.
+func (l Lease) IsExpired(t time.Time) bool {
+	return l.Expires.Before(t)
+}
+
+// KeyVals represents the lease as slice of interface.
+func (l Lease) KeyVals() []interface{} {
+	return []interface{}{
+		"id", l.ID,
+		"namespace", l.Namespace,
+		"ttl", l.TTL,
+		"expires", l.Expires,
+	}
+}
+
+// Leases is a list of leases.
+type Leases []Lease
+
+// Expired returns all leases that expired before t.
+func (l Leases) Expired(t time.Time) Leases {
+	leases := Leases{}
+
+	for i := range l {
+		if l[i].IsExpired(t) {
+			leases = append(leases, l[i])
+		}
+	}
+
+	return leases
+}
@@ -3,6 +3,7 @@ package discovery
 import (
 	"errors"
 	"sort"
+	"strconv"
 	"time"
 )
 
@@ -25,7 +26,14 @@ const (
 type Namespace struct {
 	Name     string       `json:"name"`
 	Export   ExportConfig `json:"export"`
+	Labels   Labels       `json:"labels,omitempty"`
 	Modified time.Time    `json:"modified,omitempty"`
+	// Public namespaces are readable by everyone, private namespaces only with access to
+	// the namespace.
+	Public bool `json:"public,omitempty"`
+	// Replicas is the number of servers services are distributed to. If 0, the default
+	// of the discovery server is used.
+	Replicas int `json:"replicas,omitempty"`
 }
 
 // Validate checks if a services values are valid.
@@ -38,17 +46,21 @@ func (n Namespace) Validate() error {
 		return errors.New("name must only contain 'a-z', 'A-Z', '0-9' and '_'")
 	}
 
-	return nil
+	if n.Replicas < 0 {
+		return errors.New("replicas cannot be negative")
+	}
+
+	return n.Labels.Validate()
 }
 
 // Header creates the header for csv or table output.
 func (n Namespace) Header() []string {
-	return []string{"NAME", "EXPORTCONFIG", "MODIFIED"}
+	return []string{"NAME", "EXPORTCONFIG", "PUBLIC", "REPLICAS", "LABELS", "MODIFIED"}
 }
 
 // Row creates a row for csv or table output.
 func (n Namespace) Row() []string {
-	return []string{n.Name, n.Export.String(), n.Modified.Format(time.RFC3339)}
+	return []string{n.Name, n.Export.String(), strconv.FormatBool(n.Public), replicasString(n.Replicas), n.Labels.String(), n.Modified.Format(time.RFC3339)}
 }
 
 // Namespaces is a list of namespaces.
@@ -99,3 +111,52 @@ func DefaultNamespace() *Namespace {
 		Modified: time.Now(),
 	}
 }
+
+// DeletedNamespace is an unregistered namespace. Until it expires, it can be restored
+// with all services it contained.
+type DeletedNamespace struct {
+	Namespace Namespace `json:"namespace"`
+	Services  int       `json:"services"`
+	Deleted   time.Time `json:"deleted"`
+	Expires   time.Time `json:"expires"`
+}
+
+// IsExpired returns true if the deleted namespace expired before t.
+func (d DeletedNamespace) IsExpired(t time.Time) bool {
+	return d.Expires.Before(t)
+}
+
+// Header creates the header for csv or table output.
+func (d DeletedNamespace) Header() []string {
+	return []string{"NAME", "SERVICES", "DELETED", "EXPIRES"}
+}
+
+// Row creates a row for csv or table output.
+func (d DeletedNamespace) Row() []string {
+	return []string{d.Namespace.Name, strconv.Itoa(d.Services), d.Deleted.Format(time.RFC3339), d.Expires.Format(time.RFC3339)}
+}
+
+// DeletedNamespaces is a list of deleted namespaces.
+type DeletedNamespaces []DeletedNamespace
+
+// Expired returns all deleted namespaces that expired before t.
+func (d DeletedNamespaces) Expired(t time.Time) DeletedNamespaces {
+	namespaces := DeletedNamespaces{}
+
+	for i := range d {
+		if d[i].IsExpired(t) {
+			namespaces = append(namespaces, d[i])
+		}
+	}
+
+	return namespaces
+}
+
+// replicasString returns the number of replicas or "-" for the default.
+func replicasString(replicas int) string {
+	if replicas == 0 {
+		return "-"
+	}
+
+	return strconv.Itoa(replicas)
+}
@@ -0,0 +1,107 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: postfinance/discovery/v1/audit_api.proto
+
+package discoveryv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// AuditAPIClient is the client API for AuditAPI service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AuditAPIClient interface {
+	// ListAuditRecord lists audit records.
+	ListAuditRecord(ctx context.Context, in *ListAuditRecordRequest, opts ...grpc.CallOption) (*ListAuditRecordResponse, error)
+}
+
+type auditAPIClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAuditAPIClient(cc grpc.ClientConnInterface) AuditAPIClient {
+	return &auditAPIClient{cc}
+}
+
+func (c *auditAPIClient) ListAuditRecord(ctx context.Context, in *ListAuditRecordRequest, opts ...grpc.CallOption) (*ListAuditRecordResponse, error) {
+	out := new(ListAuditRecordResponse)
+	err := c.cc.Invoke(ctx, "/postfinance.discovery.v1.AuditAPI/ListAuditRecord", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AuditAPIServer is the server API for AuditAPI service.
+// All implementations must embed UnimplementedAuditAPIServer
+// for forward compatibility
+type AuditAPIServer interface {
+	// ListAuditRecord lists audit records.
+	ListAuditRecord(context.Context, *ListAuditRecordRequest) (*ListAuditRecordResponse, error)
+	mustEmbedUnimplementedAuditAPIServer()
+}
+
+// UnimplementedAuditAPIServer must be embedded to have forward compatible implementations.
+type UnimplementedAuditAPIServer struct {
+}
+
+func (UnimplementedAuditAPIServer) ListAuditRecord(context.Context, *ListAuditRecordRequest) (*ListAuditRecordResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAuditRecord not implemented")
+}
+func (UnimplementedAuditAPIServer) mustEmbedUnimplementedAuditAPIServer() {}
+
+// UnsafeAuditAPIServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AuditAPIServer will
+// result in compilation errors.
+type UnsafeAuditAPIServer interface {
+	mustEmbedUnimplementedAuditAPIServer()
+}
+
+func RegisterAuditAPIServer(s grpc.ServiceRegistrar, srv AuditAPIServer) {
+	s.RegisterService(&AuditAPI_ServiceDesc, srv)
+}
+
+func _AuditAPI_ListAuditRecord_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAuditRecordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuditAPIServer).ListAuditRecord(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/postfinance.discovery.v1.AuditAPI/ListAuditRecord",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuditAPIServer).ListAuditRecord(ctx, req.(*ListAuditRecordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AuditAPI_ServiceDesc is the grpc.ServiceDesc for AuditAPI service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AuditAPI_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "postfinance.discovery.v1.AuditAPI",
+	HandlerType: (*AuditAPIServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListAuditRecord",
+			Handler:    _AuditAPI_ListAuditRecord_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "postfinance/discovery/v1/audit_api.proto",
+}
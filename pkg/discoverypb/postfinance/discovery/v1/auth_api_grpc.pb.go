@@ -0,0 +1,107 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: postfinance/discovery/v1/auth_api.proto
+
+package discoveryv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// AuthAPIClient is the client API for AuthAPI service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AuthAPIClient interface {
+	// CanI evaluates if the calling user is allowed to call a method.
+	CanI(ctx context.Context, in *CanIRequest, opts ...grpc.CallOption) (*CanIResponse, error)
+}
+
+type authAPIClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAuthAPIClient(cc grpc.ClientConnInterface) AuthAPIClient {
+	return &authAPIClient{cc}
+}
+
+func (c *authAPIClient) CanI(ctx context.Context, in *CanIRequest, opts ...grpc.CallOption) (*CanIResponse, error) {
+	out := new(CanIResponse)
+	err := c.cc.Invoke(ctx, "/postfinance.discovery.v1.AuthAPI/CanI", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AuthAPIServer is the server API for AuthAPI service.
+// All implementations must embed UnimplementedAuthAPIServer
+// for forward compatibility
+type AuthAPIServer interface {
+	// CanI evaluates if the calling user is allowed to call a method.
+	CanI(context.Context, *CanIRequest) (*CanIResponse, error)
+	mustEmbedUnimplementedAuthAPIServer()
+}
+
+// UnimplementedAuthAPIServer must be embedded to have forward compatible implementations.
+type UnimplementedAuthAPIServer struct {
+}
+
+func (UnimplementedAuthAPIServer) CanI(context.Context, *CanIRequest) (*CanIResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CanI not implemented")
+}
+func (UnimplementedAuthAPIServer) mustEmbedUnimplementedAuthAPIServer() {}
+
+// UnsafeAuthAPIServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AuthAPIServer will
+// result in compilation errors.
+type UnsafeAuthAPIServer interface {
+	mustEmbedUnimplementedAuthAPIServer()
+}
+
+func RegisterAuthAPIServer(s grpc.ServiceRegistrar, srv AuthAPIServer) {
+	s.RegisterService(&AuthAPI_ServiceDesc, srv)
+}
+
+func _AuthAPI_CanI_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CanIRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthAPIServer).CanI(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/postfinance.discovery.v1.AuthAPI/CanI",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthAPIServer).CanI(ctx, req.(*CanIRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AuthAPI_ServiceDesc is the grpc.ServiceDesc for AuthAPI service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AuthAPI_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "postfinance.discovery.v1.AuthAPI",
+	HandlerType: (*AuthAPIServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CanI",
+			Handler:    _AuthAPI_CanI_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "postfinance/discovery/v1/auth_api.proto",
+}
@@ -28,6 +28,10 @@ type NamespaceAPIClient interface {
 	UnregisterNamespace(ctx context.Context, in *UnregisterNamespaceRequest, opts ...grpc.CallOption) (*UnregisterNamespaceResponse, error)
 	// ListNamespace lists all namespaces.
 	ListNamespace(ctx context.Context, in *ListNamespaceRequest, opts ...grpc.CallOption) (*ListNamespaceResponse, error)
+	// ListDeletedNamespace lists all unregistered namespaces that can be restored.
+	ListDeletedNamespace(ctx context.Context, in *ListDeletedNamespaceRequest, opts ...grpc.CallOption) (*ListDeletedNamespaceResponse, error)
+	// RestoreNamespace restores an unregistered namespace with its services.
+	RestoreNamespace(ctx context.Context, in *RestoreNamespaceRequest, opts ...grpc.CallOption) (*RestoreNamespaceResponse, error)
 }
 
 type namespaceAPIClient struct {
@@ -65,6 +69,24 @@ func (c *namespaceAPIClient) ListNamespace(ctx context.Context, in *ListNamespac
 	return out, nil
 }
 
+func (c *namespaceAPIClient) ListDeletedNamespace(ctx context.Context, in *ListDeletedNamespaceRequest, opts ...grpc.CallOption) (*ListDeletedNamespaceResponse, error) {
+	out := new(ListDeletedNamespaceResponse)
+	err := c.cc.Invoke(ctx, "/postfinance.discovery.v1.NamespaceAPI/ListDeletedNamespace", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *namespaceAPIClient) RestoreNamespace(ctx context.Context, in *RestoreNamespaceRequest, opts ...grpc.CallOption) (*RestoreNamespaceResponse, error) {
+	out := new(RestoreNamespaceResponse)
+	err := c.cc.Invoke(ctx, "/postfinance.discovery.v1.NamespaceAPI/RestoreNamespace", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // NamespaceAPIServer is the server API for NamespaceAPI service.
 // All implementations must embed UnimplementedNamespaceAPIServer
 // for forward compatibility
@@ -75,6 +97,10 @@ type NamespaceAPIServer interface {
 	UnregisterNamespace(context.Context, *UnregisterNamespaceRequest) (*UnregisterNamespaceResponse, error)
 	// ListNamespace lists all namespaces.
 	ListNamespace(context.Context, *ListNamespaceRequest) (*ListNamespaceResponse, error)
+	// ListDeletedNamespace lists all unregistered namespaces that can be restored.
+	ListDeletedNamespace(context.Context, *ListDeletedNamespaceRequest) (*ListDeletedNamespaceResponse, error)
+	// RestoreNamespace restores an unregistered namespace with its services.
+	RestoreNamespace(context.Context, *RestoreNamespaceRequest) (*RestoreNamespaceResponse, error)
 	mustEmbedUnimplementedNamespaceAPIServer()
 }
 
@@ -91,6 +117,12 @@ func (UnimplementedNamespaceAPIServer) UnregisterNamespace(context.Context, *Unr
 func (UnimplementedNamespaceAPIServer) ListNamespace(context.Context, *ListNamespaceRequest) (*ListNamespaceResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListNamespace not implemented")
 }
+func (UnimplementedNamespaceAPIServer) ListDeletedNamespace(context.Context, *ListDeletedNamespaceRequest) (*ListDeletedNamespaceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDeletedNamespace not implemented")
+}
+func (UnimplementedNamespaceAPIServer) RestoreNamespace(context.Context, *RestoreNamespaceRequest) (*RestoreNamespaceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreNamespace not implemented")
+}
 func (UnimplementedNamespaceAPIServer) mustEmbedUnimplementedNamespaceAPIServer() {}
 
 // UnsafeNamespaceAPIServer may be embedded to opt out of forward compatibility for this service.
@@ -158,6 +190,42 @@ func _NamespaceAPI_ListNamespace_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _NamespaceAPI_ListDeletedNamespace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDeletedNamespaceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NamespaceAPIServer).ListDeletedNamespace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/postfinance.discovery.v1.NamespaceAPI/ListDeletedNamespace",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NamespaceAPIServer).ListDeletedNamespace(ctx, req.(*ListDeletedNamespaceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NamespaceAPI_RestoreNamespace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreNamespaceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NamespaceAPIServer).RestoreNamespace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/postfinance.discovery.v1.NamespaceAPI/RestoreNamespace",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NamespaceAPIServer).RestoreNamespace(ctx, req.(*RestoreNamespaceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // NamespaceAPI_ServiceDesc is the grpc.ServiceDesc for NamespaceAPI service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -177,6 +245,14 @@ var NamespaceAPI_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ListNamespace",
 			Handler:    _NamespaceAPI_ListNamespace_Handler,
 		},
+		{
+			MethodName: "ListDeletedNamespace",
+			Handler:    _NamespaceAPI_ListDeletedNamespace_Handler,
+		},
+		{
+			MethodName: "RestoreNamespace",
+			Handler:    _NamespaceAPI_RestoreNamespace_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "postfinance/discovery/v1/namespace_api.proto",
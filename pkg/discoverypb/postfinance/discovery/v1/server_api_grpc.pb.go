@@ -28,6 +28,14 @@ type ServerAPIClient interface {
 	UnregisterServer(ctx context.Context, in *UnregisterServerRequest, opts ...grpc.CallOption) (*UnregisterServerResponse, error)
 	// ListServer lists all servers.
 	ListServer(ctx context.Context, in *ListServerRequest, opts ...grpc.CallOption) (*ListServerResponse, error)
+	// CordonServer cordons a server. It keeps its services but gets no new services.
+	CordonServer(ctx context.Context, in *CordonServerRequest, opts ...grpc.CallOption) (*CordonServerResponse, error)
+	// UncordonServer makes a cordoned or draining server active again.
+	UncordonServer(ctx context.Context, in *UncordonServerRequest, opts ...grpc.CallOption) (*UncordonServerResponse, error)
+	// DrainServer moves the services of a server gradually to other servers.
+	DrainServer(ctx context.Context, in *DrainServerRequest, opts ...grpc.CallOption) (*DrainServerResponse, error)
+	// Rebalance redistributes all services on the servers.
+	Rebalance(ctx context.Context, in *RebalanceRequest, opts ...grpc.CallOption) (*RebalanceResponse, error)
 }
 
 type serverAPIClient struct {
@@ -65,6 +73,42 @@ func (c *serverAPIClient) ListServer(ctx context.Context, in *ListServerRequest,
 	return out, nil
 }
 
+func (c *serverAPIClient) CordonServer(ctx context.Context, in *CordonServerRequest, opts ...grpc.CallOption) (*CordonServerResponse, error) {
+	out := new(CordonServerResponse)
+	err := c.cc.Invoke(ctx, "/postfinance.discovery.v1.ServerAPI/CordonServer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serverAPIClient) UncordonServer(ctx context.Context, in *UncordonServerRequest, opts ...grpc.CallOption) (*UncordonServerResponse, error) {
+	out := new(UncordonServerResponse)
+	err := c.cc.Invoke(ctx, "/postfinance.discovery.v1.ServerAPI/UncordonServer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serverAPIClient) DrainServer(ctx context.Context, in *DrainServerRequest, opts ...grpc.CallOption) (*DrainServerResponse, error) {
+	out := new(DrainServerResponse)
+	err := c.cc.Invoke(ctx, "/postfinance.discovery.v1.ServerAPI/DrainServer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serverAPIClient) Rebalance(ctx context.Context, in *RebalanceRequest, opts ...grpc.CallOption) (*RebalanceResponse, error) {
+	out := new(RebalanceResponse)
+	err := c.cc.Invoke(ctx, "/postfinance.discovery.v1.ServerAPI/Rebalance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ServerAPIServer is the server API for ServerAPI service.
 // All implementations must embed UnimplementedServerAPIServer
 // for forward compatibility
@@ -75,6 +119,14 @@ type ServerAPIServer interface {
 	UnregisterServer(context.Context, *UnregisterServerRequest) (*UnregisterServerResponse, error)
 	// ListServer lists all servers.
 	ListServer(context.Context, *ListServerRequest) (*ListServerResponse, error)
+	// CordonServer cordons a server. It keeps its services but gets no new services.
+	CordonServer(context.Context, *CordonServerRequest) (*CordonServerResponse, error)
+	// UncordonServer makes a cordoned or draining server active again.
+	UncordonServer(context.Context, *UncordonServerRequest) (*UncordonServerResponse, error)
+	// DrainServer moves the services of a server gradually to other servers.
+	DrainServer(context.Context, *DrainServerRequest) (*DrainServerResponse, error)
+	// Rebalance redistributes all services on the servers.
+	Rebalance(context.Context, *RebalanceRequest) (*RebalanceResponse, error)
 	mustEmbedUnimplementedServerAPIServer()
 }
 
@@ -91,6 +143,18 @@ func (UnimplementedServerAPIServer) UnregisterServer(context.Context, *Unregiste
 func (UnimplementedServerAPIServer) ListServer(context.Context, *ListServerRequest) (*ListServerResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListServer not implemented")
 }
+func (UnimplementedServerAPIServer) CordonServer(context.Context, *CordonServerRequest) (*CordonServerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CordonServer not implemented")
+}
+func (UnimplementedServerAPIServer) UncordonServer(context.Context, *UncordonServerRequest) (*UncordonServerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UncordonServer not implemented")
+}
+func (UnimplementedServerAPIServer) DrainServer(context.Context, *DrainServerRequest) (*DrainServerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DrainServer not implemented")
+}
+func (UnimplementedServerAPIServer) Rebalance(context.Context, *RebalanceRequest) (*RebalanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Rebalance not implemented")
+}
 func (UnimplementedServerAPIServer) mustEmbedUnimplementedServerAPIServer() {}
 
 // UnsafeServerAPIServer may be embedded to opt out of forward compatibility for this service.
@@ -158,6 +222,78 @@ func _ServerAPI_ListServer_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ServerAPI_CordonServer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CordonServerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServerAPIServer).CordonServer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/postfinance.discovery.v1.ServerAPI/CordonServer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServerAPIServer).CordonServer(ctx, req.(*CordonServerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ServerAPI_UncordonServer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UncordonServerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServerAPIServer).UncordonServer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/postfinance.discovery.v1.ServerAPI/UncordonServer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServerAPIServer).UncordonServer(ctx, req.(*UncordonServerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ServerAPI_DrainServer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DrainServerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServerAPIServer).DrainServer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/postfinance.discovery.v1.ServerAPI/DrainServer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServerAPIServer).DrainServer(ctx, req.(*DrainServerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ServerAPI_Rebalance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RebalanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServerAPIServer).Rebalance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/postfinance.discovery.v1.ServerAPI/Rebalance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServerAPIServer).Rebalance(ctx, req.(*RebalanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ServerAPI_ServiceDesc is the grpc.ServiceDesc for ServerAPI service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -177,6 +313,22 @@ var ServerAPI_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ListServer",
 			Handler:    _ServerAPI_ListServer_Handler,
 		},
+		{
+			MethodName: "CordonServer",
+			Handler:    _ServerAPI_CordonServer_Handler,
+		},
+		{
+			MethodName: "UncordonServer",
+			Handler:    _ServerAPI_UncordonServer_Handler,
+		},
+		{
+			MethodName: "DrainServer",
+			Handler:    _ServerAPI_DrainServer_Handler,
+		},
+		{
+			MethodName: "Rebalance",
+			Handler:    _ServerAPI_Rebalance_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "postfinance/discovery/v1/server_api.proto",
@@ -24,14 +24,28 @@ const _ = grpc.SupportPackageIsVersion7
 type ServiceAPIClient interface {
 	// RegisterService registers a service.
 	RegisterService(ctx context.Context, in *RegisterServiceRequest, opts ...grpc.CallOption) (*RegisterServiceResponse, error)
+	// RegisterServices registers multiple services at once. All services are validated
+	// before the first one is registered. The services are not registered in one transaction:
+	// every service is registered on its own and a failed service does not roll back the
+	// others. The result of every service is returned in the same order as in the request.
+	RegisterServices(ctx context.Context, in *RegisterServicesRequest, opts ...grpc.CallOption) (*RegisterServicesResponse, error)
+	// UnregisterServices unregisters multiple services at once. The result of every service is
+	// returned in the same order as in the request.
+	UnregisterServices(ctx context.Context, in *UnregisterServicesRequest, opts ...grpc.CallOption) (*UnregisterServicesResponse, error)
 	// UnRegisterService unregisters a service.
 	UnRegisterService(ctx context.Context, in *UnRegisterServiceRequest, opts ...grpc.CallOption) (*UnRegisterServiceResponse, error)
+	// Heartbeat renews the lease of a service registered with a ttl.
+	Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error)
 	// ListService lists all services.
 	ListService(ctx context.Context, in *ListServiceRequest, opts ...grpc.CallOption) (*ListServiceResponse, error)
 	// ListTargetGroup converts services to prometheus target groups. Those can
 	// be used for http_sd (see: https://prometheus.io/docs/prometheus/latest/http_sd/
 	// for more information).
 	ListTargetGroup(ctx context.Context, in *ListTargetGroupRequest, opts ...grpc.CallOption) (*ListTargetGroupResponse, error)
+	// WatchServices streams a snapshot of all selected services followed by
+	// incremental change and delete events. Streams cannot be resumed: after a
+	// reconnect, a new snapshot is sent.
+	WatchServices(ctx context.Context, in *WatchServicesRequest, opts ...grpc.CallOption) (ServiceAPI_WatchServicesClient, error)
 }
 
 type serviceAPIClient struct {
@@ -51,6 +65,24 @@ func (c *serviceAPIClient) RegisterService(ctx context.Context, in *RegisterServ
 	return out, nil
 }
 
+func (c *serviceAPIClient) RegisterServices(ctx context.Context, in *RegisterServicesRequest, opts ...grpc.CallOption) (*RegisterServicesResponse, error) {
+	out := new(RegisterServicesResponse)
+	err := c.cc.Invoke(ctx, "/postfinance.discovery.v1.ServiceAPI/RegisterServices", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serviceAPIClient) UnregisterServices(ctx context.Context, in *UnregisterServicesRequest, opts ...grpc.CallOption) (*UnregisterServicesResponse, error) {
+	out := new(UnregisterServicesResponse)
+	err := c.cc.Invoke(ctx, "/postfinance.discovery.v1.ServiceAPI/UnregisterServices", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *serviceAPIClient) UnRegisterService(ctx context.Context, in *UnRegisterServiceRequest, opts ...grpc.CallOption) (*UnRegisterServiceResponse, error) {
 	out := new(UnRegisterServiceResponse)
 	err := c.cc.Invoke(ctx, "/postfinance.discovery.v1.ServiceAPI/UnRegisterService", in, out, opts...)
@@ -60,6 +92,15 @@ func (c *serviceAPIClient) UnRegisterService(ctx context.Context, in *UnRegister
 	return out, nil
 }
 
+func (c *serviceAPIClient) Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error) {
+	out := new(HeartbeatResponse)
+	err := c.cc.Invoke(ctx, "/postfinance.discovery.v1.ServiceAPI/Heartbeat", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *serviceAPIClient) ListService(ctx context.Context, in *ListServiceRequest, opts ...grpc.CallOption) (*ListServiceResponse, error) {
 	out := new(ListServiceResponse)
 	err := c.cc.Invoke(ctx, "/postfinance.discovery.v1.ServiceAPI/ListService", in, out, opts...)
@@ -78,20 +119,66 @@ func (c *serviceAPIClient) ListTargetGroup(ctx context.Context, in *ListTargetGr
 	return out, nil
 }
 
+func (c *serviceAPIClient) WatchServices(ctx context.Context, in *WatchServicesRequest, opts ...grpc.CallOption) (ServiceAPI_WatchServicesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ServiceAPI_ServiceDesc.Streams[0], "/postfinance.discovery.v1.ServiceAPI/WatchServices", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &serviceAPIWatchServicesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ServiceAPI_WatchServicesClient interface {
+	Recv() (*WatchServicesResponse, error)
+	grpc.ClientStream
+}
+
+type serviceAPIWatchServicesClient struct {
+	grpc.ClientStream
+}
+
+func (x *serviceAPIWatchServicesClient) Recv() (*WatchServicesResponse, error) {
+	m := new(WatchServicesResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // ServiceAPIServer is the server API for ServiceAPI service.
 // All implementations must embed UnimplementedServiceAPIServer
 // for forward compatibility
 type ServiceAPIServer interface {
 	// RegisterService registers a service.
 	RegisterService(context.Context, *RegisterServiceRequest) (*RegisterServiceResponse, error)
+	// RegisterServices registers multiple services at once. All services are validated
+	// before the first one is registered. The services are not registered in one transaction:
+	// every service is registered on its own and a failed service does not roll back the
+	// others. The result of every service is returned in the same order as in the request.
+	RegisterServices(context.Context, *RegisterServicesRequest) (*RegisterServicesResponse, error)
+	// UnregisterServices unregisters multiple services at once. The result of every service is
+	// returned in the same order as in the request.
+	UnregisterServices(context.Context, *UnregisterServicesRequest) (*UnregisterServicesResponse, error)
 	// UnRegisterService unregisters a service.
 	UnRegisterService(context.Context, *UnRegisterServiceRequest) (*UnRegisterServiceResponse, error)
+	// Heartbeat renews the lease of a service registered with a ttl.
+	Heartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error)
 	// ListService lists all services.
 	ListService(context.Context, *ListServiceRequest) (*ListServiceResponse, error)
 	// ListTargetGroup converts services to prometheus target groups. Those can
 	// be used for http_sd (see: https://prometheus.io/docs/prometheus/latest/http_sd/
 	// for more information).
 	ListTargetGroup(context.Context, *ListTargetGroupRequest) (*ListTargetGroupResponse, error)
+	// WatchServices streams a snapshot of all selected services followed by
+	// incremental change and delete events. Streams cannot be resumed: after a
+	// reconnect, a new snapshot is sent.
+	WatchServices(*WatchServicesRequest, ServiceAPI_WatchServicesServer) error
 	mustEmbedUnimplementedServiceAPIServer()
 }
 
@@ -102,15 +189,27 @@ type UnimplementedServiceAPIServer struct {
 func (UnimplementedServiceAPIServer) RegisterService(context.Context, *RegisterServiceRequest) (*RegisterServiceResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RegisterService not implemented")
 }
+func (UnimplementedServiceAPIServer) RegisterServices(context.Context, *RegisterServicesRequest) (*RegisterServicesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterServices not implemented")
+}
+func (UnimplementedServiceAPIServer) UnregisterServices(context.Context, *UnregisterServicesRequest) (*UnregisterServicesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnregisterServices not implemented")
+}
 func (UnimplementedServiceAPIServer) UnRegisterService(context.Context, *UnRegisterServiceRequest) (*UnRegisterServiceResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UnRegisterService not implemented")
 }
+func (UnimplementedServiceAPIServer) Heartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Heartbeat not implemented")
+}
 func (UnimplementedServiceAPIServer) ListService(context.Context, *ListServiceRequest) (*ListServiceResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListService not implemented")
 }
 func (UnimplementedServiceAPIServer) ListTargetGroup(context.Context, *ListTargetGroupRequest) (*ListTargetGroupResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListTargetGroup not implemented")
 }
+func (UnimplementedServiceAPIServer) WatchServices(*WatchServicesRequest, ServiceAPI_WatchServicesServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchServices not implemented")
+}
 func (UnimplementedServiceAPIServer) mustEmbedUnimplementedServiceAPIServer() {}
 
 // UnsafeServiceAPIServer may be embedded to opt out of forward compatibility for this service.
@@ -142,6 +241,42 @@ func _ServiceAPI_RegisterService_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ServiceAPI_RegisterServices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterServicesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServiceAPIServer).RegisterServices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/postfinance.discovery.v1.ServiceAPI/RegisterServices",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServiceAPIServer).RegisterServices(ctx, req.(*RegisterServicesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ServiceAPI_UnregisterServices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnregisterServicesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServiceAPIServer).UnregisterServices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/postfinance.discovery.v1.ServiceAPI/UnregisterServices",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServiceAPIServer).UnregisterServices(ctx, req.(*UnregisterServicesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ServiceAPI_UnRegisterService_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(UnRegisterServiceRequest)
 	if err := dec(in); err != nil {
@@ -160,6 +295,24 @@ func _ServiceAPI_UnRegisterService_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ServiceAPI_Heartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HeartbeatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServiceAPIServer).Heartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/postfinance.discovery.v1.ServiceAPI/Heartbeat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServiceAPIServer).Heartbeat(ctx, req.(*HeartbeatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ServiceAPI_ListService_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ListServiceRequest)
 	if err := dec(in); err != nil {
@@ -196,6 +349,27 @@ func _ServiceAPI_ListTargetGroup_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ServiceAPI_WatchServices_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchServicesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ServiceAPIServer).WatchServices(m, &serviceAPIWatchServicesServer{stream})
+}
+
+type ServiceAPI_WatchServicesServer interface {
+	Send(*WatchServicesResponse) error
+	grpc.ServerStream
+}
+
+type serviceAPIWatchServicesServer struct {
+	grpc.ServerStream
+}
+
+func (x *serviceAPIWatchServicesServer) Send(m *WatchServicesResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // ServiceAPI_ServiceDesc is the grpc.ServiceDesc for ServiceAPI service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -207,10 +381,22 @@ var ServiceAPI_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RegisterService",
 			Handler:    _ServiceAPI_RegisterService_Handler,
 		},
+		{
+			MethodName: "RegisterServices",
+			Handler:    _ServiceAPI_RegisterServices_Handler,
+		},
+		{
+			MethodName: "UnregisterServices",
+			Handler:    _ServiceAPI_UnregisterServices_Handler,
+		},
 		{
 			MethodName: "UnRegisterService",
 			Handler:    _ServiceAPI_UnRegisterService_Handler,
 		},
+		{
+			MethodName: "Heartbeat",
+			Handler:    _ServiceAPI_Heartbeat_Handler,
+		},
 		{
 			MethodName: "ListService",
 			Handler:    _ServiceAPI_ListService_Handler,
@@ -220,6 +406,12 @@ var ServiceAPI_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _ServiceAPI_ListTargetGroup_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchServices",
+			Handler:       _ServiceAPI_WatchServices_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "postfinance/discovery/v1/service_api.proto",
 }
@@ -26,6 +26,10 @@ type TokenAPIClient interface {
 	Create(ctx context.Context, in *CreateRequest, opts ...grpc.CallOption) (*CreateResponse, error)
 	// Info gives token information.
 	Info(ctx context.Context, in *InfoRequest, opts ...grpc.CallOption) (*InfoResponse, error)
+	// Revoke revokes a token.
+	Revoke(ctx context.Context, in *RevokeRequest, opts ...grpc.CallOption) (*RevokeResponse, error)
+	// List lists all issued tokens.
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
 }
 
 type tokenAPIClient struct {
@@ -54,6 +58,24 @@ func (c *tokenAPIClient) Info(ctx context.Context, in *InfoRequest, opts ...grpc
 	return out, nil
 }
 
+func (c *tokenAPIClient) Revoke(ctx context.Context, in *RevokeRequest, opts ...grpc.CallOption) (*RevokeResponse, error) {
+	out := new(RevokeResponse)
+	err := c.cc.Invoke(ctx, "/postfinance.discovery.v1.TokenAPI/Revoke", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tokenAPIClient) List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error) {
+	out := new(ListResponse)
+	err := c.cc.Invoke(ctx, "/postfinance.discovery.v1.TokenAPI/List", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // TokenAPIServer is the server API for TokenAPI service.
 // All implementations must embed UnimplementedTokenAPIServer
 // for forward compatibility
@@ -62,6 +84,10 @@ type TokenAPIServer interface {
 	Create(context.Context, *CreateRequest) (*CreateResponse, error)
 	// Info gives token information.
 	Info(context.Context, *InfoRequest) (*InfoResponse, error)
+	// Revoke revokes a token.
+	Revoke(context.Context, *RevokeRequest) (*RevokeResponse, error)
+	// List lists all issued tokens.
+	List(context.Context, *ListRequest) (*ListResponse, error)
 	mustEmbedUnimplementedTokenAPIServer()
 }
 
@@ -75,6 +101,12 @@ func (UnimplementedTokenAPIServer) Create(context.Context, *CreateRequest) (*Cre
 func (UnimplementedTokenAPIServer) Info(context.Context, *InfoRequest) (*InfoResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Info not implemented")
 }
+func (UnimplementedTokenAPIServer) Revoke(context.Context, *RevokeRequest) (*RevokeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Revoke not implemented")
+}
+func (UnimplementedTokenAPIServer) List(context.Context, *ListRequest) (*ListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
 func (UnimplementedTokenAPIServer) mustEmbedUnimplementedTokenAPIServer() {}
 
 // UnsafeTokenAPIServer may be embedded to opt out of forward compatibility for this service.
@@ -124,6 +156,42 @@ func _TokenAPI_Info_Handler(srv interface{}, ctx context.Context, dec func(inter
 	return interceptor(ctx, in, info, handler)
 }
 
+func _TokenAPI_Revoke_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenAPIServer).Revoke(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/postfinance.discovery.v1.TokenAPI/Revoke",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenAPIServer).Revoke(ctx, req.(*RevokeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TokenAPI_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenAPIServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/postfinance.discovery.v1.TokenAPI/List",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenAPIServer).List(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // TokenAPI_ServiceDesc is the grpc.ServiceDesc for TokenAPI service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -139,6 +207,14 @@ var TokenAPI_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Info",
 			Handler:    _TokenAPI_Info_Handler,
 		},
+		{
+			MethodName: "Revoke",
+			Handler:    _TokenAPI_Revoke_Handler,
+		},
+		{
+			MethodName: "List",
+			Handler:    _TokenAPI_List_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "postfinance/discovery/v1/token_api.proto",
@@ -0,0 +1,12 @@
+package discovery
+
+import "time"
+
+// Rebalance is the state of a running redistribution of services. It is saved before the
+// first service is moved and deleted after the last one, so that an interrupted
+// redistribution can be resumed.
+type Rebalance struct {
+	Started time.Time `json:"started"`
+	// Leaving are the servers that are deleted when all services are moved.
+	Leaving []string `json:"leaving,omitempty"`
+}
@@ -3,6 +3,7 @@ package discovery
 import (
 	"errors"
 	"sort"
+	"strconv"
 	"time"
 
 	"k8s.io/apimachinery/pkg/labels"
@@ -16,21 +17,34 @@ type ServerState int
 // Leaving: server was unregistered and is leaving
 // Joining: server was registered and is ready for services
 // Active: server already has services configured
+// Cordoned: server keeps its services but gets no new services
+// Draining: server gets no new services and its services are moved to other servers
 const (
-	Leaving ServerState = iota // leaving
-	Joining                    // joining
-	Active                     // active
+	Leaving  ServerState = iota // leaving
+	Joining                     // joining
+	Active                      // active
+	Cordoned                    // cordoned
+	Draining                    // draining
 )
 
+// DefaultWeight is the weight of servers without weight.
+const DefaultWeight = 1
+
 // Server represents a registered server.
 //
 // With kubernetes selectors it is possible to select a server by labels.
 // If IsActive is false, no services are distributed to this server.
+// Services are distributed proportionally to the weights of the servers. A server with
+// MaxTargets > 0 gets no more than MaxTargets services. DrainRate is the number of
+// services moved per drain interval while the server is draining.
 type Server struct {
-	Name     string      `json:"name"`
-	Labels   Labels      `json:"labels"`
-	State    ServerState `json:"state"`
-	Modified time.Time   `json:"modified,omitempty"`
+	Name       string      `json:"name"`
+	Labels     Labels      `json:"labels"`
+	State      ServerState `json:"state"`
+	Modified   time.Time   `json:"modified,omitempty"`
+	Weight     int         `json:"weight,omitempty"`
+	MaxTargets int         `json:"max_targets,omitempty"`
+	DrainRate  int         `json:"drain_rate,omitempty"`
 }
 
 // NewServer creates a new server instance.
@@ -49,9 +63,40 @@ func (s Server) Validate() error {
 		return errors.New("name cannot be empty")
 	}
 
+	if s.Weight < 0 {
+		return errors.New("weight cannot be negative")
+	}
+
+	if s.MaxTargets < 0 {
+		return errors.New("max targets cannot be negative")
+	}
+
+	if s.DrainRate < 0 {
+		return errors.New("drain rate cannot be negative")
+	}
+
 	return nil
 }
 
+// EffectiveWeight returns the weight of the server or DefaultWeight, if the server has no weight.
+func (s Server) EffectiveWeight() int {
+	if s.Weight == 0 {
+		return DefaultWeight
+	}
+
+	return s.Weight
+}
+
+// IsFull returns true, if the server has a maximum number of targets and numTargets reached it.
+func (s Server) IsFull(numTargets int) bool {
+	return s.MaxTargets > 0 && numTargets >= s.MaxTargets
+}
+
+// Schedulable returns true, if new services can be distributed to the server.
+func (s Server) Schedulable() bool {
+	return s.State == Joining || s.State == Active
+}
+
 // Servers is a list of servers.
 type Servers []Server
 
@@ -69,7 +114,7 @@ func (s Servers) SortByDate() {
 	})
 }
 
-// Enabled returns all active and joining servers.
+// Enabled returns all servers that are not leaving.
 func (s Servers) Enabled() Servers {
 	servers := make(Servers, 0, len(s))
 
@@ -115,12 +160,17 @@ func ServersBySelector(selector labels.Selector) func(Server) bool {
 
 // Header creates the header for csv or table output.
 func (s Server) Header() []string {
-	return []string{"NAME", "MODIFIED", "STATE", "LABELS"}
+	return []string{"NAME", "MODIFIED", "STATE", "WEIGHT", "MAX TARGETS", "LABELS"}
 }
 
 // Row creates a row for csv or table output.
 func (s Server) Row() []string {
-	return []string{s.Name, s.Modified.Format(time.RFC3339), s.State.String(), s.Labels.String()}
+	maxTargets := "-"
+	if s.MaxTargets > 0 {
+		maxTargets = strconv.Itoa(s.MaxTargets)
+	}
+
+	return []string{s.Name, s.Modified.Format(time.RFC3339), s.State.String(), strconv.Itoa(s.EffectiveWeight()), maxTargets, s.Labels.String()}
 }
 
 // KeyVals represents the service as slice of interface.
@@ -130,5 +180,8 @@ func (s Server) KeyVals() []interface{} {
 		"modified", s.Modified,
 		"labels", s.Labels.String(),
 		"state", s.State.String(),
+		"weight", s.EffectiveWeight(),
+		"max_targets", s.MaxTargets,
+		"drain_rate", s.DrainRate,
 	}
 }
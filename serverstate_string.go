@@ -11,11 +11,13 @@ func _() {
 	_ = x[Leaving-0]
 	_ = x[Joining-1]
 	_ = x[Active-2]
+	_ = x[Cordoned-3]
+	_ = x[Draining-4]
 }
 
-const _ServerState_name = "leavingjoiningactive"
+const _ServerState_name = "leavingjoiningactivecordoneddraining"
 
-var _ServerState_index = [...]uint8{0, 7, 14, 20}
+var _ServerState_index = [...]uint8{0, 7, 14, 20, 28, 36}
 
 func (i ServerState) String() string {
 	if i < 0 || i >= ServerState(len(_ServerState_index)-1) {
@@ -27,15 +27,18 @@ const (
 
 // Service contains all information for service discovery.
 type Service struct {
-	ID          string    `json:"id,omitempty"`
-	Name        string    `json:"name,omitempty"`
-	Namespace   string    `json:"namespace,omitempty"`
-	Endpoint    *url.URL  `json:"endpoint,omitempty"`
-	Selector    string    `json:"selector,omitempty"`
-	Servers     []string  `json:"servers,omitempty"`
-	Labels      Labels    `json:"labels,omitempty"`
-	Description string    `json:"description,omitempty"`
-	Modified    time.Time `json:"modified,omitempty"`
+	ID          string        `json:"id,omitempty"`
+	Name        string        `json:"name,omitempty"`
+	Namespace   string        `json:"namespace,omitempty"`
+	Endpoint    *url.URL      `json:"endpoint,omitempty"`
+	Selector    string        `json:"selector,omitempty"`
+	Servers     []string      `json:"servers,omitempty"`
+	Labels      Labels        `json:"labels,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Modified    time.Time     `json:"modified,omitempty"`
+	TTL         time.Duration `json:"ttl,omitempty"`
+	// Replicas overrides the number of replicas of the namespace, if it is > 0.
+	Replicas int `json:"replicas,omitempty"`
 }
 
 // NewService creates a new service with ID and timestamp.
@@ -91,6 +94,14 @@ func (s Service) Validate() error {
 		return errors.New("endpoint cannot be null")
 	}
 
+	if s.TTL < 0 {
+		return errors.New("ttl cannot be negative")
+	}
+
+	if s.Replicas < 0 {
+		return errors.New("replicas cannot be negative")
+	}
+
 	return s.Labels.Validate()
 }
 
@@ -107,26 +118,28 @@ func (s Service) HasServer(serverName string) bool {
 
 // Header creates the header for csv or table output.
 func (s Service) Header() []string {
-	return []string{"NAME", "NAMESPACE", "ID", "ENDPOINT", "SERVERS", "LABELS", "SELECTOR", "MODIFIED", "DESCRIPTION"}
+	return []string{"NAME", "NAMESPACE", "ID", "ENDPOINT", "SERVERS", "REPLICAS", "LABELS", "SELECTOR", "MODIFIED", "DESCRIPTION"}
 }
 
 // Row creates a row for csv or table output.
 func (s Service) Row() []string {
-	return []string{s.Name, s.Namespace, s.ID, s.Endpoint.String(), strings.Join(s.Servers, ","), s.Labels.String(), s.Selector, s.Modified.Format(time.RFC3339), s.Description}
+	return []string{s.Name, s.Namespace, s.ID, s.Endpoint.String(), strings.Join(s.Servers, ","), replicasString(s.Replicas), s.Labels.String(), s.Selector, s.Modified.Format(time.RFC3339), s.Description}
 }
 
 // UnmarshalJSON is a custom json unmarshaller.
 func (s *Service) UnmarshalJSON(j []byte) error {
 	raw := struct {
-		ID          string    `json:"id,omitempty"`
-		Name        string    `json:"name,omitempty"`
-		Namespace   string    `json:"namespace,omitempty"`
-		Endpoint    string    `json:"endpoint,omitempty"`
-		Labels      Labels    `json:"labels,omitempty"`
-		Servers     []string  `json:"servers,omitempty"`
-		Selector    string    `json:"selector,omitempty"`
-		Description string    `json:"description,omitempty"`
-		Modified    time.Time `json:"modified,omitempty"`
+		ID          string        `json:"id,omitempty"`
+		Name        string        `json:"name,omitempty"`
+		Namespace   string        `json:"namespace,omitempty"`
+		Endpoint    string        `json:"endpoint,omitempty"`
+		Labels      Labels        `json:"labels,omitempty"`
+		Servers     []string      `json:"servers,omitempty"`
+		Selector    string        `json:"selector,omitempty"`
+		Description string        `json:"description,omitempty"`
+		Modified    time.Time     `json:"modified,omitempty"`
+		TTL         time.Duration `json:"ttl,omitempty"`
+		Replicas    int           `json:"replicas,omitempty"`
 	}{}
 
 	err := json.Unmarshal(j, &raw)
@@ -142,6 +155,8 @@ func (s *Service) UnmarshalJSON(j []byte) error {
 	s.Servers = raw.Servers
 	s.Description = raw.Description
 	s.Modified = raw.Modified
+	s.TTL = raw.TTL
+	s.Replicas = raw.Replicas
 
 	if raw.Endpoint == "" {
 		s.Endpoint = nil
@@ -166,15 +181,17 @@ func (s Service) MarshalJSON() ([]byte, error) {
 	}
 
 	raw := struct {
-		ID          string    `json:"id,omitempty"`
-		Name        string    `json:"name,omitempty"`
-		Namespace   string    `json:"namespace,omitempty"`
-		Endpoint    string    `json:"endpoint,omitempty"`
-		Labels      Labels    `json:"labels,omitempty"`
-		Servers     []string  `json:"servers,omitempty"`
-		Selector    string    `json:"selector,omitempty"`
-		Description string    `json:"description,omitempty"`
-		Modified    time.Time `json:"modified,omitempty"`
+		ID          string        `json:"id,omitempty"`
+		Name        string        `json:"name,omitempty"`
+		Namespace   string        `json:"namespace,omitempty"`
+		Endpoint    string        `json:"endpoint,omitempty"`
+		Labels      Labels        `json:"labels,omitempty"`
+		Servers     []string      `json:"servers,omitempty"`
+		Selector    string        `json:"selector,omitempty"`
+		Description string        `json:"description,omitempty"`
+		Modified    time.Time     `json:"modified,omitempty"`
+		TTL         time.Duration `json:"ttl,omitempty"`
+		Replicas    int           `json:"replicas,omitempty"`
 	}{
 		ID:          s.ID,
 		Name:        s.Name,
@@ -185,6 +202,8 @@ func (s Service) MarshalJSON() ([]byte, error) {
 		Selector:    s.Selector,
 		Description: s.Description,
 		Modified:    s.Modified,
+		TTL:         s.TTL,
+		Replicas:    s.Replicas,
 	}
 
 	return json.Marshal(raw)
@@ -200,6 +219,7 @@ func (s Service) KeyVals() []interface{} {
 		"modified", s.Modified,
 		"selector", s.Selector,
 		"description", s.Description,
+		"ttl", s.TTL,
 	}
 }
 
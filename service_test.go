@@ -130,6 +130,7 @@ func TestKey(t *testing.T) {
 func TestMarshalUnmarshal(t *testing.T) {
 	s, err := NewService("name", "https://test.pnet.ch:7001/metrics")
 	assert.NoError(t, err)
+	s.Replicas = 2
 	t.Run("with url", func(t *testing.T) {
 		d, err := json.Marshal(s)
 		assert.NoError(t, err)
@@ -143,6 +144,7 @@ func TestMarshalUnmarshal(t *testing.T) {
 		assert.Equal(t, s.Labels, s2.Labels)
 		assert.Equal(t, s.ID, s2.ID)
 		assert.Equal(t, s.Description, s2.Description)
+		assert.Equal(t, s.Replicas, s2.Replicas)
 		assert.True(t, s.Modified.Equal(s2.Modified))
 	})
 	t.Run("without url", func(t *testing.T) {
@@ -0,0 +1,57 @@
+package discovery
+
+import (
+	"strings"
+	"time"
+)
+
+// Token represents an issued machine token.
+type Token struct {
+	TokenID    string    `json:"token_id"`
+	ID         string    `json:"id"`
+	Namespaces []string  `json:"namespaces"`
+	IssuedAt   time.Time `json:"issued_at"`
+	ExpiresAt  time.Time `json:"expires_at,omitempty"`
+	RevokedAt  time.Time `json:"revoked_at,omitempty"`
+	Scope      string    `json:"scope,omitempty"`
+	Servers    []string  `json:"servers,omitempty"`
+}
+
+// IsRevoked returns true if the token is revoked.
+func (t Token) IsRevoked() bool {
+	return !t.RevokedAt.IsZero()
+}
+
+// Header creates the header for csv or table output.
+func (t Token) Header() []string {
+	return []string{"TOKEN ID", "ID", "SCOPE", "NAMESPACES", "SERVERS", "ISSUED", "EXPIRES", "REVOKED"}
+}
+
+// Row creates a row for csv or table output.
+func (t Token) Row() []string {
+	return []string{t.TokenID, t.ID, t.Scope, strings.Join(t.Namespaces, ","), strings.Join(t.Servers, ","), t.IssuedAt.Format(time.RFC3339), formatTime(t.ExpiresAt, "never"), formatTime(t.RevokedAt, "")}
+}
+
+// Tokens is a list of tokens.
+type Tokens []Token
+
+// Revoked returns the revoked tokens.
+func (t Tokens) Revoked() Tokens {
+	tokens := Tokens{}
+
+	for i := range t {
+		if t[i].IsRevoked() {
+			tokens = append(tokens, t[i])
+		}
+	}
+
+	return tokens
+}
+
+func formatTime(t time.Time, zero string) string {
+	if t.IsZero() {
+		return zero
+	}
+
+	return t.Format(time.RFC3339)
+}